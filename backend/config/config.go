@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -14,6 +16,22 @@ type Config struct {
 	DB_HOST        string `envconfig:"DB_HOST" default:"localhost"`
 	DBUnixSocket   string `envconfig:"INSTANCE_UNIX_SOCKET" default:""`
 	StaticFilePath string `envconfig:"STATIC_FILE_PATH" required:"true"`
+
+	MaxQueryAttempts int `envconfig:"MAX_QUERY_ATTEMPTS" default:"3"`
+	// LLMCallRetention is how long the audit log of LLM calls is kept; 0 keeps it forever
+	LLMCallRetention time.Duration `envconfig:"LLM_CALL_RETENTION" default:"720h"`
+
+	// DataDir holds the files that may be registered as SQLite and DuckDB databases;
+	// a database path outside it is rejected
+	DataDir string `envconfig:"DATA_DIR" default:"data"`
+
+	// Uploaded datasets are stored in DatasetDir, each upload request being at most
+	// MaxDatasetUploadSize bytes. A dataset is deleted once the ttl of its upload has
+	// passed, DatasetTTL by default and at most MaxDatasetTTL.
+	DatasetDir           string        `envconfig:"DATASET_DIR" default:"datasets"`
+	MaxDatasetUploadSize int64         `envconfig:"MAX_DATASET_UPLOAD_SIZE" default:"52428800"`
+	DatasetTTL           time.Duration `envconfig:"DATASET_TTL" default:"168h"`
+	MaxDatasetTTL        time.Duration `envconfig:"MAX_DATASET_TTL" default:"720h"`
 }
 
 func Load() (*Config, error) {
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -17,6 +18,7 @@ import (
 	orchestrator "github.com/shahariaazam/smart-insights/internal/orchastrator"
 	"github.com/shahariaazam/smart-insights/internal/source"
 	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/shahariaazam/smart-insights/internal/usage"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -30,11 +32,22 @@ type AssistantManager struct {
 	storage          storage.Storage
 	sourceRegistry   *source.Registry
 	llmRegistry      *llmregistry.Registry
+	broker           *source.Broker
 	orchestratorPool chan struct{}
+	maxQueryAttempts int
+
+	// cancels holds the cancel func of every ask that is queued or running
+	cancelMu sync.Mutex
+	cancels  map[string]context.CancelFunc
+
+	// threadMu serializes the assignment of sequence numbers within threads
+	threadMu sync.Mutex
 }
 
 type AssistantManagerConfig struct {
 	MaxConcurrentOrchestrations int
+	// MaxQueryAttempts bounds how many times a failing query is repaired and re-run
+	MaxQueryAttempts int
 }
 
 // NewAssistantManager creates a new instance of AssistantManager
@@ -43,6 +56,7 @@ func NewAssistantManager(
 	storage storage.Storage,
 	sourceRegistry *source.Registry,
 	llmRegistry *llmregistry.Registry, // Update type
+	broker *source.Broker,
 	config AssistantManagerConfig,
 ) *AssistantManager {
 	return &AssistantManager{
@@ -51,10 +65,13 @@ func NewAssistantManager(
 		storage:        storage,
 		sourceRegistry: sourceRegistry,
 		llmRegistry:    llmRegistry,
+		broker:         broker,
 		orchestratorPool: make(
 			chan struct{},
 			config.MaxConcurrentOrchestrations,
 		),
+		maxQueryAttempts: config.MaxQueryAttempts,
+		cancels:          make(map[string]context.CancelFunc),
 	}
 }
 
@@ -66,10 +83,18 @@ func (am *AssistantManager) HandleAssistant(w http.ResponseWriter, r *http.Reque
 	case r.Method == http.MethodGet && r.URL.Path == "/assistant/ask":
 		// Handle invalid get request without UUID
 		am.handleError(w, r, http.StatusBadRequest, "Missing UUID in path", nil)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/assistant/ask/") && strings.HasSuffix(r.URL.Path, "/events"):
+		am.StreamAssistantEvents(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/assistant/ask/") && strings.HasSuffix(r.URL.Path, "/llm-calls"):
+		am.GetAssistantLLMCalls(w, r)
 	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/assistant/ask/"):
 		am.GetAssistantResponse(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/assistant/ask/"):
+		am.CancelAssistantRequest(w, r)
 	case r.Method == http.MethodGet && r.URL.Path == "/assistant/histories":
 		am.GetAssistantHistories(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/assistant/threads/"):
+		am.GetAssistantThread(w, r)
 	default:
 		http.Error(w, "Not found", http.StatusNotFound)
 	}
@@ -88,6 +113,17 @@ func (am *AssistantManager) CreateAssistantRequest(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// Refuse the ask when an LLM config it would use has spent its monthly budget
+	if err := am.checkBudgets(ctx, request); err != nil {
+		var budgetErr *usage.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			am.handleError(w, r, http.StatusPaymentRequired, "Monthly budget exceeded", err)
+			return
+		}
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to check LLM budgets", err)
+		return
+	}
+
 	// Create initial response
 	response := models.AssistantResponse{
 		UUID:     uuid.New().String(),
@@ -101,65 +137,62 @@ func (am *AssistantManager) CreateAssistantRequest(w http.ResponseWriter, r *htt
 		}},
 	}
 
-	// Save initial response
-	if err := am.storage.SaveAssistantResponse(ctx, response); err != nil {
+	// Save initial response as the next ask of its thread
+	if err := am.saveThreadResponse(ctx, request.ThreadID, &response); err != nil {
+		if errors.Is(err, errThreadNotFound) {
+			am.handleError(w, r, http.StatusNotFound, "Thread not found", nil)
+			return
+		}
 		am.handleError(w, r, http.StatusInternalServerError, "Failed to save response", err)
 		return
 	}
 
+	// The ask outlives the HTTP request, so its context is only cancelled through
+	// DELETE /assistant/ask/{uuid}
+	askCtx, cancel := context.WithCancel(context.Background())
+	am.trackAsk(response.UUID, cancel)
+
 	// Start orchestration in background
 	go func() {
-		// Acquire orchestration slot
-		am.orchestratorPool <- struct{}{}
-		defer func() { <-am.orchestratorPool }()
-
-		// Create a background context that's not tied to the request
-		bgCtx := context.Background()
+		defer am.untrackAsk(response.UUID)
 
-		// Load LLM configuration
-		llmConfigInterface, err := am.storage.LoadLLMConfig(
-			bgCtx,
-			request.Options.LLMProvider,
-			request.Options.LLMConfig,
-		)
-		if err != nil {
-			if err == storage.ErrConfigNotFound {
-				am.handleOrchestrationError(bgCtx, response.UUID,
-					fmt.Sprintf("LLM configuration '%s' not found for provider '%s'",
-						request.Options.LLMConfig, request.Options.LLMProvider), err)
-				return
-			}
-			am.handleOrchestrationError(bgCtx, response.UUID,
-				"Failed to load LLM configuration", err)
+		// Acquire orchestration slot, unless the ask is cancelled while queued
+		select {
+		case am.orchestratorPool <- struct{}{}:
+		case <-askCtx.Done():
+			am.markCancelled(response.UUID)
 			return
 		}
+		defer func() { <-am.orchestratorPool }()
 
-		// Convert to concrete LLMConfig
-		llmConfig, ok := llmConfigInterface.(*models.LLMConfig)
-		if !ok {
-			am.handleOrchestrationError(bgCtx, response.UUID,
-				"Invalid LLM configuration type", fmt.Errorf("expected *models.LLMConfig"))
+		// Load the LLM configurations of every pipeline step
+		routes, err := am.resolveRoutes(askCtx, request)
+		if err != nil {
+			am.handleOrchestrationError(askCtx, response.UUID,
+				"Failed to load LLM configuration", err)
 			return
 		}
 
 		// Create and run orchestrator
 		orc, err := orchestrator.NewOrchestrator(
-			bgCtx,
+			askCtx,
 			am.storage,
 			am.sourceRegistry,
-			request.Options.LLMProvider,
-			llmConfig,
+			am.broker,
+			routes,
 			request.DBConfigurationName,
 			response.UUID,
+			am.maxQueryAttempts,
+			request.Options.Mode,
 			am.logger,
 		)
 		if err != nil {
-			am.handleOrchestrationError(bgCtx, response.UUID,
+			am.handleOrchestrationError(askCtx, response.UUID,
 				"Failed to create orchestrator", err)
 			return
 		}
 
-		orc.Run(bgCtx)
+		orc.Run(askCtx)
 	}()
 
 	// Return initial response
@@ -168,21 +201,283 @@ func (am *AssistantManager) CreateAssistantRequest(w http.ResponseWriter, r *htt
 	json.NewEncoder(w).Encode(response)
 }
 
+// resolveRoutes returns the LLM settings of every pipeline step of an ask. Steps
+// routed by the ask's profile, or else by the default profile of its database, use
+// the profile's settings; the other steps use the LLM of the ask.
+func (am *AssistantManager) resolveRoutes(ctx context.Context, request *models.AssistantRequest) (map[string]orchestrator.StepRoute, error) {
+	options := request.Options
+	defaults, err := am.loadLLMConfigs(ctx, options.LLMProvider, options.LLMConfig, options.LLMChain)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := am.loadProfile(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make(map[string]orchestrator.StepRoute, len(models.OrchestrationSteps))
+	for _, step := range models.OrchestrationSteps {
+		route := orchestrator.StepRoute{LLMConfigs: defaults}
+
+		if settings, ok := profile.Steps[step]; ok {
+			llmConfigs, err := am.loadLLMConfigs(ctx, string(settings.LLMProvider), settings.LLMConfig, settings.LLMChain)
+			if err != nil {
+				return nil, fmt.Errorf("profile '%s', %s step: %w", profile.Name, step, err)
+			}
+			if llmConfigs != nil {
+				route.LLMConfigs = llmConfigs
+			}
+			route.Model = settings.Model
+			route.Temperature = settings.Temperature
+			route.MaxTokens = settings.MaxTokens
+		}
+
+		if len(route.LLMConfigs) == 0 {
+			return nil, fmt.Errorf("no LLM for the %s step: set llm_config or llm_chain, or use a profile that routes every step", step)
+		}
+		routes[step] = route
+	}
+	return routes, nil
+}
+
+// checkBudgets returns a *usage.BudgetExceededError when any LLM config the ask
+// would use, including every entry of a chain, has spent its monthly budget. An
+// ask whose LLM cannot be resolved passes; it fails with that error once it runs.
+func (am *AssistantManager) checkBudgets(ctx context.Context, request *models.AssistantRequest) error {
+	routes, err := am.resolveRoutes(ctx, request)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	checked := make(map[string]bool)
+	for _, step := range models.OrchestrationSteps {
+		for _, llmConfig := range routes[step].LLMConfigs {
+			key := string(llmConfig.Type) + "/" + llmConfig.Name
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			if err := usage.CheckBudget(ctx, am.storage, llmConfig, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadProfile returns the orchestration profile of an ask, or an empty profile
+// when neither the ask nor its database names one
+func (am *AssistantManager) loadProfile(ctx context.Context, request *models.AssistantRequest) (*models.OrchestrationProfile, error) {
+	name := request.Options.Profile
+	if name == "" {
+		dbConfig, err := am.storage.LoadDatabaseConfig(ctx, request.DBConfigurationName)
+		if err != nil && !errors.Is(err, storage.ErrConfigNotFound) {
+			return nil, fmt.Errorf("failed to load database configuration: %w", err)
+		}
+		if dbConfig != nil {
+			name = dbConfig.Profile
+		}
+	}
+	if name == "" {
+		return &models.OrchestrationProfile{}, nil
+	}
+
+	profile, err := am.storage.LoadOrchestrationProfile(ctx, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			return nil, fmt.Errorf("orchestration profile '%s' not found", name)
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// loadLLMConfigs returns LLM configurations in the order they are tried: the
+// entries of a fallback chain, or a single configuration. It returns nil when
+// neither is named.
+func (am *AssistantManager) loadLLMConfigs(ctx context.Context, provider, config, chainName string) ([]*models.LLMConfig, error) {
+	if chainName == "" {
+		if config == "" {
+			return nil, nil
+		}
+		llmConfig, err := am.loadLLMConfig(ctx, provider, config)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.LLMConfig{llmConfig}, nil
+	}
+
+	chain, err := am.storage.LoadLLMChain(ctx, chainName)
+	if err != nil {
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			return nil, fmt.Errorf("LLM chain '%s' not found", chainName)
+		}
+		return nil, err
+	}
+
+	llmConfigs := make([]*models.LLMConfig, 0, len(chain.Entries))
+	for _, entry := range chain.Entries {
+		llmConfig, err := am.loadLLMConfig(ctx, string(entry.Provider), entry.Config)
+		if err != nil {
+			return nil, fmt.Errorf("chain '%s': %w", chain.Name, err)
+		}
+		llmConfigs = append(llmConfigs, llmConfig)
+	}
+	return llmConfigs, nil
+}
+
+func (am *AssistantManager) loadLLMConfig(ctx context.Context, provider, name string) (*models.LLMConfig, error) {
+	llmConfigInterface, err := am.storage.LoadLLMConfig(ctx, provider, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			return nil, fmt.Errorf("LLM configuration '%s' not found for provider '%s'", name, provider)
+		}
+		return nil, err
+	}
+
+	// Storages return the config by value or by pointer
+	var llmConfig models.LLMConfig
+	switch c := llmConfigInterface.(type) {
+	case *models.LLMConfig:
+		llmConfig = *c
+	case models.LLMConfig:
+		llmConfig = c
+	default:
+		return nil, fmt.Errorf("invalid LLM configuration type %T", llmConfigInterface)
+	}
+
+	// The orchestrator picks the provider by type, which is the storage key
+	llmConfig.Type = models.LLMType(provider)
+	return &llmConfig, nil
+}
+
+// CancelAssistantRequest stops a queued or running ask
+// DELETE /assistant/ask/{uuid}
+func (am *AssistantManager) CancelAssistantRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("handler", "cancel_assistant_request"))
+
+	uuid := extractUUID(r.URL.Path)
+	if !isValidUUID(uuid) {
+		am.handleError(w, r, http.StatusBadRequest, "Invalid UUID format", nil)
+		return
+	}
+
+	if am.cancelAsk(uuid) {
+		// The orchestration goroutine records the cancelled status once it has stopped
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cancellation requested"})
+		return
+	}
+
+	response, err := am.storage.LoadAssistantResponse(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, storage.ErrResponseNotFound) {
+			am.handleError(w, r, http.StatusNotFound, "Response not found", err)
+			return
+		}
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to load response", err)
+		return
+	}
+
+	if response.Status != "in_progress" {
+		am.handleError(w, r, http.StatusConflict, fmt.Sprintf("Request is already %s", response.Status), nil)
+		return
+	}
+
+	// Nothing is running for this ask any more (e.g. after a restart), so it can be
+	// marked as cancelled right away
+	am.markCancelled(uuid)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Request cancelled"})
+}
+
+// errThreadNotFound is returned when a follow-up names a thread without any asks
+var errThreadNotFound = errors.New("thread not found")
+
+// saveThreadResponse saves a new ask at the end of the given thread, or as the first
+// ask of a new thread when threadID is empty
+func (am *AssistantManager) saveThreadResponse(ctx context.Context, threadID string, response *models.AssistantResponse) error {
+	am.threadMu.Lock()
+	defer am.threadMu.Unlock()
+
+	if threadID == "" {
+		response.ThreadID = uuid.New().String()
+		response.Sequence = 1
+	} else {
+		thread, err := am.storage.GetThreadResponses(ctx, threadID)
+		if err != nil {
+			return err
+		}
+		if len(thread) == 0 {
+			return errThreadNotFound
+		}
+		response.ThreadID = threadID
+		response.Sequence = thread[len(thread)-1].Sequence + 1
+	}
+
+	if err := am.storage.SaveAssistantResponse(ctx, *response); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (am *AssistantManager) trackAsk(uuid string, cancel context.CancelFunc) {
+	am.cancelMu.Lock()
+	defer am.cancelMu.Unlock()
+	am.cancels[uuid] = cancel
+}
+
+func (am *AssistantManager) untrackAsk(uuid string) {
+	am.cancelMu.Lock()
+	defer am.cancelMu.Unlock()
+	if cancel, ok := am.cancels[uuid]; ok {
+		cancel()
+		delete(am.cancels, uuid)
+	}
+}
+
+// cancelAsk cancels the context of a tracked ask and reports whether one was found
+func (am *AssistantManager) cancelAsk(uuid string) bool {
+	am.cancelMu.Lock()
+	defer am.cancelMu.Unlock()
+	cancel, ok := am.cancels[uuid]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (am *AssistantManager) markCancelled(uuid string) {
+	ctx := context.Background()
+	appender := source.NewResponseAppender(am.storage, am.broker)
+	if err := appender.AppendResponse(ctx, uuid, "step_output", "Request cancelled"); err != nil {
+		am.logger.WithError(err).Error("Failed to record cancellation")
+	}
+	if err := appender.UpdateStatus(ctx, uuid, "cancelled", false); err != nil {
+		am.logger.WithError(err).Error("Failed to save cancelled status")
+	}
+}
+
 func (am *AssistantManager) handleOrchestrationError(ctx context.Context, uuid string, message string, err error) {
+	if ctx.Err() == context.Canceled {
+		am.markCancelled(uuid)
+		return
+	}
+
 	am.logger.WithError(err).Error(message)
 
-	response := models.AssistantResponse{
-		UUID:    uuid,
-		Status:  "failed",
-		Success: false,
-		Response: []models.Update{{
-			Text:      fmt.Sprintf("%s: %v", message, err),
-			Timestamp: time.Now(),
-			Type:      "error",
-		}},
+	appender := source.NewResponseAppender(am.storage, am.broker)
+	if err := appender.AppendResponse(ctx, uuid, "error", fmt.Sprintf("%s: %v", message, err)); err != nil {
+		am.logger.WithError(err).Error("Failed to save error update")
 	}
-
-	if err := am.storage.SaveAssistantResponse(ctx, response); err != nil {
+	if err := appender.UpdateStatus(ctx, uuid, "failed", false); err != nil {
 		am.logger.WithError(err).Error("Failed to save error status")
 	}
 }
@@ -256,6 +551,33 @@ func (am *AssistantManager) GetAssistantHistories(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(histories)
 }
 
+// GetAssistantThread lists the asks of a conversation thread in order
+// GET /assistant/threads/{thread_id}
+func (am *AssistantManager) GetAssistantThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("handler", "get_assistant_thread"))
+
+	threadID := extractUUID(r.URL.Path)
+	if !isValidUUID(threadID) {
+		am.handleError(w, r, http.StatusBadRequest, "Invalid thread ID format", nil)
+		return
+	}
+
+	thread, err := am.storage.GetThreadResponses(ctx, threadID)
+	if err != nil {
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to load thread", err)
+		return
+	}
+	if len(thread) == 0 {
+		am.handleError(w, r, http.StatusNotFound, "Thread not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
 func (am *AssistantManager) handleError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
 	span := trace.SpanFromContext(r.Context())
 	if err != nil {
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sseHeartbeatInterval keeps idle event streams from being closed by proxies
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamAssistantEvents streams the updates of an ask as Server-Sent Events, followed
+// by a terminal status event once the ask has completed, failed or been cancelled.
+// Clients may resume with the Last-Event-ID header.
+// GET /assistant/ask/{uuid}/events
+func (am *AssistantManager) StreamAssistantEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("handler", "stream_assistant_events"))
+
+	uuid := extractUUID(strings.TrimSuffix(r.URL.Path, "/events"))
+	if !isValidUUID(uuid) {
+		am.handleError(w, r, http.StatusBadRequest, "Invalid UUID format", nil)
+		return
+	}
+
+	// Subscribe before loading the response so no update can slip in between
+	events, unsubscribe := am.broker.Subscribe(uuid)
+	defer unsubscribe()
+
+	response, err := am.storage.LoadAssistantResponse(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, storage.ErrResponseNotFound) {
+			am.handleError(w, r, http.StatusNotFound, "Response not found", err)
+			return
+		}
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to load response", err)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		am.handleError(w, r, http.StatusBadRequest, "Invalid Last-Event-ID header", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		lastID: lastEventID,
+	}
+
+	if err := stream.sendUpdates(response.Response); err != nil {
+		return
+	}
+	if isTerminalStatus(response.Status) {
+		stream.sendStatus(response.Status, response.Success)
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-heartbeat.C:
+			if err := stream.sendComment("keep-alive"); err != nil {
+				return
+			}
+
+		case event := <-events:
+			// Events are dropped for subscribers that fall behind, so fill any gap
+			// from storage before going on
+			if event.ID > stream.lastID+1 || (event.Update == nil && event.ID > stream.lastID) {
+				response, err := am.storage.LoadAssistantResponse(ctx, uuid)
+				if err != nil {
+					am.logger.WithError(err).Error("Failed to reload response for event stream")
+					return
+				}
+				if err := stream.sendUpdates(response.Response); err != nil {
+					return
+				}
+			}
+
+			if event.Update != nil && event.ID == stream.lastID+1 {
+				if err := stream.sendUpdate(event.ID, *event.Update); err != nil {
+					return
+				}
+			}
+
+			if isTerminalStatus(event.Status) {
+				stream.sendStatus(event.Status, event.Success)
+				return
+			}
+		}
+	}
+}
+
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+func parseLastEventID(header string) (int, error) {
+	if header == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(header)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("expected a non-negative integer, got %q", header)
+	}
+	return id, nil
+}
+
+// eventStream writes Server-Sent Events, tracking the ID of the last update sent
+type eventStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	lastID int
+}
+
+// sendUpdates sends the updates that come after the last one sent
+func (s *eventStream) sendUpdates(updates []models.Update) error {
+	for i := s.lastID; i < len(updates); i++ {
+		if err := s.sendUpdate(i+1, updates[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *eventStream) sendUpdate(id int, update models.Update) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: update\ndata: %s\n\n", id, data); err != nil {
+		return err
+	}
+	s.lastID = id
+	return s.rc.Flush()
+}
+
+func (s *eventStream) sendStatus(status string, success bool) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"status":  status,
+		"success": success,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", status, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *eventStream) sendComment(comment string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
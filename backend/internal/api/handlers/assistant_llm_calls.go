@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetAssistantLLMCalls returns the audit log of an ask: every LLM call it made
+// with the messages sent, the raw completion, usage, latency and error, oldest
+// first. Secrets were redacted when the calls were recorded.
+// GET /assistant/ask/{uuid}/llm-calls
+func (am *AssistantManager) GetAssistantLLMCalls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("handler", "get_assistant_llm_calls"))
+
+	uuid := extractUUID(strings.TrimSuffix(r.URL.Path, "/llm-calls"))
+	if !isValidUUID(uuid) {
+		am.handleError(w, r, http.StatusBadRequest, "Invalid UUID format", nil)
+		return
+	}
+
+	if _, err := am.storage.LoadAssistantResponse(ctx, uuid); err != nil {
+		if errors.Is(err, storage.ErrResponseNotFound) {
+			am.handleError(w, r, http.StatusNotFound, "Response not found", err)
+			return
+		}
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to load response", err)
+		return
+	}
+
+	calls, err := am.storage.GetLLMCalls(ctx, uuid)
+	if err != nil {
+		am.handleError(w, r, http.StatusInternalServerError, "Failed to load LLM calls", err)
+		return
+	}
+
+	// If no calls found, return empty array instead of null
+	if calls == nil {
+		calls = make([]models.LLMCall, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calls)
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func setupAssistantHandler() (*AssistantManager, storage.Storage) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	return NewAssistantManager(logger, store, nil, nil, source.NewBroker(), AssistantManagerConfig{
+		MaxConcurrentOrchestrations: 1,
+	}), store
+}
+
+func TestCancelAssistantRequest(t *testing.T) {
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test_span")
+	defer span.End()
+
+	cancelRequest := func(am *AssistantManager, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/assistant/ask/"+id, nil).WithContext(ctx)
+		rr := httptest.NewRecorder()
+		am.HandleAssistant(rr, req)
+		return rr
+	}
+
+	t.Run("running ask is cancelled through its context", func(t *testing.T) {
+		am, _ := setupAssistantHandler()
+		id := uuid.New().String()
+
+		askCtx, cancel := context.WithCancel(context.Background())
+		am.trackAsk(id, cancel)
+
+		rr := cancelRequest(am, id)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.ErrorIs(t, askCtx.Err(), context.Canceled)
+	})
+
+	t.Run("untracked in-progress ask is marked cancelled", func(t *testing.T) {
+		am, store := setupAssistantHandler()
+		id := uuid.New().String()
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:    id,
+			Success: true,
+			Status:  "in_progress",
+		}))
+
+		rr := cancelRequest(am, id)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		response, err := store.LoadAssistantResponse(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "cancelled", response.Status)
+		assert.False(t, response.Success)
+	})
+
+	t.Run("finished ask cannot be cancelled", func(t *testing.T) {
+		am, store := setupAssistantHandler()
+		id := uuid.New().String()
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:    id,
+			Success: true,
+			Status:  "completed",
+		}))
+
+		rr := cancelRequest(am, id)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("unknown ask", func(t *testing.T) {
+		am, _ := setupAssistantHandler()
+
+		assert.Equal(t, http.StatusNotFound, cancelRequest(am, uuid.New().String()).Code)
+		assert.Equal(t, http.StatusBadRequest, cancelRequest(am, "not-a-uuid").Code)
+	})
+}
+
+func TestStreamAssistantEvents(t *testing.T) {
+	ctx := context.Background()
+
+	readEvents := func(t *testing.T, body io.Reader, until string) []string {
+		var events []string
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "id: ") || strings.HasPrefix(line, "event: ") {
+				events = append(events, line)
+			}
+			if line == "event: "+until {
+				break
+			}
+		}
+		require.NoError(t, scanner.Err())
+		return events
+	}
+
+	t.Run("finished ask replays updates from Last-Event-ID", func(t *testing.T) {
+		am, store := setupAssistantHandler()
+		id := uuid.New().String()
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:    id,
+			Success: true,
+			Status:  "completed",
+			Response: []models.Update{
+				{Text: "first", Type: "step_output"},
+				{Text: "second", Type: "step_output"},
+				{Text: "report", Type: "final_response"},
+			},
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/assistant/ask/"+id+"/events", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		rr := httptest.NewRecorder()
+		am.HandleAssistant(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+		assert.Equal(t, []string{
+			"id: 2", "event: update",
+			"id: 3", "event: update",
+			"event: completed",
+		}, readEvents(t, rr.Body, "completed"))
+	})
+
+	t.Run("running ask pushes appended updates", func(t *testing.T) {
+		am, store := setupAssistantHandler()
+		id := uuid.New().String()
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:     id,
+			Success:  true,
+			Status:   "in_progress",
+			Response: []models.Update{{Text: "Processing your request...", Type: "step_output"}},
+		}))
+
+		server := httptest.NewServer(http.HandlerFunc(am.HandleAssistant))
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/assistant/ask/" + id + "/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// The stored update is only sent once the stream has subscribed
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "id: 1\n", line)
+
+		appender := source.NewResponseAppender(store, am.broker)
+		require.NoError(t, appender.AppendResponse(ctx, id, "step_output", "Generating SQL query..."))
+		require.NoError(t, appender.UpdateStatus(ctx, id, "failed", false))
+
+		assert.Equal(t, []string{
+			"event: update",
+			"id: 2", "event: update",
+			"event: failed",
+		}, readEvents(t, reader, "failed"))
+	})
+}
+
+func TestAssistantThreads(t *testing.T) {
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test_span")
+	defer span.End()
+
+	ask := func(am *AssistantManager, threadID string) *httptest.ResponseRecorder {
+		body := `{"db_configuration_name":"db","question":"q","options":{"llm_provider":"openai","llm_config":"missing"},"thread_id":"` + threadID + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/assistant/ask", strings.NewReader(body)).WithContext(ctx)
+		rr := httptest.NewRecorder()
+		am.HandleAssistant(rr, req)
+		return rr
+	}
+
+	getThread := func(am *AssistantManager, threadID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/assistant/threads/"+threadID, nil).WithContext(ctx)
+		rr := httptest.NewRecorder()
+		am.HandleAssistant(rr, req)
+		return rr
+	}
+
+	t.Run("follow-up asks are appended to the thread in order", func(t *testing.T) {
+		am, _ := setupAssistantHandler()
+
+		rr := ask(am, "")
+		require.Equal(t, http.StatusCreated, rr.Code)
+		var first models.AssistantResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&first))
+		require.NotEmpty(t, first.ThreadID)
+		assert.Equal(t, 1, first.Sequence)
+
+		rr = ask(am, first.ThreadID)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		var second models.AssistantResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&second))
+		assert.Equal(t, first.ThreadID, second.ThreadID)
+		assert.Equal(t, 2, second.Sequence)
+
+		rr = getThread(am, first.ThreadID)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var thread []models.AssistantResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&thread))
+		require.Len(t, thread, 2)
+		assert.Equal(t, first.UUID, thread[0].UUID)
+		assert.Equal(t, second.UUID, thread[1].UUID)
+	})
+
+	t.Run("unknown thread", func(t *testing.T) {
+		am, _ := setupAssistantHandler()
+
+		assert.Equal(t, http.StatusNotFound, ask(am, uuid.New().String()).Code)
+		assert.Equal(t, http.StatusNotFound, getThread(am, uuid.New().String()).Code)
+		assert.Equal(t, http.StatusBadRequest, getThread(am, "not-a-uuid").Code)
+	})
+}
+
+func TestAssistantBudget(t *testing.T) {
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test_span")
+	defer span.End()
+
+	am, store := setupAssistantHandler()
+	require.NoError(t, store.SaveLLMConfig(ctx, "openai", models.LLMConfig{
+		Name: "gpt-4o", Type: models.OpenAI, APIKey: "key", Model: "gpt-4o", MonthlyBudget: 5,
+	}))
+	require.NoError(t, store.SaveLLMUsage(ctx, models.LLMUsage{
+		Provider: models.OpenAI, Config: "gpt-4o", Cost: 5.25, CreatedAt: time.Now(),
+	}))
+
+	body := `{"db_configuration_name":"db","question":"q","options":{"llm_provider":"openai","llm_config":"gpt-4o"}}`
+	req := httptest.NewRequest(http.MethodPost, "/assistant/ask", strings.NewReader(body)).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	am.HandleAssistant(rr, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assert.Contains(t, rr.Body.String(), "LLM configuration 'gpt-4o' of provider 'openai' has spent $5.25 of its $5.00 monthly budget")
+
+	histories, err := store.GetAssistantHistories(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, histories)
+}
+
+func TestAssistantLLMCalls(t *testing.T) {
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test_span")
+	defer span.End()
+
+	am, store := setupAssistantHandler()
+	askID := uuid.New().String()
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{UUID: askID, Status: "completed"}))
+
+	getCalls := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/assistant/ask/"+id+"/llm-calls", nil).WithContext(ctx)
+		rr := httptest.NewRecorder()
+		am.HandleAssistant(rr, req)
+		return rr
+	}
+
+	rr := getCalls(askID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+
+	call := models.LLMCall{
+		ID:         uuid.New().String(),
+		AskID:      askID,
+		Step:       models.StepSQL,
+		Provider:   models.OpenAI,
+		Config:     "gpt-4o",
+		Model:      "gpt-4o-2024-08-06",
+		Messages:   []models.LLMCallMessage{{Role: "user", Content: "How many users?"}},
+		Completion: "<sql>SELECT count(*) FROM users</sql>",
+		LatencyMS:  812,
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	require.NoError(t, store.SaveLLMCall(ctx, call))
+
+	rr = getCalls(askID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var calls []models.LLMCall
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&calls))
+	require.Len(t, calls, 1)
+	assert.True(t, call.CreatedAt.Equal(calls[0].CreatedAt))
+	calls[0].CreatedAt = call.CreatedAt
+	assert.Equal(t, call, calls[0])
+
+	assert.Equal(t, http.StatusNotFound, getCalls(uuid.New().String()).Code)
+	assert.Equal(t, http.StatusBadRequest, getCalls("not-a-uuid").Code)
+}
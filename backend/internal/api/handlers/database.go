@@ -3,9 +3,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -21,13 +23,16 @@ type DatabaseManager struct {
 	logger    *logrus.Logger
 	validator *validator.Validate
 	storage   storage.Storage
+	// dataDir is the only directory SQLite and DuckDB databases may be read from
+	dataDir string
 }
 
-func NewDatabaseManager(logger *logrus.Logger, storage storage.Storage) *DatabaseManager {
+func NewDatabaseManager(logger *logrus.Logger, storage storage.Storage, dataDir string) *DatabaseManager {
 	return &DatabaseManager{
 		logger:    logger,
 		validator: validator.New(),
 		storage:   storage,
+		dataDir:   dataDir,
 	}
 }
 
@@ -70,6 +75,17 @@ func (dm *DatabaseManager) CreateDatabaseConfig(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if config.Profile != "" {
+		if _, err := dm.storage.LoadOrchestrationProfile(ctx, config.Profile); err != nil {
+			if errors.Is(err, storage.ErrConfigNotFound) {
+				dm.handleError(w, r, http.StatusBadRequest, fmt.Sprintf("Orchestration profile '%s' not found", config.Profile), nil)
+				return
+			}
+			dm.handleError(w, r, http.StatusInternalServerError, "Failed to load orchestration profile", err)
+			return
+		}
+	}
+
 	if err := dm.storage.SaveDatabaseConfig(ctx, config); err != nil {
 		if err == storage.ErrConfigExists {
 			dm.handleError(w, r, http.StatusConflict, "Configuration already exists", err)
@@ -88,6 +104,17 @@ func (dm *DatabaseManager) CreateDatabaseConfig(w http.ResponseWriter, r *http.R
 }
 
 func (dm *DatabaseManager) validateTypeOptions(config *models.DatabaseConfig) error {
+	if config.Path != "" && !config.Type.IsFileBased() {
+		return fmt.Errorf("path is not supported by %s databases", config.Type)
+	}
+	if config.Path != "" {
+		path, err := dm.resolveDataPath(config.Path)
+		if err != nil {
+			return err
+		}
+		config.Path = path
+	}
+
 	if config.Options == nil {
 		return nil
 	}
@@ -116,6 +143,8 @@ func (dm *DatabaseManager) validateTypeOptions(config *models.DatabaseConfig) er
 			return err
 		}
 		config.Options = opts
+	case models.SQLite, models.DuckDB:
+		return fmt.Errorf("%s databases have no options", config.Type)
 	default:
 		return fmt.Errorf("unsupported database type: %s", config.Type)
 	}
@@ -123,6 +152,37 @@ func (dm *DatabaseManager) validateTypeOptions(config *models.DatabaseConfig) er
 	return nil
 }
 
+// resolveDataPath resolves the path of a file database, relative to the data
+// directory unless absolute, and rejects it unless it lies inside the data directory
+// once symbolic links are followed
+func (dm *DatabaseManager) resolveDataPath(path string) (string, error) {
+	if dm.dataDir == "" {
+		return "", fmt.Errorf("no data directory is configured for file databases")
+	}
+	root, err := filepath.Abs(dm.dataDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid data directory: %w", err)
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("data directory is not available: %w", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("path %s does not exist", path)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the data directory", path)
+	}
+	return resolved, nil
+}
+
 func (dm *DatabaseManager) handleError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
 	span := trace.SpanFromContext(r.Context())
 	if err != nil {
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/shahariaazam/smart-insights/internal/api/models"
@@ -20,12 +22,17 @@ import (
 )
 
 func setupTestHandler() (*DatabaseManager, storage.Storage) {
+	return setupTestHandlerWithDataDir("")
+}
+
+// setupTestHandlerWithDataDir creates a handler that registers file databases from dataDir
+func setupTestHandlerWithDataDir(dataDir string) (*DatabaseManager, storage.Storage) {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	store := memory.NewMemoryStorage()
-	return NewDatabaseManager(logger, store), store
+	return NewDatabaseManager(logger, store, dataDir), store
 }
 
 func TestDatabaseHandlers(t *testing.T) {
@@ -187,3 +194,105 @@ func TestDatabaseHandlers(t *testing.T) {
 		}
 	})
 }
+
+func TestFileDatabaseConfigs(t *testing.T) {
+	// Only files inside the data directory may be registered
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "shop.sqlite"), nil, 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dataDir, "exports"), 0o755))
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.sqlite"), nil, 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.sqlite"), filepath.Join(dataDir, "link.sqlite")))
+	root, err := filepath.EvalSymlinks(dataDir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		status int
+		path   string
+	}{
+		{
+			name:   "sqlite with a path",
+			config: map[string]interface{}{"name": "shop", "type": "sqlite", "path": filepath.Join(dataDir, "shop.sqlite")},
+			status: http.StatusCreated,
+			path:   filepath.Join(root, "shop.sqlite"),
+		},
+		{
+			name:   "duckdb directory relative to the data directory",
+			config: map[string]interface{}{"name": "exports", "type": "duckdb", "path": "exports"},
+			status: http.StatusCreated,
+			path:   filepath.Join(root, "exports"),
+		},
+		{
+			name:   "file outside the data directory",
+			config: map[string]interface{}{"name": "secret", "type": "sqlite", "path": filepath.Join(outside, "secret.sqlite")},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "path escaping the data directory",
+			config: map[string]interface{}{"name": "secret", "type": "sqlite", "path": "exports/../../" + filepath.Base(outside) + "/secret.sqlite"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "symbolic link out of the data directory",
+			config: map[string]interface{}{"name": "secret", "type": "sqlite", "path": "link.sqlite"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing file",
+			config: map[string]interface{}{"name": "shop", "type": "sqlite", "path": "missing.sqlite"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "sqlite without a path",
+			config: map[string]interface{}{"name": "shop", "type": "sqlite"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "server database with a path",
+			config: map[string]interface{}{
+				"name": "pg", "type": "postgresql", "host": "localhost", "port": "5432",
+				"db_name": "shop", "username": "reader", "password": "secret", "path": "shop.sqlite",
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "server database without a host",
+			config: map[string]interface{}{"name": "pg", "type": "postgresql"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "options on a file database",
+			config: map[string]interface{}{"name": "shop", "type": "sqlite", "path": "shop.sqlite", "options": map[string]interface{}{"charset": "utf8"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestHandlerWithDataDir(dataDir)
+			body, err := json.Marshal(tt.config)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/databases", bytes.NewBuffer(body))
+			rr := httptest.NewRecorder()
+			handler.HandleDatabases(rr, req)
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+
+			if tt.status == http.StatusCreated {
+				saved, err := store.LoadDatabaseConfig(context.Background(), tt.config["name"].(string))
+				require.NoError(t, err)
+				assert.Equal(t, tt.path, saved.Path)
+			}
+		})
+	}
+
+	// Without a data directory no file database can be registered
+	handler, _ := setupTestHandler()
+	body, err := json.Marshal(map[string]interface{}{"name": "shop", "type": "sqlite", "path": filepath.Join(dataDir, "shop.sqlite")})
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.HandleDatabases(rr, httptest.NewRequest(http.MethodPost, "/databases", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/datasets"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// datasetFormMemory is how much of an upload is held in memory; the rest of the
+// file is buffered on disk while the form is parsed
+const datasetFormMemory = 8 << 20
+
+// DatasetManager handles the upload of CSV and Excel files as datasets
+type DatasetManager struct {
+	logger   *logrus.Logger
+	storage  storage.Storage
+	datasets *datasets.Manager
+	config   DatasetManagerConfig
+}
+
+// DatasetManagerConfig limits the uploads of a DatasetManager
+type DatasetManagerConfig struct {
+	// MaxUploadSize bounds the size of an upload request in bytes
+	MaxUploadSize int64
+	// DefaultTTL is how long datasets are kept when the upload names no ttl
+	DefaultTTL time.Duration
+	// MaxTTL bounds the ttl an upload may ask for
+	MaxTTL time.Duration
+}
+
+// NewDatasetManager creates a new instance of DatasetManager
+func NewDatasetManager(logger *logrus.Logger, storage storage.Storage, datasets *datasets.Manager, config DatasetManagerConfig) *DatasetManager {
+	return &DatasetManager{
+		logger:   logger,
+		storage:  storage,
+		datasets: datasets,
+		config:   config,
+	}
+}
+
+// HandleDatasets routes requests for datasets
+func (dm *DatasetManager) HandleDatasets(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/datasets"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		dm.CreateDataset(w, r)
+	case r.Method == http.MethodGet && name == "":
+		dm.GetDatasets(w, r)
+	case r.Method == http.MethodGet:
+		dm.GetDataset(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		dm.DeleteDataset(w, r, name)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// CreateDataset loads an uploaded CSV or Excel file into a new dataset, which can
+// then be asked about as the database config of the same name. The multipart form
+// holds the file, and optionally the name of the dataset and a ttl after which it
+// is deleted, e.g. 24h.
+// POST /datasets
+func (dm *DatasetManager) CreateDataset(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, dm.config.MaxUploadSize)
+	if err := r.ParseMultipartForm(datasetFormMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			dm.handleError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds the limit of %d bytes", maxBytesErr.Limit), nil)
+			return
+		}
+		dm.handleError(w, r, http.StatusBadRequest, "Invalid multipart form", err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		dm.handleError(w, r, http.StatusBadRequest, "Missing file", err)
+		return
+	}
+	defer file.Close()
+
+	ttl := dm.config.DefaultTTL
+	if value := r.FormValue("ttl"); value != "" {
+		if ttl, err = time.ParseDuration(value); err != nil {
+			dm.handleError(w, r, http.StatusBadRequest, "Invalid ttl", err)
+			return
+		}
+	}
+	if ttl <= 0 || ttl > dm.config.MaxTTL {
+		dm.handleError(w, r, http.StatusBadRequest, fmt.Sprintf("ttl must be positive and at most %s", dm.config.MaxTTL), nil)
+		return
+	}
+
+	dataset, err := dm.datasets.Create(r.Context(), r.FormValue("name"), header.Filename, file, ttl)
+	switch {
+	case errors.Is(err, storage.ErrConfigExists):
+		dm.handleError(w, r, http.StatusConflict, "Database configuration already exists", err)
+		return
+	case errors.Is(err, datasets.ErrUnsupportedFormat), errors.Is(err, datasets.ErrInvalidName), errors.Is(err, datasets.ErrInvalidFile):
+		dm.handleError(w, r, http.StatusBadRequest, "Failed to load dataset", err)
+		return
+	case err != nil:
+		dm.handleError(w, r, http.StatusInternalServerError, "Failed to create dataset", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dataset)
+}
+
+// GET /datasets
+func (dm *DatasetManager) GetDatasets(w http.ResponseWriter, r *http.Request) {
+	list, err := dm.storage.GetDatasets(r.Context())
+	if err != nil {
+		dm.handleError(w, r, http.StatusInternalServerError, "Failed to load datasets", err)
+		return
+	}
+
+	// If no datasets found, return empty array instead of null
+	if list == nil {
+		list = make([]models.Dataset, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GET /datasets/{name}
+func (dm *DatasetManager) GetDataset(w http.ResponseWriter, r *http.Request, name string) {
+	dataset, err := dm.storage.LoadDataset(r.Context(), name)
+	if errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Dataset not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dm.handleError(w, r, http.StatusInternalServerError, "Failed to load dataset", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dataset)
+}
+
+// DeleteDataset deletes a dataset together with its database config
+// DELETE /datasets/{name}
+func (dm *DatasetManager) DeleteDataset(w http.ResponseWriter, r *http.Request, name string) {
+	if err := dm.datasets.Delete(r.Context(), name); errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Dataset not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		dm.handleError(w, r, http.StatusInternalServerError, "Failed to delete dataset", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Dataset deleted successfully"})
+}
+
+func (dm *DatasetManager) handleError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
+	span := trace.SpanFromContext(r.Context())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, message)
+		dm.logger.WithError(err).Error(message)
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	http.Error(w, message, statusCode)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/datasets"
+	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasetHandlers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	registry := source.NewRegistry(store)
+	defer registry.Close()
+	manager, err := datasets.NewManager(t.TempDir(), store, registry)
+	require.NoError(t, err)
+	dm := NewDatasetManager(logger, store, manager, DatasetManagerConfig{
+		MaxUploadSize: 1024,
+		DefaultTTL:    time.Hour,
+		MaxTTL:        24 * time.Hour,
+	})
+
+	upload := func(fields map[string]string, fileName, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for key, value := range fields {
+			require.NoError(t, form.WriteField(key, value))
+		}
+		if fileName != "" {
+			part, err := form.CreateFormFile("file", fileName)
+			require.NoError(t, err)
+			_, err = io.WriteString(part, content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/datasets", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		dm.HandleDatasets(rr, req)
+		return rr
+	}
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		dm.HandleDatasets(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	t.Run("create", func(t *testing.T) {
+		rr := upload(map[string]string{"ttl": "2h"}, "Orders.csv", "id,total\n1,9.5\n2,12\n")
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		var dataset models.Dataset
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dataset))
+		assert.Equal(t, "orders", dataset.Name)
+		assert.Equal(t, int64(2), dataset.Rows)
+		assert.Equal(t, 2*time.Hour, dataset.ExpiresAt.Sub(dataset.CreatedAt))
+
+		// The dataset is registered as a database config
+		config, err := store.LoadDatabaseConfig(context.Background(), "orders")
+		require.NoError(t, err)
+		assert.Equal(t, models.DuckDB, config.Type)
+
+		rr = upload(nil, "orders.csv", "id\n1\n")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("invalid uploads", func(t *testing.T) {
+		rr := upload(map[string]string{"name": "notes"}, "", "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = upload(nil, "notes.txt", "hello")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = upload(map[string]string{"ttl": "48h"}, "late.csv", "id\n1\n")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = upload(map[string]string{"ttl": "soon"}, "late.csv", "id\n1\n")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = upload(nil, "large.csv", "id\n"+string(bytes.Repeat([]byte("1\n"), 1024)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rr := do(http.MethodGet, "/datasets")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var list []models.Dataset
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		require.Len(t, list, 1)
+		assert.Equal(t, "orders", list[0].Name)
+
+		rr = do(http.MethodGet, "/datasets/orders")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = do(http.MethodGet, "/datasets/missing")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rr := do(http.MethodDelete, "/datasets/orders")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		_, err := store.LoadDatabaseConfig(context.Background(), "orders")
+		assert.Error(t, err)
+
+		rr = do(http.MethodDelete, "/datasets/orders")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -68,7 +68,8 @@ func (lm *LLMManager) CreateLLMConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (lm *LLMManager) validateTypeOptions(config *models.LLMConfig) error {
-	if config.Options == nil {
+	// OpenAI-compatible endpoints and HTTP gateways cannot do without their options
+	if config.Options == nil && config.Type != models.OpenAICompatible && config.Type != models.HTTP {
 		return nil
 	}
 
@@ -88,6 +89,41 @@ func (lm *LLMManager) validateTypeOptions(config *models.LLMConfig) error {
 			"organization": opts.Organization,
 			"max_tokens":   opts.MaxTokens,
 		}
+	case models.OpenAICompatible:
+		var opts models.OpenAICompatibleOptions
+		if err := json.Unmarshal(optionsJSON, &opts); err != nil {
+			return err
+		}
+		if opts.BaseURL == "" {
+			return fmt.Errorf("base_url is required")
+		}
+		optionsMap = map[string]interface{}{
+			"base_url":          opts.BaseURL,
+			"headers":           opts.Headers,
+			"api_version":       opts.APIVersion,
+			"max_tokens":        opts.MaxTokens,
+			"structured_output": opts.StructuredOutput,
+		}
+	case models.HTTP:
+		var opts models.HTTPOptions
+		if err := json.Unmarshal(optionsJSON, &opts); err != nil {
+			return err
+		}
+		if opts.URL == "" || opts.RequestTemplate == "" || opts.ContentPath == "" {
+			return fmt.Errorf("url, request_template and content_path are required")
+		}
+		optionsMap = map[string]interface{}{
+			"url":                    opts.URL,
+			"method":                 opts.Method,
+			"headers":                opts.Headers,
+			"request_template":       opts.RequestTemplate,
+			"content_path":           opts.ContentPath,
+			"prompt_tokens_path":     opts.PromptTokensPath,
+			"completion_tokens_path": opts.CompletionTokensPath,
+			"total_tokens_path":      opts.TotalTokensPath,
+			"error_path":             opts.ErrorPath,
+			"max_tokens":             opts.MaxTokens,
+		}
 	case models.Anthropic:
 		var opts models.AnthropicOptions
 		if err := json.Unmarshal(optionsJSON, &opts); err != nil {
@@ -104,7 +140,6 @@ func (lm *LLMManager) validateTypeOptions(config *models.LLMConfig) error {
 			return err
 		}
 		optionsMap = map[string]interface{}{
-			"location":          opts.Location,
 			"temperature":       opts.Temperature,
 			"max_output_tokens": opts.MaxOutputTokens,
 		}
@@ -116,11 +151,30 @@ func (lm *LLMManager) validateTypeOptions(config *models.LLMConfig) error {
 		optionsMap = map[string]interface{}{
 			"region":         opts.Region,
 			"model_provider": opts.ModelProvider,
+			"endpoint":       opts.Endpoint,
 		}
 	default:
 		return fmt.Errorf("unsupported LLM type: %s", config.Type)
 	}
 
+	// Rate limits apply to every provider type
+	var limits models.RateLimitOptions
+	if err := json.Unmarshal(optionsJSON, &limits); err != nil {
+		return err
+	}
+	if err := lm.validator.Struct(limits); err != nil {
+		return err
+	}
+	for key, value := range map[string]int{
+		"requests_per_minute": limits.RequestsPerMinute,
+		"tokens_per_minute":   limits.TokensPerMinute,
+		"max_wait_seconds":    limits.MaxWaitSeconds,
+	} {
+		if value > 0 {
+			optionsMap[key] = value
+		}
+	}
+
 	config.Options = optionsMap
 	return nil
 }
@@ -158,7 +212,7 @@ func (lm *LLMManager) GetLLMConfigs(w http.ResponseWriter, r *http.Request) {
 	var allConfigs []models.LLMConfig
 
 	// Try to get configs for each provider
-	for _, provider := range []models.LLMType{models.OpenAI, models.Anthropic, models.Gemini, models.Bedrock} {
+	for _, provider := range []models.LLMType{models.OpenAI, models.Anthropic, models.Gemini, models.Bedrock, models.OpenAICompatible, models.HTTP} {
 		providerConfigs, err := lm.storage.GetLLMConfigs(r.Context(), string(provider))
 		if err != nil {
 			// Log the error but continue with other providers
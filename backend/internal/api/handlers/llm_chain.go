@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+)
+
+// HandleLLMChains routes requests for LLM fallback chains
+func (lm *LLMManager) HandleLLMChains(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/llm-chains"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		lm.CreateLLMChain(w, r)
+	case r.Method == http.MethodGet && name == "":
+		lm.GetLLMChains(w, r)
+	case r.Method == http.MethodGet:
+		lm.GetLLMChain(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		lm.DeleteLLMChain(w, r, name)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// CreateLLMChain stores a new fallback chain. Every entry must name an existing
+// LLM configuration.
+// POST /llm-chains
+func (lm *LLMManager) CreateLLMChain(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+	defer r.Body.Close()
+
+	var chain models.LLMChain
+	if err := json.Unmarshal(body, &chain); err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	if err := lm.validator.Struct(chain); err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	for _, entry := range chain.Entries {
+		_, err := lm.storage.LoadLLMConfig(r.Context(), string(entry.Provider), entry.Config)
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			lm.handleError(w, r, http.StatusBadRequest,
+				fmt.Sprintf("LLM configuration '%s' not found for provider '%s'", entry.Config, entry.Provider), nil)
+			return
+		}
+		if err != nil {
+			lm.handleError(w, r, http.StatusInternalServerError, "Failed to load configuration", err)
+			return
+		}
+	}
+
+	if err := lm.storage.SaveLLMChain(r.Context(), chain); err != nil {
+		if errors.Is(err, storage.ErrConfigExists) {
+			http.Error(w, "Chain already exists", http.StatusConflict)
+			return
+		}
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to save chain", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "LLM chain created successfully"})
+}
+
+// GET /llm-chains
+func (lm *LLMManager) GetLLMChains(w http.ResponseWriter, r *http.Request) {
+	chains, err := lm.storage.GetLLMChains(r.Context())
+	if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to load chains", err)
+		return
+	}
+
+	// If no chains found, return empty array instead of null
+	if chains == nil {
+		chains = make([]models.LLMChain, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chains)
+}
+
+// GET /llm-chains/{name}
+func (lm *LLMManager) GetLLMChain(w http.ResponseWriter, r *http.Request, name string) {
+	chain, err := lm.storage.LoadLLMChain(r.Context(), name)
+	if errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Chain not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to load chain", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chain)
+}
+
+// DELETE /llm-chains/{name}
+func (lm *LLMManager) DeleteLLMChain(w http.ResponseWriter, r *http.Request, name string) {
+	if err := lm.storage.DeleteLLMChain(r.Context(), name); errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Chain not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to delete chain", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "LLM chain deleted successfully"})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLMChainHandlers(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	require.NoError(t, store.SaveLLMConfig(ctx, "openai", models.LLMConfig{Name: "gpt-4o", Type: models.OpenAI, APIKey: "key", Model: "gpt-4o"}))
+	require.NoError(t, store.SaveLLMConfig(ctx, "anthropic", models.LLMConfig{Name: "claude", Type: models.Anthropic, APIKey: "key", Model: "claude-sonnet-4-5"}))
+	lm := NewLLMManager(logger, store)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		lm.HandleLLMChains(rr, req)
+		return rr
+	}
+
+	t.Run("create", func(t *testing.T) {
+		rr := do(http.MethodPost, "/llm-chains", `{"name": "resilient", "entries": [
+			{"provider": "openai", "config": "gpt-4o"},
+			{"provider": "anthropic", "config": "claude"}
+		]}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = do(http.MethodPost, "/llm-chains", `{"name": "resilient", "entries": [{"provider": "openai", "config": "gpt-4o"}]}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("entries must exist", func(t *testing.T) {
+		rr := do(http.MethodPost, "/llm-chains", `{"name": "broken", "entries": [{"provider": "gemini", "config": "missing"}]}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "'missing' not found")
+
+		rr = do(http.MethodPost, "/llm-chains", `{"name": "empty", "entries": []}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rr := do(http.MethodGet, "/llm-chains/resilient", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var chain models.LLMChain
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&chain))
+		assert.Equal(t, []models.LLMChainEntry{
+			{Provider: models.OpenAI, Config: "gpt-4o"},
+			{Provider: models.Anthropic, Config: "claude"},
+		}, chain.Entries)
+
+		rr = do(http.MethodGet, "/llm-chains", "")
+		var chains []models.LLMChain
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&chains))
+		assert.Len(t, chains, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/llm-chains/resilient", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/llm-chains/resilient", "").Code)
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLMRateLimitOptions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	lm := NewLLMManager(logger, store)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/llm", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		lm.HandleLLM(rr, req)
+		return rr
+	}
+
+	rr := create(`{"name": "limited", "type": "anthropic", "api_key": "key", "model": "claude-sonnet-4-5",
+		"options": {"max_tokens_to_sample": 1024, "requests_per_minute": 50, "tokens_per_minute": 40000, "unknown": true}}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	loaded, err := store.LoadLLMConfig(context.Background(), "anthropic", "limited")
+	require.NoError(t, err)
+	saved, ok := loaded.(models.LLMConfig)
+	require.True(t, ok)
+	assert.Equal(t, 50, saved.Options["requests_per_minute"])
+	assert.Equal(t, 40000, saved.Options["tokens_per_minute"])
+	assert.NotContains(t, saved.Options, "max_wait_seconds")
+	assert.NotContains(t, saved.Options, "unknown")
+
+	rr = create(`{"name": "negative", "type": "openai", "api_key": "key", "model": "gpt-4o", "options": {"requests_per_minute": -1}}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
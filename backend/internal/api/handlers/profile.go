@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+)
+
+// HandleProfiles routes requests for orchestration profiles
+func (lm *LLMManager) HandleProfiles(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/orchestration-profiles"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		lm.CreateProfile(w, r)
+	case r.Method == http.MethodGet && name == "":
+		lm.GetProfiles(w, r)
+	case r.Method == http.MethodGet:
+		lm.GetProfile(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		lm.DeleteProfile(w, r, name)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// CreateProfile stores a new orchestration profile. Every LLM config or chain it
+// routes a step to must exist.
+// POST /orchestration-profiles
+func (lm *LLMManager) CreateProfile(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+	defer r.Body.Close()
+
+	var profile models.OrchestrationProfile
+	if err := json.Unmarshal(body, &profile); err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	if err := lm.validator.Struct(profile); err != nil {
+		lm.handleError(w, r, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	for step, settings := range profile.Steps {
+		// The validator does not descend into struct values of a map
+		if err := lm.validator.Struct(settings); err != nil {
+			lm.handleError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid %s step", step), err)
+			return
+		}
+		if err := lm.checkStepLLM(r.Context(), settings); err != nil {
+			if errors.Is(err, storage.ErrConfigNotFound) {
+				lm.handleError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid %s step", step), err)
+				return
+			}
+			lm.handleError(w, r, http.StatusInternalServerError, "Failed to load configuration", err)
+			return
+		}
+	}
+
+	if err := lm.storage.SaveOrchestrationProfile(r.Context(), profile); err != nil {
+		if errors.Is(err, storage.ErrConfigExists) {
+			http.Error(w, "Profile already exists", http.StatusConflict)
+			return
+		}
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to save profile", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Orchestration profile created successfully"})
+}
+
+// checkStepLLM makes sure the LLM config or chain of a step exists
+func (lm *LLMManager) checkStepLLM(ctx context.Context, settings models.StepSettings) error {
+	switch {
+	case settings.LLMChain != "":
+		if _, err := lm.storage.LoadLLMChain(ctx, settings.LLMChain); err != nil {
+			return fmt.Errorf("LLM chain '%s': %w", settings.LLMChain, err)
+		}
+	case settings.LLMConfig != "":
+		if _, err := lm.storage.LoadLLMConfig(ctx, string(settings.LLMProvider), settings.LLMConfig); err != nil {
+			return fmt.Errorf("LLM configuration '%s' for provider '%s': %w", settings.LLMConfig, settings.LLMProvider, err)
+		}
+	}
+	return nil
+}
+
+// GET /orchestration-profiles
+func (lm *LLMManager) GetProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := lm.storage.GetOrchestrationProfiles(r.Context())
+	if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to load profiles", err)
+		return
+	}
+
+	// If no profiles found, return empty array instead of null
+	if profiles == nil {
+		profiles = make([]models.OrchestrationProfile, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// GET /orchestration-profiles/{name}
+func (lm *LLMManager) GetProfile(w http.ResponseWriter, r *http.Request, name string) {
+	profile, err := lm.storage.LoadOrchestrationProfile(r.Context(), name)
+	if errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to load profile", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// DELETE /orchestration-profiles/{name}
+func (lm *LLMManager) DeleteProfile(w http.ResponseWriter, r *http.Request, name string) {
+	if err := lm.storage.DeleteOrchestrationProfile(r.Context(), name); errors.Is(err, storage.ErrConfigNotFound) {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		lm.handleError(w, r, http.StatusInternalServerError, "Failed to delete profile", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Orchestration profile deleted successfully"})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileHandlers(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	require.NoError(t, store.SaveLLMConfig(ctx, "openai", models.LLMConfig{Name: "gpt-4o", Type: models.OpenAI, APIKey: "key", Model: "gpt-4o"}))
+	require.NoError(t, store.SaveLLMConfig(ctx, "openai", models.LLMConfig{Name: "gpt-4o-mini", Type: models.OpenAI, APIKey: "key", Model: "gpt-4o-mini"}))
+	lm := NewLLMManager(logger, store)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		lm.HandleProfiles(rr, req)
+		return rr
+	}
+
+	t.Run("create", func(t *testing.T) {
+		rr := do(http.MethodPost, "/orchestration-profiles", `{"name": "balanced", "steps": {
+			"sql": {"llm_provider": "openai", "llm_config": "gpt-4o", "temperature": 0},
+			"report": {"llm_provider": "openai", "llm_config": "gpt-4o-mini", "max_tokens": 800}
+		}}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = do(http.MethodPost, "/orchestration-profiles", `{"name": "balanced", "steps": {"sql": {"model": "gpt-4o"}}}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		rr := do(http.MethodPost, "/orchestration-profiles", `{"name": "unknown", "steps": {"summary": {"model": "gpt-4o"}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = do(http.MethodPost, "/orchestration-profiles", `{"name": "missing", "steps": {"sql": {"llm_provider": "openai", "llm_config": "missing"}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Invalid sql step")
+
+		rr = do(http.MethodPost, "/orchestration-profiles", `{"name": "hot", "steps": {"report": {"temperature": 3}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rr := do(http.MethodGet, "/orchestration-profiles/balanced", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var profile models.OrchestrationProfile
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&profile))
+		assert.Equal(t, "gpt-4o-mini", profile.Steps[models.StepReport].LLMConfig)
+		assert.Equal(t, 800, profile.Steps[models.StepReport].MaxTokens)
+
+		rr = do(http.MethodGet, "/orchestration-profiles", "")
+		var profiles []models.OrchestrationProfile
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&profiles))
+		assert.Len(t, profiles, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/orchestration-profiles/balanced", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/orchestration-profiles/balanced", "").Code)
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/shahariaazam/smart-insights/internal/usage"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UsageManager reports the token usage and cost of LLM calls
+type UsageManager struct {
+	logger  *logrus.Logger
+	storage storage.Storage
+}
+
+// NewUsageManager creates a new instance of UsageManager
+func NewUsageManager(logger *logrus.Logger, storage storage.Storage) *UsageManager {
+	return &UsageManager{
+		logger:  logger,
+		storage: storage,
+	}
+}
+
+// HandleUsage routes usage requests
+func (um *UsageManager) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/usage":
+		um.GetUsage(w, r)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// GetUsage adds up LLM usage in total, per LLM config and per day. The query
+// parameters llm_provider, llm_config and db_config filter the calls; from and to
+// are inclusive dates (2006-01-02) or RFC 3339 times.
+// GET /usage
+func (um *UsageManager) GetUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.UsageFilter{
+		Provider: models.LLMType(query.Get("llm_provider")),
+		Config:   query.Get("llm_config"),
+		DBConfig: query.Get("db_config"),
+	}
+
+	var err error
+	if filter.From, err = parseUsageTime(query.Get("from"), false); err != nil {
+		um.handleError(w, r, http.StatusBadRequest, "Invalid from", err)
+		return
+	}
+	if filter.To, err = parseUsageTime(query.Get("to"), true); err != nil {
+		um.handleError(w, r, http.StatusBadRequest, "Invalid to", err)
+		return
+	}
+
+	records, err := um.storage.GetLLMUsage(r.Context(), filter)
+	if err != nil {
+		um.handleError(w, r, http.StatusInternalServerError, "Failed to load usage", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage.Report(records))
+}
+
+// parseUsageTime parses a date or an RFC 3339 time. A date used as the end of a
+// range includes the whole day.
+func parseUsageTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2006-01-02 or an RFC 3339 time, got %q", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func (um *UsageManager) handleError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
+	span := trace.SpanFromContext(r.Context())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, message)
+		um.logger.WithError(err).Error(message)
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+
+	http.Error(w, message, statusCode)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageHandlers(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewMemoryStorage()
+	for _, record := range []models.LLMUsage{
+		{AskID: "a", Provider: models.OpenAI, Config: "gpt-4o", DBConfig: "sales", TotalTokens: 100, Cost: 1, CreatedAt: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)},
+		{AskID: "a", Provider: models.OpenAI, Config: "gpt-4o-mini", DBConfig: "sales", TotalTokens: 50, Cost: 0.1, CreatedAt: time.Date(2026, 10, 1, 10, 1, 0, 0, time.UTC)},
+		{AskID: "b", Provider: models.OpenAI, Config: "gpt-4o", DBConfig: "hr", TotalTokens: 200, Cost: 2, CreatedAt: time.Date(2026, 10, 3, 9, 0, 0, 0, time.UTC)},
+	} {
+		require.NoError(t, store.SaveLLMUsage(ctx, record))
+	}
+	um := NewUsageManager(logger, store)
+
+	get := func(query string) (*httptest.ResponseRecorder, models.UsageReport) {
+		req := httptest.NewRequest(http.MethodGet, "/usage"+query, nil)
+		rr := httptest.NewRecorder()
+		um.HandleUsage(rr, req)
+
+		var report models.UsageReport
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+		}
+		return rr, report
+	}
+
+	t.Run("all usage", func(t *testing.T) {
+		rr, report := get("")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 3, report.Total.Calls)
+		assert.Equal(t, 350, report.Total.TotalTokens)
+		assert.Len(t, report.ByConfig, 2)
+		assert.Len(t, report.ByDay, 2)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		_, report := get("?llm_provider=openai&llm_config=gpt-4o")
+		assert.Equal(t, 2, report.Total.Calls)
+		assert.InDelta(t, 3.0, report.Total.Cost, 1e-9)
+
+		_, report = get("?db_config=sales")
+		assert.Equal(t, 2, report.Total.Calls)
+
+		// to is an inclusive date
+		_, report = get("?from=2026-10-01&to=2026-10-01")
+		assert.Equal(t, 2, report.Total.Calls)
+
+		_, report = get("?from=2026-10-02T00:00:00Z")
+		assert.Equal(t, 1, report.Total.Calls)
+		assert.Equal(t, []models.DailyUsage{{Date: "2026-10-03", UsageSummary: report.Total}}, report.ByDay)
+	})
+
+	t.Run("invalid dates", func(t *testing.T) {
+		rr, _ := get("?from=yesterday")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
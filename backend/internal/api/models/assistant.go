@@ -3,14 +3,25 @@ package models
 import "time"
 
 type AssistantRequestOptions struct {
-	LLMProvider string `json:"llm_provider" validate:"required,oneof=openai anthropic gemini bedrock"`
-	LLMConfig   string `json:"llm_config" validate:"required"`
+	LLMProvider string `json:"llm_provider,omitempty" validate:"required_with=LLMConfig,omitempty,oneof=openai anthropic gemini bedrock openai_compatible http"`
+	LLMConfig   string `json:"llm_config,omitempty" validate:"excluded_with=LLMChain"`
+	// LLMChain names a fallback chain to use instead of a single LLM config
+	LLMChain string `json:"llm_chain,omitempty"`
+	// Profile routes the pipeline steps to their own LLM settings, overriding the
+	// default profile of the database. Steps the profile does not route use the LLM
+	// config or chain above, so one of them is needed unless every step is routed.
+	Profile string `json:"profile,omitempty"`
+	// Mode is "prompt" (default) to send the whole schema, or "agent" to let the
+	// model explore the database through tools
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=prompt agent"`
 }
 
 type AssistantRequest struct {
 	DBConfigurationName string                  `json:"db_configuration_name" validate:"required"`
 	Question            string                  `json:"question" validate:"required"`
 	Options             AssistantRequestOptions `json:"options" validate:"required"`
+	// ThreadID continues an existing conversation. A new thread is started when empty.
+	ThreadID string `json:"thread_id,omitempty" validate:"omitempty,uuid"`
 }
 
 type AssistantResponse struct {
@@ -19,6 +30,17 @@ type AssistantResponse struct {
 	Success  bool     `json:"success"`
 	Status   string   `json:"status"`
 	Response []Update `json:"response,omitempty"`
+	// ThreadID and Sequence place the ask in its conversation, starting at 1
+	ThreadID string `json:"thread_id,omitempty"`
+	Sequence int    `json:"sequence,omitempty"`
+	// SQLQuery and ResultSummary are carried into follow-up questions of the thread
+	SQLQuery      string `json:"sql_query,omitempty"`
+	ResultSummary string `json:"result_summary,omitempty"`
+	// AnsweredBy is the LLM that answered the latest LLM call of the ask, which is
+	// not the first entry of its chain when that one failed
+	AnsweredBy *LLMReference `json:"answered_by,omitempty"`
+	// Usage adds up the tokens and cost of the LLM calls of the ask
+	Usage *UsageSummary `json:"usage,omitempty"`
 }
 
 type Update struct {
@@ -7,18 +7,32 @@ const (
 	PostgreSQL DatabaseType = "postgresql"
 	MySQL      DatabaseType = "mysql"
 	MongoDB    DatabaseType = "mongodb"
+	// SQLite and DuckDB are read from a local file instead of a server
+	SQLite DatabaseType = "sqlite"
+	DuckDB DatabaseType = "duckdb"
 )
 
+// IsFileBased reports whether databases of type t are configured with a Path
+// rather than a server address and credentials
+func (t DatabaseType) IsFileBased() bool {
+	return t == SQLite || t == DuckDB
+}
+
 // DatabaseConfig represents the base configuration
 type DatabaseConfig struct {
 	Name     string       `json:"name" validate:"required"`
-	Type     DatabaseType `json:"type" validate:"required,oneof=postgresql mysql mongodb"`
-	Host     string       `json:"host" validate:"required"`
-	Port     string       `json:"port" validate:"required"`
-	DBName   string       `json:"db_name" validate:"required"`
-	Username string       `json:"username" validate:"required"`
-	Password string       `json:"password" validate:"required"`
-	Options  interface{}  `json:"options,omitempty"` // Type-specific options
+	Type     DatabaseType `json:"type" validate:"required,oneof=postgresql mysql mongodb sqlite duckdb"`
+	Host     string       `json:"host" validate:"required_without=Path"`
+	Port     string       `json:"port" validate:"required_without=Path"`
+	DBName   string       `json:"db_name" validate:"required_without=Path"`
+	Username string       `json:"username" validate:"required_without=Path"`
+	Password string       `json:"password" validate:"required_without=Path"`
+	// Path is the database file of a SQLite or DuckDB source. For DuckDB it may
+	// also be a directory of CSV and Parquet files, each read as a table.
+	Path    string      `json:"path,omitempty" validate:"required_if=Type sqlite,required_if=Type duckdb"`
+	Options interface{} `json:"options,omitempty"` // Type-specific options
+	// Profile is the orchestration profile of asks that do not name one
+	Profile string `json:"profile,omitempty"`
 }
 
 // PostgresConfig holds PostgreSQL-specific options
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// DatasetFormat is the file format of an uploaded dataset
+type DatasetFormat string
+
+const (
+	CSV  DatasetFormat = "csv"
+	XLSX DatasetFormat = "xlsx"
+)
+
+// Dataset is a CSV or Excel file uploaded to be asked about. Its rows are loaded
+// into a DuckDB table named after the dataset, and the DuckDB file is registered
+// as the database config of the same name until the dataset expires.
+type Dataset struct {
+	Name     string        `json:"name"`
+	FileName string        `json:"file_name"`
+	Format   DatasetFormat `json:"format"`
+	// Sheet is the worksheet loaded from an Excel file
+	Sheet string `json:"sheet,omitempty"`
+	// Size is the size of the uploaded file in bytes
+	Size      int64           `json:"size"`
+	Rows      int64           `json:"rows"`
+	Columns   []DatasetColumn `json:"columns"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// DatasetColumn is a column of a dataset with the type inferred from its values
+type DatasetColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
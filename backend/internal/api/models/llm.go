@@ -7,14 +7,31 @@ const (
 	Anthropic LLMType = "anthropic"
 	Gemini    LLMType = "gemini"
 	Bedrock   LLMType = "bedrock"
+	// OpenAICompatible is any server that speaks the OpenAI chat API, such as vLLM,
+	// Ollama, llama.cpp or an Azure OpenAI deployment
+	OpenAICompatible LLMType = "openai_compatible"
+	// HTTP is an in-house gateway described by a request template and response
+	// selectors
+	HTTP LLMType = "http"
 )
 
 type LLMConfig struct {
 	Name    string                 `json:"name" validate:"required"`
-	Type    LLMType                `json:"type" validate:"required,oneof=openai anthropic gemini bedrock"`
-	APIKey  string                 `json:"api_key" validate:"required"`
+	Type    LLMType                `json:"type" validate:"required,oneof=openai anthropic gemini bedrock openai_compatible http"`
+	APIKey  string                 `json:"api_key" validate:"required_if=Type openai,required_if=Type anthropic,required_if=Type gemini,required_if=Type bedrock"`
 	Model   string                 `json:"model" validate:"required"`
 	Options map[string]interface{} `json:"options,omitempty"`
+	// Pricing overrides the built-in price of the model
+	Pricing *LLMPricing `json:"pricing,omitempty"`
+	// MonthlyBudget in USD refuses new asks using the config once its cost in the
+	// current calendar month (UTC) reaches the budget. Zero means no budget.
+	MonthlyBudget float64 `json:"monthly_budget,omitempty" validate:"omitempty,gt=0"`
+}
+
+// LLMPricing is the price of a model in USD per million tokens
+type LLMPricing struct {
+	InputPerMillion  float64 `json:"input_per_million" validate:"min=0"`
+	OutputPerMillion float64 `json:"output_per_million" validate:"min=0"`
 }
 
 type OpenAIOptions struct {
@@ -22,6 +39,35 @@ type OpenAIOptions struct {
 	MaxTokens    int    `json:"max_tokens,omitempty"`
 }
 
+// OpenAICompatibleOptions points the OpenAI client at another endpoint. Setting
+// APIVersion selects Azure OpenAI, which takes the key in an api-key header and the
+// version as a query parameter; BaseURL is then the deployment URL.
+type OpenAICompatibleOptions struct {
+	BaseURL    string            `json:"base_url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	APIVersion string            `json:"api_version,omitempty"`
+	MaxTokens  int               `json:"max_tokens,omitempty"`
+	// StructuredOutput enables strict JSON schema response formats, which many
+	// compatible servers do not support
+	StructuredOutput bool `json:"structured_output,omitempty"`
+}
+
+// HTTPOptions describe a JSON gateway. RequestTemplate is a Go text/template
+// executed with the completion request, and the paths are JSONPath-style selectors
+// such as "$.output[0].text".
+type HTTPOptions struct {
+	URL                  string            `json:"url"`
+	Method               string            `json:"method,omitempty"`
+	Headers              map[string]string `json:"headers,omitempty"`
+	RequestTemplate      string            `json:"request_template"`
+	ContentPath          string            `json:"content_path"`
+	PromptTokensPath     string            `json:"prompt_tokens_path,omitempty"`
+	CompletionTokensPath string            `json:"completion_tokens_path,omitempty"`
+	TotalTokensPath      string            `json:"total_tokens_path,omitempty"`
+	ErrorPath            string            `json:"error_path,omitempty"`
+	MaxTokens            int               `json:"max_tokens,omitempty"`
+}
+
 type AnthropicOptions struct {
 	MaxTokensToSample int     `json:"max_tokens_to_sample,omitempty"`
 	Temperature       float64 `json:"temperature,omitempty"`
@@ -29,7 +75,6 @@ type AnthropicOptions struct {
 }
 
 type GeminiOptions struct {
-	Location        string  `json:"location,omitempty"`
 	Temperature     float64 `json:"temperature,omitempty"`
 	MaxOutputTokens int     `json:"max_output_tokens,omitempty"`
 }
@@ -37,4 +82,66 @@ type GeminiOptions struct {
 type BedrockOptions struct {
 	Region        string `json:"region,omitempty"`
 	ModelProvider string `json:"model_provider,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+}
+
+// RateLimitOptions cap how fast asks may call an LLM config, shared by every ask
+// using it. They can be set in the options of any provider type. Calls over the
+// limit wait in a queue for up to MaxWaitSeconds, a minute by default.
+type RateLimitOptions struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty" validate:"min=0"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty" validate:"min=0"`
+	MaxWaitSeconds    int `json:"max_wait_seconds,omitempty" validate:"min=0"`
+}
+
+// LLMChain is an ordered list of LLM configs. An ask using the chain falls through
+// to the next entry when an entry fails.
+type LLMChain struct {
+	Name    string          `json:"name" validate:"required"`
+	Entries []LLMChainEntry `json:"entries" validate:"required,min=1,dive"`
+}
+
+// LLMChainEntry names a stored LLM config
+type LLMChainEntry struct {
+	Provider LLMType `json:"provider" validate:"required,oneof=openai anthropic gemini bedrock openai_compatible http"`
+	Config   string  `json:"config" validate:"required"`
+}
+
+// LLMReference identifies the LLM config and model that answered an ask
+type LLMReference struct {
+	Provider LLMType `json:"provider"`
+	Config   string  `json:"config"`
+	Model    string  `json:"model"`
+}
+
+// Pipeline steps of an ask that make LLM calls
+const (
+	// StepSQL generates the query, in prompt and in agent mode
+	StepSQL = "sql"
+	// StepRepair corrects a query that failed to execute
+	StepRepair = "repair"
+	// StepReport explains the query result
+	StepReport = "report"
+)
+
+// OrchestrationSteps lists the steps a profile can route
+var OrchestrationSteps = []string{StepSQL, StepRepair, StepReport}
+
+// OrchestrationProfile routes the steps of an ask to their own LLM settings, e.g. a
+// strong model for SQL and a cheap one for the report. Steps without settings use
+// the LLM of the ask and the step's default temperature.
+type OrchestrationProfile struct {
+	Name  string                  `json:"name" validate:"required"`
+	Steps map[string]StepSettings `json:"steps" validate:"required,min=1,dive,keys,oneof=sql repair report,endkeys"`
+}
+
+// StepSettings configure the LLM calls of a step. The LLM is a single config or a
+// fallback chain; empty fields keep the defaults.
+type StepSettings struct {
+	LLMProvider LLMType  `json:"llm_provider,omitempty" validate:"required_with=LLMConfig,omitempty,oneof=openai anthropic gemini bedrock openai_compatible http"`
+	LLMConfig   string   `json:"llm_config,omitempty" validate:"excluded_with=LLMChain"`
+	LLMChain    string   `json:"llm_chain,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
+	MaxTokens   int      `json:"max_tokens,omitempty" validate:"omitempty,min=1"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// LLMCall is the audit record of one call to an LLM provider: what was sent, what
+// came back and how long it took. Retried attempts are recorded as calls of their
+// own. Secrets are redacted before the call is stored.
+type LLMCall struct {
+	ID       string           `json:"id"`
+	AskID    string           `json:"ask_id"`
+	Step     string           `json:"step,omitempty"`
+	Provider LLMType          `json:"provider"`
+	Config   string           `json:"config"`
+	Model    string           `json:"model"`
+	Messages []LLMCallMessage `json:"messages"`
+	// Completion is the raw content returned by the model, before any parsing
+	Completion       string            `json:"completion"`
+	ToolCalls        []LLMCallToolCall `json:"tool_calls,omitempty"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	TotalTokens      int               `json:"total_tokens"`
+	LatencyMS        int64             `json:"latency_ms"`
+	// Error is set when the call failed
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LLMCallMessage is a message sent to the model
+type LLMCallMessage struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []LLMCallToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+// LLMCallToolCall is a tool call requested by the model
+type LLMCallToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// LLMUsage records the tokens and cost of one LLM call
+type LLMUsage struct {
+	AskID            string    `json:"ask_id"`
+	Provider         LLMType   `json:"provider"`
+	Config           string    `json:"config"`
+	Model            string    `json:"model"`
+	DBConfig         string    `json:"db_config,omitempty"`
+	Step             string    `json:"step,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UsageSummary adds up the usage of several LLM calls. Cost is in USD.
+type UsageSummary struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add counts one LLM call into the summary
+func (s *UsageSummary) Add(usage LLMUsage) {
+	s.Calls++
+	s.PromptTokens += usage.PromptTokens
+	s.CompletionTokens += usage.CompletionTokens
+	s.TotalTokens += usage.TotalTokens
+	s.Cost += usage.Cost
+}
+
+// UsageFilter selects LLM usage records. Empty fields match everything; From is
+// inclusive and To exclusive.
+type UsageFilter struct {
+	Provider LLMType
+	Config   string
+	DBConfig string
+	From     time.Time
+	To       time.Time
+}
+
+// Matches reports whether a usage record is selected by the filter
+func (f UsageFilter) Matches(usage LLMUsage) bool {
+	switch {
+	case f.Provider != "" && usage.Provider != f.Provider:
+		return false
+	case f.Config != "" && usage.Config != f.Config:
+		return false
+	case f.DBConfig != "" && usage.DBConfig != f.DBConfig:
+		return false
+	case !f.From.IsZero() && usage.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !usage.CreatedAt.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// UsageReport is the usage selected by a filter, in total, per LLM config and per day
+type UsageReport struct {
+	Total    UsageSummary  `json:"total"`
+	ByConfig []ConfigUsage `json:"by_config"`
+	ByDay    []DailyUsage  `json:"by_day"`
+}
+
+// ConfigUsage is the usage of one LLM config
+type ConfigUsage struct {
+	Provider LLMType `json:"provider"`
+	Config   string  `json:"config"`
+	UsageSummary
+}
+
+// DailyUsage is the usage of one UTC day, formatted as 2006-01-02
+type DailyUsage struct {
+	Date string `json:"date"`
+	UsageSummary
+}
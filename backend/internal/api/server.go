@@ -5,16 +5,20 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"github.com/shahariaazam/smart-insights/config"
 	"github.com/shahariaazam/smart-insights/internal/api/handlers"
+	"github.com/shahariaazam/smart-insights/internal/datasets"
 	"github.com/shahariaazam/smart-insights/internal/llm"
+	"github.com/shahariaazam/smart-insights/internal/llm/ratelimit"
 	"github.com/shahariaazam/smart-insights/internal/llmregistry"
 	"github.com/shahariaazam/smart-insights/internal/middleware"
 	"github.com/shahariaazam/smart-insights/internal/source"
 	"github.com/shahariaazam/smart-insights/internal/storage/postgres"
 	"github.com/shahariaazam/smart-insights/internal/telemetry"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
 )
 
 type Server struct {
@@ -23,6 +27,7 @@ type Server struct {
 	router     *http.ServeMux
 	tel        *telemetry.Telemetry
 	store      *postgres.PostgresStorage
+	datasets   *datasets.Manager
 	staticPath string
 }
 
@@ -32,6 +37,9 @@ func NewServer(cfg *config.Config, logger *logrus.Logger) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize telemetry: %w", err)
 	}
+	if err := ratelimit.RegisterMetrics(otel.Meter("smart-insights")); err != nil {
+		return nil, fmt.Errorf("failed to register rate limit metrics: %w", err)
+	}
 
 	// Initialize storage
 	dbHost := cfg.DB_HOST
@@ -73,18 +81,33 @@ func (s *Server) setupRoutes() error {
 	// Initialize core components with PostgreSQL storage
 	sourceRegistry := source.NewRegistry(s.store)
 	llmRegistry := llmregistry.NewRegistry(s.store)
+	broker := source.NewBroker()
+
+	datasetManager, err := datasets.NewManager(s.cfg.DatasetDir, s.store, sourceRegistry)
+	if err != nil {
+		return err
+	}
+	s.datasets = datasetManager
 
 	// Initialize handlers
 	pingManager := handlers.NewPingManager(s.logger)
-	dbManager := handlers.NewDatabaseManager(s.logger, s.store)
+	dbManager := handlers.NewDatabaseManager(s.logger, s.store, s.cfg.DataDir)
 	llmManager := handlers.NewLLMManager(s.logger, s.store)
+	usageManager := handlers.NewUsageManager(s.logger, s.store)
+	datasetHandler := handlers.NewDatasetManager(s.logger, s.store, datasetManager, handlers.DatasetManagerConfig{
+		MaxUploadSize: s.cfg.MaxDatasetUploadSize,
+		DefaultTTL:    s.cfg.DatasetTTL,
+		MaxTTL:        s.cfg.MaxDatasetTTL,
+	})
 	assistantManager := handlers.NewAssistantManager(
 		s.logger,
 		s.store,
 		sourceRegistry,
 		llmRegistry,
+		broker,
 		handlers.AssistantManagerConfig{
 			MaxConcurrentOrchestrations: 10,
+			MaxQueryAttempts:            s.cfg.MaxQueryAttempts,
 		},
 	)
 
@@ -119,6 +142,13 @@ func (s *Server) setupRoutes() error {
 	s.router.Handle("/databases", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(dbManager.HandleDatabases)))
 	s.router.Handle("/llm/", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleLLM)))
 	s.router.Handle("/llm", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleLLM)))
+	s.router.Handle("/llm-chains/", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleLLMChains)))
+	s.router.Handle("/llm-chains", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleLLMChains)))
+	s.router.Handle("/orchestration-profiles/", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleProfiles)))
+	s.router.Handle("/orchestration-profiles", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(llmManager.HandleProfiles)))
+	s.router.Handle("/datasets/", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(datasetHandler.HandleDatasets)))
+	s.router.Handle("/datasets", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(datasetHandler.HandleDatasets)))
+	s.router.Handle("/usage", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(usageManager.HandleUsage)))
 	s.router.Handle("/assistant/", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(assistantManager.HandleAssistant)))
 	s.router.Handle("/assistant/ask", middleware.TelemetryMiddleware(s.tel)(http.HandlerFunc(assistantManager.HandleAssistant)))
 
@@ -128,6 +158,61 @@ func (s *Server) setupRoutes() error {
 	return nil
 }
 
+// llmCallPruneInterval is how often LLM calls past their retention are deleted
+const llmCallPruneInterval = time.Hour
+
+// pruneLLMCalls deletes the audit records of LLM calls older than the configured
+// retention, once at startup and then every llmCallPruneInterval until ctx is done
+func (s *Server) pruneLLMCalls(ctx context.Context) {
+	if s.cfg.LLMCallRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(llmCallPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.store.DeleteLLMCallsBefore(ctx, time.Now().Add(-s.cfg.LLMCallRetention))
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to delete expired LLM calls")
+		} else if deleted > 0 {
+			s.logger.Infof("Deleted %d LLM call(s) older than %s", deleted, s.cfg.LLMCallRetention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// datasetPruneInterval is how often expired datasets are deleted
+const datasetPruneInterval = 10 * time.Minute
+
+// pruneDatasets deletes the datasets past their expiry, once at startup and then
+// every datasetPruneInterval until ctx is done
+func (s *Server) pruneDatasets(ctx context.Context) {
+	ticker := time.NewTicker(datasetPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.datasets.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to delete expired datasets")
+		}
+		if deleted > 0 {
+			s.logger.Infof("Deleted %d expired dataset(s)", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.cfg.Port),
@@ -143,6 +228,9 @@ func (s *Server) Start(ctx context.Context) error {
 
 	s.logger.Infof("Server started on port %d", s.cfg.Port)
 
+	go s.pruneLLMCalls(ctx)
+	go s.pruneDatasets(ctx)
+
 	// Wait for context cancellation (shutdown signal)
 	<-ctx.Done()
 
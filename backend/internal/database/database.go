@@ -1,8 +1,18 @@
+// Package database gives access to the database providers. Importing it registers
+// the provider of every supported database type with dbregistry; a new type only
+// needs a provider package that registers itself and an import here.
 package database
 
 import (
 	"github.com/shahariaazam/smart-insights/internal/dbinterface"
 	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+
+	// Providers register themselves under their models.DatabaseType
+	_ "github.com/shahariaazam/smart-insights/internal/database/duckdb"
+	_ "github.com/shahariaazam/smart-insights/internal/database/mongodb"
+	_ "github.com/shahariaazam/smart-insights/internal/database/mysql"
+	_ "github.com/shahariaazam/smart-insights/internal/database/postgresql"
+	_ "github.com/shahariaazam/smart-insights/internal/database/sqlite"
 )
 
 // Re-export the interfaces
@@ -0,0 +1,263 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+)
+
+// DuckDBCredentials implements dbinterface.Credentials for a DuckDB database file
+// or a directory of CSV and Parquet files
+type DuckDBCredentials struct {
+	Path string
+}
+
+// NewCredentials builds DuckDB credentials from a stored database configuration
+func NewCredentials(config *models.DatabaseConfig) (*DuckDBCredentials, error) {
+	return &DuckDBCredentials{Path: config.Path}, nil
+}
+
+func (c *DuckDBCredentials) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+func (c *DuckDBCredentials) Type() string {
+	return string(models.DuckDB)
+}
+
+// fileReaders are the DuckDB table functions reading the files of a directory
+// source, by file extension
+var fileReaders = map[string]string{
+	".csv":     "read_csv_auto",
+	".tsv":     "read_csv_auto",
+	".parquet": "read_parquet",
+}
+
+// open opens the DuckDB database file at path read-only. When path is a directory,
+// its CSV and Parquet files are first loaded into a snapshot database, one table
+// per file named after it; the snapshot lives in snapshotDir until the provider is
+// closed, so later changes to the files are not seen.
+func open(ctx context.Context, path string) (db *sql.DB, snapshotDir string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open database file: %w", err)
+	}
+
+	dbPath := path
+	if info.IsDir() {
+		snapshotDir, err = os.MkdirTemp("", "smart-insights-duckdb-")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		dbPath = filepath.Join(snapshotDir, "snapshot.duckdb")
+		if err := snapshotFiles(path, dbPath); err != nil {
+			os.RemoveAll(snapshotDir)
+			return nil, "", err
+		}
+	}
+
+	dataSource, err := dsn(dbPath)
+	if err == nil {
+		db, err = sqlutil.Open(ctx, "duckdb", dataSource)
+	}
+	if err != nil {
+		if snapshotDir != "" {
+			os.RemoveAll(snapshotDir)
+		}
+		return nil, "", err
+	}
+	return db, snapshotDir, nil
+}
+
+// dsn builds the go-duckdb DSN opening the database file at path read-only,
+// without access to any other file
+func dsn(path string) (string, error) {
+	// go-duckdb takes everything before the first ? as the file name
+	if strings.Contains(path, "?") {
+		return "", fmt.Errorf("invalid path %q: DuckDB paths cannot contain '?'", path)
+	}
+	return path + "?access_mode=read_only&enable_external_access=false", nil
+}
+
+// snapshotFiles creates the DuckDB database dbPath with a table for each CSV and
+// Parquet file in dir
+func snapshotFiles(dir, dbPath string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	files := make(map[string]string)
+	var tables []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || fileReaders[ext] == "" {
+			continue
+		}
+		table := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if existing, ok := files[table]; ok {
+			return fmt.Errorf("files %s and %s would both be read as table %s", existing, entry.Name(), table)
+		}
+		files[table] = entry.Name()
+		tables = append(tables, table)
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("no CSV or Parquet files found in %s", dir)
+	}
+	sort.Strings(tables)
+
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot database: %w", err)
+	}
+	defer db.Close()
+
+	for _, table := range tables {
+		file := filepath.Join(dir, files[table])
+		reader := fileReaders[strings.ToLower(filepath.Ext(file))]
+		statement := fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s(%s)", sqlutil.QuoteIdentifier(table), reader, sqlutil.QuoteLiteral(file))
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to load %s: %w", files[table], err)
+		}
+	}
+	return nil
+}
+
+// DuckDBProvider implements dbinterface.Provider for a DuckDB database file or a
+// directory of CSV and Parquet files
+type DuckDBProvider struct {
+	db *sql.DB
+	// snapshotDir holds the snapshot of a directory of files, removed on close
+	snapshotDir string
+}
+
+// NewDuckDBProvider creates a new DuckDB provider
+func NewDuckDBProvider() *DuckDBProvider {
+	return &DuckDBProvider{}
+}
+
+func init() {
+	dbregistry.RegisterProvider(string(models.DuckDB), NewDuckDBProvider())
+}
+
+// NewCredentials implements dbinterface.Provider
+func (d *DuckDBProvider) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return NewCredentials(config)
+}
+
+func (d *DuckDBProvider) Connect(ctx context.Context, creds dbinterface.Credentials) error {
+	duckdbCreds, ok := creds.(*DuckDBCredentials)
+	if !ok {
+		return fmt.Errorf("invalid credentials type for DuckDB")
+	}
+
+	db, snapshotDir, err := open(ctx, duckdbCreds.Path)
+	if err != nil {
+		return err
+	}
+
+	d.db = db
+	d.snapshotDir = snapshotDir
+	return nil
+}
+
+func (d *DuckDBProvider) Close(ctx context.Context) error {
+	if d.db == nil {
+		return nil
+	}
+	err := d.db.Close()
+	if d.snapshotDir != "" {
+		os.RemoveAll(d.snapshotDir)
+	}
+	return err
+}
+
+// ExecuteQuery executes a SQL query and returns the results. The database is open
+// read-only and without access to other files, so anything the query validator may
+// have missed is refused. When ctx is cancelled the driver interrupts the query.
+func (d *DuckDBProvider) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := sqlutil.ReadRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range result.Rows {
+		for column, value := range row {
+			row[column] = normalizeValue(value)
+		}
+	}
+	return result, nil
+}
+
+// normalizeValue converts the DuckDB values that do not encode to plain JSON
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case duckdb.Decimal:
+		return v.Float64()
+	case *big.Int:
+		// HUGEINT, e.g. the sum of a BIGINT column
+		if v.IsInt64() {
+			return v.Int64()
+		}
+		return v.String()
+	case duckdb.Map:
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			values[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return values
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (d *DuckDBProvider) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// Dialect implements dbinterface.Provider
+func (d *DuckDBProvider) Dialect() string {
+	return prompt.DuckDB
+}
+
+// Clone creates a new instance of the DuckDB provider
+func (d *DuckDBProvider) Clone() dbinterface.Provider {
+	return NewDuckDBProvider()
+}
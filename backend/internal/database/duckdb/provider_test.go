@@ -0,0 +1,130 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connect connects a provider to the DuckDB file or directory at path and closes
+// it when the test ends
+func connect(t *testing.T, path string) *DuckDBProvider {
+	t.Helper()
+	ctx := context.Background()
+	provider := NewDuckDBProvider()
+	creds, err := provider.NewCredentials(&models.DatabaseConfig{Type: models.DuckDB, Path: path})
+	require.NoError(t, err)
+	require.NoError(t, provider.Connect(ctx, creds))
+	t.Cleanup(func() { provider.Close(ctx) })
+	return provider
+}
+
+func TestDuckDBProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.duckdb")
+	db, err := sql.Open("duckdb", path)
+	require.NoError(t, err)
+	for _, statement := range []string{
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL)",
+		"COMMENT ON TABLE customers IS 'People who ordered'",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers(id), total DECIMAL(10, 2))",
+		"INSERT INTO customers VALUES (1, 'Ada'), (2, 'Grace')",
+		"INSERT INTO orders VALUES (1, 1, 250.5), (2, 1, 20), (3, 2, 120)",
+	} {
+		_, err = db.Exec(statement)
+		require.NoError(t, err, statement)
+	}
+	require.NoError(t, db.Close())
+
+	ctx := context.Background()
+	provider := connect(t, path)
+	assert.Equal(t, prompt.DuckDB, provider.Dialect())
+
+	schema, err := provider.GetSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `Database Schema:
+
+Table: customers
+Description: People who ordered
+Columns:
+  - id INTEGER
+  - name VARCHAR
+Primary Key: id
+
+Table: orders
+Columns:
+  - id INTEGER
+  - customer_id INTEGER
+  - total DECIMAL(10,2)
+Primary Key: id
+Foreign Keys:
+  - (customer_id) references customers(id)
+
+`, prompt.FormatSchema(provider.Dialect(), schema))
+
+	result, err := provider.ExecuteQuery(ctx, "SELECT c.name, count(*) AS orders, sum(o.total) AS spent FROM orders o JOIN customers c ON c.id = o.customer_id GROUP BY c.name ORDER BY c.name")
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "Ada", "orders": int64(2), "spent": 270.5},
+		{"name": "Grace", "orders": int64(1), "spent": 120.0},
+	}, result.Rows)
+
+	// The file is open read-only and other files cannot be read
+	_, err = provider.ExecuteQuery(ctx, "DELETE FROM orders")
+	assert.Error(t, err)
+	_, err = provider.ExecuteQuery(ctx, "SELECT * FROM read_csv_auto('/etc/hosts')")
+	assert.Error(t, err)
+}
+
+func TestDuckDBDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sales 2024.csv"), []byte("region,amount\nnorth,10\nsouth,32\nnorth,5\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a table"), 0o644))
+
+	ctx := context.Background()
+	provider := connect(t, dir)
+
+	schema, err := provider.GetSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `Database Schema:
+
+Table: sales 2024
+Columns:
+  - region VARCHAR
+  - amount BIGINT
+
+`, prompt.FormatSchema(provider.Dialect(), schema))
+
+	result, err := provider.ExecuteQuery(ctx, `SELECT region, sum(amount) AS total FROM "sales 2024" GROUP BY region ORDER BY region`)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "north", result.Rows[0]["region"])
+	assert.Equal(t, int64(15), result.Rows[0]["total"])
+
+	_, err = provider.ExecuteQuery(ctx, `DROP TABLE "sales 2024"`)
+	assert.Error(t, err)
+
+	// The snapshot is removed with the provider
+	snapshotDir := provider.snapshotDir
+	require.DirExists(t, snapshotDir)
+	require.NoError(t, provider.Close(ctx))
+	assert.NoDirExists(t, snapshotDir)
+}
+
+func TestDuckDBDirectoryErrors(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	_, _, err := open(ctx, dir)
+	assert.ErrorContains(t, err, "no CSV or Parquet files found")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders.csv"), []byte("id\n1\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders.tsv"), []byte("id\n1\n"), 0o644))
+	_, _, err = open(ctx, dir)
+	assert.ErrorContains(t, err, "would both be read as table orders")
+}
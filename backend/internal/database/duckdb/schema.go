@@ -0,0 +1,150 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+)
+
+// GetSchema reads the tables and views of the database with their columns,
+// comments, primary keys and foreign keys from the DuckDB metadata functions.
+// Tables outside the main schema are named with their schema.
+func (d *DuckDBProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	tableRows, err := d.db.QueryContext(ctx, duckdbTablesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer tableRows.Close()
+
+	var names []string
+	tables := make(map[string]*dbinterface.TableInfo)
+	views := make(map[string]*dbinterface.ViewInfo)
+	for tableRows.Next() {
+		var name, tableType string
+		var comment sql.NullString
+		if err := tableRows.Scan(&name, &tableType, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		names = append(names, name)
+		if tableType == "VIEW" {
+			views[name] = &dbinterface.ViewInfo{Name: name, Description: comment.String}
+		} else {
+			tables[name] = &dbinterface.TableInfo{Name: name, Description: comment.String}
+		}
+	}
+	if err := tableRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	columnRows, err := d.db.QueryContext(ctx, duckdbColumnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer columnRows.Close()
+
+	for columnRows.Next() {
+		var tableName string
+		var column dbinterface.ColumnInfo
+		var comment sql.NullString
+		if err := columnRows.Scan(&tableName, &column.Name, &column.DataType, &column.IsNullable, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		column.Description = comment.String
+
+		if table, ok := tables[tableName]; ok {
+			table.Columns = append(table.Columns, column)
+		} else if view, ok := views[tableName]; ok {
+			view.Columns = append(view.Columns, column)
+		}
+	}
+	if err := columnRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	keyRows, err := d.db.QueryContext(ctx, duckdbKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query keys: %w", err)
+	}
+	defer keyRows.Close()
+
+	for keyRows.Next() {
+		var tableName, constraintType, columns string
+		var refTable, refColumns sql.NullString
+		if err := keyRows.Scan(&tableName, &constraintType, &columns, &refTable, &refColumns); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		table, ok := tables[tableName]
+		if !ok {
+			continue
+		}
+
+		if constraintType == "PRIMARY KEY" {
+			table.PrimaryKey = strings.Split(columns, ",")
+			continue
+		}
+		table.ForeignKeys = append(table.ForeignKeys, dbinterface.ForeignKeyInfo{
+			Name:           fmt.Sprintf("%s_fk_%d", tableName, len(table.ForeignKeys)),
+			ColumnNames:    strings.Split(columns, ","),
+			RefTableName:   refTable.String,
+			RefColumnNames: strings.Split(refColumns.String, ","),
+		})
+	}
+	if err := keyRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating keys: %w", err)
+	}
+
+	schema := &dbinterface.SchemaInfo{
+		Tables: make([]dbinterface.TableInfo, 0, len(tables)),
+		Views:  make([]dbinterface.ViewInfo, 0, len(views)),
+	}
+	for _, name := range names {
+		if table, ok := tables[name]; ok {
+			schema.Tables = append(schema.Tables, *table)
+		} else {
+			schema.Views = append(schema.Views, *views[name])
+		}
+	}
+	return schema, nil
+}
+
+// Queries to get the schema of a DuckDB database. Names outside the main schema
+// are qualified with their schema.
+const (
+	duckdbTablesQuery = `
+SELECT name, table_type, comment FROM (
+    SELECT CASE WHEN schema_name = 'main' THEN table_name ELSE schema_name || '.' || table_name END AS name,
+        'BASE TABLE' AS table_type, comment
+    FROM duckdb_tables()
+    WHERE database_name = current_database() AND NOT internal
+    UNION ALL
+    SELECT CASE WHEN schema_name = 'main' THEN view_name ELSE schema_name || '.' || view_name END,
+        'VIEW', comment
+    FROM duckdb_views()
+    WHERE database_name = current_database() AND NOT internal
+)
+ORDER BY name`
+
+	duckdbColumnsQuery = `
+SELECT CASE WHEN schema_name = 'main' THEN table_name ELSE schema_name || '.' || table_name END,
+    column_name, data_type, is_nullable, comment
+FROM duckdb_columns()
+WHERE database_name = current_database() AND NOT internal
+ORDER BY schema_name, table_name, column_index`
+
+	duckdbKeysQuery = `
+SELECT CASE WHEN schema_name = 'main' THEN table_name ELSE schema_name || '.' || table_name END,
+    constraint_type,
+    array_to_string(constraint_column_names, ','),
+    referenced_table,
+    array_to_string(referenced_column_names, ',')
+FROM duckdb_constraints()
+WHERE database_name = current_database() AND constraint_type IN ('PRIMARY KEY', 'FOREIGN KEY')
+ORDER BY schema_name, table_name, constraint_index`
+)
@@ -0,0 +1,285 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/shahariaazam/smart-insights/internal/sqlguard"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// pingTimeout bounds the checks that the deployment is reachable
+const pingTimeout = 10 * time.Second
+
+// MongoCredentials implements dbinterface.Credentials for MongoDB
+type MongoCredentials struct {
+	Host       string
+	Port       string
+	User       string
+	Password   string
+	DBName     string
+	AuthDB     string
+	AuthMech   string
+	ReplicaSet string
+	DirectConn bool
+}
+
+// NewCredentials builds MongoDB credentials from a stored database configuration
+func NewCredentials(config *models.DatabaseConfig) (*MongoCredentials, error) {
+	var options models.MongoDBConfig
+	if err := sqlutil.DecodeOptions(config, &options); err != nil {
+		return nil, err
+	}
+
+	return &MongoCredentials{
+		Host:       config.Host,
+		Port:       config.Port,
+		User:       config.Username,
+		Password:   config.Password,
+		DBName:     config.DBName,
+		AuthDB:     options.AuthDB,
+		AuthMech:   options.AuthMech,
+		ReplicaSet: options.ReplicaSet,
+		DirectConn: options.DirectConn,
+	}, nil
+}
+
+func (c *MongoCredentials) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if c.Port == "" {
+		return fmt.Errorf("port is required")
+	}
+	if c.DBName == "" {
+		return fmt.Errorf("database name is required")
+	}
+	return nil
+}
+
+func (c *MongoCredentials) Type() string {
+	return string(models.MongoDB)
+}
+
+// clientOptions builds the options of a client connecting with the credentials
+func (c *MongoCredentials) clientOptions() *options.ClientOptions {
+	clientOptions := options.Client().
+		SetHosts([]string{net.JoinHostPort(c.Host, c.Port)}).
+		SetAuth(options.Credential{
+			AuthMechanism: c.AuthMech,
+			AuthSource:    c.AuthDB,
+			Username:      c.User,
+			Password:      c.Password,
+		}).
+		SetDirect(c.DirectConn)
+	if c.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(c.ReplicaSet)
+	}
+	return clientOptions
+}
+
+// MongoProvider implements dbinterface.Provider for MongoDB. Queries are aggregation
+// pipelines in the form parsed by sqlguard.ParsePipeline.
+type MongoProvider struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// NewMongoProvider creates a new MongoDB provider
+func NewMongoProvider() *MongoProvider {
+	return &MongoProvider{}
+}
+
+func init() {
+	dbregistry.RegisterProvider(string(models.MongoDB), NewMongoProvider())
+}
+
+// NewCredentials implements dbinterface.Provider
+func (m *MongoProvider) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return NewCredentials(config)
+}
+
+func (m *MongoProvider) Connect(ctx context.Context, creds dbinterface.Credentials) error {
+	mongoCreds, ok := creds.(*MongoCredentials)
+	if !ok {
+		return fmt.Errorf("invalid credentials type for MongoDB")
+	}
+
+	client, err := mongo.Connect(mongoCreds.clientOptions())
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Verify connection
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := client.Ping(pingCtx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	m.client = client
+	m.db = client.Database(mongoCreds.DBName)
+	return nil
+}
+
+func (m *MongoProvider) Close(ctx context.Context) error {
+	if m.client != nil {
+		return m.client.Disconnect(ctx)
+	}
+	return nil
+}
+
+// ExecuteQuery runs an aggregation pipeline and returns one row per resulting
+// document, in the same form as the rows of SQL sources: nested documents are
+// flattened into dotted column names and BSON values converted to plain Go values.
+// Pipelines that could write are refused even if the caller did not validate them.
+// Pipelines take no arguments.
+func (m *MongoProvider) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	if err := sqlguard.ValidatePipeline(query); err != nil {
+		return nil, fmt.Errorf("query failed validation: %w", err)
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("aggregation pipelines take no arguments")
+	}
+	if m.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	pipeline, err := sqlguard.ParsePipeline(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stages are decoded as Extended JSON so that values such as {"$date": ...}
+	// keep their BSON type
+	stages := make(bson.A, 0, len(pipeline.Stages))
+	for i, stage := range pipeline.Stages {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(stage, false, &doc); err != nil {
+			return nil, fmt.Errorf("invalid stage %d: %w", i+1, err)
+		}
+		stages = append(stages, doc)
+	}
+
+	cursor, err := m.db.Collection(pipeline.Collection).Aggregate(ctx, stages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := &dbinterface.QueryResult{Rows: make([]map[string]interface{}, 0)}
+	seen := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode document: %w", err)
+		}
+		row := make(map[string]interface{}, len(doc))
+		flattenDocument(row, "", doc)
+		result.Rows = append(result.Rows, row)
+
+		// Documents may differ in their fields, so the columns are all fields found
+		for _, column := range columnNames("", doc) {
+			if !seen[column] {
+				seen[column] = true
+				result.Columns = append(result.Columns, column)
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+
+	return result, nil
+}
+
+func (m *MongoProvider) Ping(ctx context.Context) error {
+	if m.client == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return m.client.Ping(pingCtx, nil)
+}
+
+// Dialect implements dbinterface.Provider
+func (m *MongoProvider) Dialect() string {
+	return prompt.MongoDB
+}
+
+// Clone creates a new instance of the MongoDB provider
+func (m *MongoProvider) Clone() dbinterface.Provider {
+	return NewMongoProvider()
+}
+
+// flattenDocument adds the fields of doc to row, naming the fields of nested
+// documents by their dotted path
+func flattenDocument(row map[string]interface{}, prefix string, doc bson.D) {
+	for _, field := range doc {
+		name := prefix + field.Key
+		if nested, ok := field.Value.(bson.D); ok {
+			flattenDocument(row, name+".", nested)
+			continue
+		}
+		row[name] = normalizeValue(field.Value)
+	}
+}
+
+// normalizeValue converts a BSON value into a value that encodes to plain JSON
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		doc := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			doc[field.Key] = normalizeValue(field.Value)
+		}
+		return doc
+	case bson.A:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = normalizeValue(item)
+		}
+		return values
+	case bson.ObjectID:
+		return v.Hex()
+	case bson.DateTime:
+		return v.Time().UTC()
+	case bson.Decimal128:
+		return v.String()
+	case bson.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC()
+	case bson.Binary:
+		return v.Data
+	case bson.Regex:
+		return v.String()
+	case bson.Null, bson.Undefined:
+		return nil
+	default:
+		return v
+	}
+}
+
+// columnNames lists the column names flattenDocument gives the fields of doc, in
+// document order
+func columnNames(prefix string, doc bson.D) []string {
+	var names []string
+	for _, field := range doc {
+		name := prefix + field.Key
+		if nested, ok := field.Value.(bson.D); ok {
+			names = append(names, columnNames(name+".", nested)...)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
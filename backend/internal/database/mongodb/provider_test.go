@@ -0,0 +1,109 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestInferCollectionSchema(t *testing.T) {
+	id := bson.NewObjectID()
+	docs := []bson.D{
+		{
+			{Key: "_id", Value: id},
+			{Key: "total", Value: 12.5},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Ada"}, {Key: "email", Value: "ada@example.com"}}},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "sku", Value: "A1"}, {Key: "qty", Value: int32(1)}},
+				bson.D{{Key: "sku", Value: "B2"}, {Key: "qty", Value: int32(3)}},
+			}},
+		},
+		{
+			{Key: "_id", Value: id},
+			{Key: "total", Value: int32(8)},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Grace"}}},
+			{Key: "items", Value: bson.A{}},
+		},
+		{
+			{Key: "_id", Value: id},
+			{Key: "total", Value: 3.0},
+			{Key: "customer", Value: nil},
+			{Key: "placed_at", Value: bson.NewDateTimeFromTime(time.Now())},
+		},
+		{
+			{Key: "_id", Value: id},
+			{Key: "total", Value: 20.0},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Linus"}}},
+		},
+	}
+
+	table := inferCollectionSchema("orders", docs).table()
+	assert.Equal(t, `Database Schema:
+
+Collection: orders
+Description: 4 sampled documents
+Fields:
+  - _id objectId -- in 100% of sampled documents
+  - total double|int -- in 100% of sampled documents
+  - customer object|null -- in 100% of sampled documents
+  - customer.name string -- in 75% of sampled documents
+  - customer.email string -- in 25% of sampled documents
+  - items array -- in 50% of sampled documents
+  - items.sku string -- in 25% of sampled documents
+  - items.qty int -- in 25% of sampled documents
+  - placed_at date -- in 25% of sampled documents
+
+`, prompt.FormatSchema(prompt.MongoDB, &dbinterface.SchemaInfo{Tables: []dbinterface.TableInfo{table}}))
+	assert.False(t, table.Columns[1].IsNullable)
+	assert.True(t, table.Columns[2].IsNullable)
+	assert.True(t, table.Columns[3].IsNullable)
+}
+
+func TestFlattenDocument(t *testing.T) {
+	id := bson.NewObjectID()
+	placed := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	price, err := bson.ParseDecimal128("19.99")
+	require.NoError(t, err)
+
+	doc := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "count", Value: int32(3)},
+		{Key: "customer", Value: bson.D{
+			{Key: "name", Value: "Ada"},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "London"}}},
+		}},
+		{Key: "placed_at", Value: bson.NewDateTimeFromTime(placed)},
+		{Key: "price", Value: price},
+		{Key: "tags", Value: bson.A{"new", bson.D{{Key: "ref", Value: id}}}},
+		{Key: "note", Value: nil},
+	}
+
+	row := make(map[string]interface{})
+	flattenDocument(row, "", doc)
+	assert.Equal(t, []string{"_id", "count", "customer.name", "customer.address.city", "placed_at", "price", "tags", "note"}, columnNames("", doc))
+	assert.Equal(t, map[string]interface{}{
+		"_id":                   id.Hex(),
+		"count":                 int32(3),
+		"customer.name":         "Ada",
+		"customer.address.city": "London",
+		"placed_at":             placed,
+		"price":                 "19.99",
+		"tags":                  []interface{}{"new", map[string]interface{}{"ref": id.Hex()}},
+		"note":                  nil,
+	}, row)
+}
+
+func TestMongoExecuteQueryRefusesWrites(t *testing.T) {
+	// Validation happens before the database is used
+	provider := NewMongoProvider()
+	_, err := provider.ExecuteQuery(context.Background(),
+		`{"collection": "orders", "pipeline": [{"$match": {}}, {"$out": "copy"}]}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$out")
+}
@@ -0,0 +1,190 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// schemaSampleSize is how many documents of each collection are sampled to infer
+// its fields
+const schemaSampleSize = 100
+
+// GetSchema samples the documents of every collection and describes each one as a
+// table whose columns are the fields found, with their types and how often they occur
+func (m *MongoProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	names, err := m.db.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+
+	schema := &dbinterface.SchemaInfo{Tables: make([]dbinterface.TableInfo, 0, len(names))}
+	for _, name := range names {
+		if strings.HasPrefix(name, "system.") {
+			continue
+		}
+
+		docs, err := m.sample(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, inferCollectionSchema(name, docs).table())
+	}
+	return schema, nil
+}
+
+// sample returns up to schemaSampleSize random documents of a collection
+func (m *MongoProvider) sample(ctx context.Context, collection string) ([]bson.D, error) {
+	pipeline := bson.A{bson.D{{Key: "$sample", Value: bson.D{{Key: "size", Value: schemaSampleSize}}}}}
+	cursor, err := m.db.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample collection %s: %w", collection, err)
+	}
+
+	var docs []bson.D
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to sample collection %s: %w", collection, err)
+	}
+	return docs, nil
+}
+
+// collectionSchema holds the fields found in a sample of a collection's documents
+type collectionSchema struct {
+	name    string
+	sampled int
+	fields  []*fieldInfo
+}
+
+// fieldInfo counts the sampled documents a field path appears in, by BSON type
+type fieldInfo struct {
+	path      string
+	documents int
+	types     map[string]int
+}
+
+// inferCollectionSchema collects the field paths of docs. Fields of documents
+// nested in arrays are reported under the path of the array, as MongoDB queries
+// address them.
+func inferCollectionSchema(name string, docs []bson.D) collectionSchema {
+	schema := collectionSchema{name: name, sampled: len(docs)}
+	fields := make(map[string]*fieldInfo)
+
+	for _, doc := range docs {
+		// A field counts once per document, however often it occurs in arrays
+		seen := make(map[string]bool)
+		var walk func(prefix string, doc bson.D)
+		record := func(path string, value interface{}) {
+			field, ok := fields[path]
+			if !ok {
+				field = &fieldInfo{path: path, types: make(map[string]int)}
+				fields[path] = field
+				schema.fields = append(schema.fields, field)
+			}
+			typeName := bsonTypeName(value)
+			if !seen[path] {
+				field.documents++
+			}
+			if !seen[path+"\x00"+typeName] {
+				field.types[typeName]++
+			}
+			seen[path] = true
+			seen[path+"\x00"+typeName] = true
+		}
+		walk = func(prefix string, doc bson.D) {
+			for _, item := range doc {
+				path := prefix + item.Key
+				record(path, item.Value)
+				switch v := item.Value.(type) {
+				case bson.D:
+					walk(path+".", v)
+				case bson.A:
+					for _, element := range v {
+						if nested, ok := element.(bson.D); ok {
+							walk(path+".", nested)
+						}
+					}
+				}
+			}
+		}
+		walk("", doc)
+	}
+	return schema
+}
+
+// table describes the collection as a table whose columns are the fields found
+func (s collectionSchema) table() dbinterface.TableInfo {
+	table := dbinterface.TableInfo{
+		Name:        s.name,
+		Description: fmt.Sprintf("%d sampled documents", s.sampled),
+		Columns:     make([]dbinterface.ColumnInfo, 0, len(s.fields)),
+	}
+	for _, field := range s.fields {
+		table.Columns = append(table.Columns, dbinterface.ColumnInfo{
+			Name:        field.path,
+			DataType:    field.typeNames(),
+			IsNullable:  field.documents < s.sampled || field.types["null"] > 0,
+			Description: fmt.Sprintf("in %d%% of sampled documents", field.documents*100/s.sampled),
+		})
+	}
+	return table
+}
+
+// typeNames lists the types of the field, most frequent first
+func (f *fieldInfo) typeNames() string {
+	names := make([]string, 0, len(f.types))
+	for name := range f.types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if f.types[names[i]] != f.types[names[j]] {
+			return f.types[names[i]] > f.types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, "|")
+}
+
+// bsonTypeName returns the MongoDB name of the BSON type of value, as used by $type
+func bsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil, bson.Null:
+		return "null"
+	case string:
+		return "string"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case bson.D:
+		return "object"
+	case bson.A:
+		return "array"
+	case bson.ObjectID:
+		return "objectId"
+	case bson.DateTime:
+		return "date"
+	case bson.Decimal128:
+		return "decimal"
+	case bson.Binary:
+		return "binData"
+	case bson.Timestamp:
+		return "timestamp"
+	case bson.Regex:
+		return "regex"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
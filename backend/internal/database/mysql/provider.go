@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+)
+
+// MySQLCredentials implements dbinterface.Credentials for MySQL
+type MySQLCredentials struct {
+	Host      string
+	Port      string
+	User      string
+	Password  string
+	DBName    string
+	Charset   string
+	Collation string
+}
+
+// NewCredentials builds MySQL credentials from a stored database configuration
+func NewCredentials(config *models.DatabaseConfig) (*MySQLCredentials, error) {
+	var options models.MySQLConfig
+	if err := sqlutil.DecodeOptions(config, &options); err != nil {
+		return nil, err
+	}
+
+	return &MySQLCredentials{
+		Host:      config.Host,
+		Port:      config.Port,
+		User:      config.Username,
+		Password:  config.Password,
+		DBName:    config.DBName,
+		Charset:   options.Charset,
+		Collation: options.Collation,
+	}, nil
+}
+
+func (c *MySQLCredentials) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if c.Port == "" {
+		return fmt.Errorf("port is required")
+	}
+	if c.User == "" {
+		return fmt.Errorf("user is required")
+	}
+	if c.DBName == "" {
+		return fmt.Errorf("database name is required")
+	}
+	return nil
+}
+
+func (c *MySQLCredentials) Type() string {
+	return string(models.MySQL)
+}
+
+// dsn builds the go-sql-driver DSN of the credentials
+func (c *MySQLCredentials) dsn() string {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, c.Port)
+	cfg.DBName = c.DBName
+	// Return DATE and DATETIME columns as time.Time rather than raw bytes
+	cfg.ParseTime = true
+	if c.Collation != "" {
+		cfg.Collation = c.Collation
+	}
+	if c.Charset != "" {
+		cfg.Params = map[string]string{"charset": c.Charset}
+	}
+	return cfg.FormatDSN()
+}
+
+// MySQLProvider implements dbinterface.Provider for MySQL
+type MySQLProvider struct {
+	db *sql.DB
+}
+
+// NewMySQLProvider creates a new MySQL provider
+func NewMySQLProvider() *MySQLProvider {
+	return &MySQLProvider{}
+}
+
+func init() {
+	dbregistry.RegisterProvider(string(models.MySQL), NewMySQLProvider())
+}
+
+// NewCredentials implements dbinterface.Provider
+func (m *MySQLProvider) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return NewCredentials(config)
+}
+
+func (m *MySQLProvider) Connect(ctx context.Context, creds dbinterface.Credentials) error {
+	mysqlCreds, ok := creds.(*MySQLCredentials)
+	if !ok {
+		return fmt.Errorf("invalid credentials type for MySQL")
+	}
+
+	db, err := sqlutil.Open(ctx, "mysql", mysqlCreds.dsn())
+	if err != nil {
+		return err
+	}
+
+	m.db = db
+	return nil
+}
+
+func (m *MySQLProvider) Close(ctx context.Context) error {
+	if m.db != nil {
+		return m.db.Close()
+	}
+	return nil
+}
+
+// killTimeout bounds how long a KILL QUERY may wait for a free connection
+const killTimeout = 5 * time.Second
+
+// ExecuteQuery executes a SQL query inside a READ ONLY transaction and returns the
+// results. The driver only drops its own socket when ctx is cancelled, which leaves
+// the query running on the server, so the query is also killed from another
+// connection by its connection id.
+func (m *MySQLProvider) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var connID int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connID); err != nil {
+		return nil, fmt.Errorf("failed to get connection id: %w", err)
+	}
+
+	killed := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		killed <- m.killQuery(connID)
+	})
+	result, err := sqlutil.QueryReadOnly(ctx, conn, query, args...)
+	if !stop() {
+		// Wait for the kill so it cannot hit a later query on a reused connection
+		if killErr := <-killed; err != nil && killErr != nil {
+			err = errors.Join(err, killErr)
+		}
+	}
+	return result, err
+}
+
+// killQuery aborts the statement running on the connection with the given id
+func (m *MySQLProvider) killQuery(connID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", connID)); err != nil {
+		return fmt.Errorf("failed to kill query on connection %d: %w", connID, err)
+	}
+	return nil
+}
+
+func (m *MySQLProvider) Ping(ctx context.Context) error {
+	if m.db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	return m.db.PingContext(ctx)
+}
+
+// Dialect implements dbinterface.Provider
+func (m *MySQLProvider) Dialect() string {
+	return prompt.MySQL
+}
+
+// Clone creates a new instance of the MySQL provider
+func (m *MySQLProvider) Clone() dbinterface.Provider {
+	return NewMySQLProvider()
+}
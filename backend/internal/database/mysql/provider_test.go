@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLDSN(t *testing.T) {
+	config := &models.DatabaseConfig{
+		Name:     "shop",
+		Type:     models.MySQL,
+		Host:     "db.internal",
+		Port:     "3306",
+		DBName:   "shop",
+		Username: "reader",
+		Password: "p@ss:word",
+		// Options read back from storage are a plain map
+		Options: map[string]interface{}{"charset": "utf8mb4", "collation": "utf8mb4_unicode_ci"},
+	}
+
+	creds, err := NewCredentials(config)
+	require.NoError(t, err)
+	require.NoError(t, creds.Validate())
+
+	parsed, err := mysql.ParseDSN(creds.dsn())
+	require.NoError(t, err)
+	assert.Equal(t, "reader", parsed.User)
+	assert.Equal(t, "p@ss:word", parsed.Passwd)
+	assert.Equal(t, "tcp", parsed.Net)
+	assert.Equal(t, "db.internal:3306", parsed.Addr)
+	assert.Equal(t, "shop", parsed.DBName)
+	assert.True(t, parsed.ParseTime)
+	assert.Equal(t, "utf8mb4_unicode_ci", parsed.Collation)
+	assert.Equal(t, "utf8mb4", parsed.Params["charset"])
+
+	config.Options = models.MySQLConfig{}
+	creds, err = NewCredentials(config)
+	require.NoError(t, err)
+	parsed, err = mysql.ParseDSN(creds.dsn())
+	require.NoError(t, err)
+	assert.Empty(t, parsed.Params)
+
+	config.Host = ""
+	creds, err = NewCredentials(config)
+	require.NoError(t, err)
+	assert.EqualError(t, creds.Validate(), "host is required")
+}
+
+// TestCancelledQueryIsKilled needs a MySQL server, e.g.
+// MYSQL_TEST_DSN='root:pass@tcp(127.0.0.1:3306)/mysql'
+func TestCancelledQueryIsKilled(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN is not set")
+	}
+
+	db, err := sqlutil.Open(context.Background(), "mysql", dsn)
+	require.NoError(t, err)
+	provider := &MySQLProvider{db: db}
+	defer provider.Close(context.Background())
+
+	const query = "SELECT SLEEP(30) AS cancelled_query_test"
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = provider.ExecuteQuery(ctx, query)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	// The server must not keep sleeping after the client gave up
+	assert.Eventually(t, func() bool {
+		var running int
+		err := db.QueryRow("SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE INFO = ?", query).Scan(&running)
+		return err == nil && running == 0
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// The pool stays usable afterwards
+	result, err := provider.ExecuteQuery(context.Background(), "SELECT 1 AS one")
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 1)
+}
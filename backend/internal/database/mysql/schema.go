@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+)
+
+// GetSchema reads the tables and views of the current database with their columns,
+// comments, primary keys and foreign keys from information_schema
+func (m *MySQLProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	tableRows, err := m.db.QueryContext(ctx, mysqlTablesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer tableRows.Close()
+
+	var names []string
+	tables := make(map[string]*dbinterface.TableInfo)
+	views := make(map[string]*dbinterface.ViewInfo)
+	for tableRows.Next() {
+		var name, tableType string
+		var comment sql.NullString
+		if err := tableRows.Scan(&name, &tableType, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		names = append(names, name)
+		if tableType == "VIEW" {
+			// MySQL reports "VIEW" as the comment of every view
+			views[name] = &dbinterface.ViewInfo{Name: name}
+		} else {
+			tables[name] = &dbinterface.TableInfo{Name: name, Description: comment.String}
+		}
+	}
+	if err := tableRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	columnRows, err := m.db.QueryContext(ctx, mysqlColumnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer columnRows.Close()
+
+	for columnRows.Next() {
+		var tableName, nullable string
+		var column dbinterface.ColumnInfo
+		var comment sql.NullString
+		if err := columnRows.Scan(&tableName, &column.Name, &column.DataType, &nullable, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		column.IsNullable = nullable == "YES"
+		column.Description = comment.String
+
+		if table, ok := tables[tableName]; ok {
+			table.Columns = append(table.Columns, column)
+		} else if view, ok := views[tableName]; ok {
+			view.Columns = append(view.Columns, column)
+		}
+	}
+	if err := columnRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	keyRows, err := m.db.QueryContext(ctx, mysqlKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query keys: %w", err)
+	}
+	defer keyRows.Close()
+
+	for keyRows.Next() {
+		var tableName, constraintName, columnName string
+		var refTable, refColumn sql.NullString
+		if err := keyRows.Scan(&tableName, &constraintName, &columnName, &refTable, &refColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		table, ok := tables[tableName]
+		if !ok {
+			continue
+		}
+
+		if constraintName == "PRIMARY" {
+			table.PrimaryKey = append(table.PrimaryKey, columnName)
+			continue
+		}
+
+		// Rows are ordered by constraint, so the columns of a foreign key are adjacent
+		last := len(table.ForeignKeys) - 1
+		if last < 0 || table.ForeignKeys[last].Name != constraintName {
+			table.ForeignKeys = append(table.ForeignKeys, dbinterface.ForeignKeyInfo{
+				Name:         constraintName,
+				RefTableName: refTable.String,
+			})
+			last++
+		}
+		table.ForeignKeys[last].ColumnNames = append(table.ForeignKeys[last].ColumnNames, columnName)
+		table.ForeignKeys[last].RefColumnNames = append(table.ForeignKeys[last].RefColumnNames, refColumn.String)
+	}
+	if err := keyRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating keys: %w", err)
+	}
+
+	schema := &dbinterface.SchemaInfo{
+		Tables: make([]dbinterface.TableInfo, 0, len(tables)),
+		Views:  make([]dbinterface.ViewInfo, 0, len(views)),
+	}
+	for _, name := range names {
+		if table, ok := tables[name]; ok {
+			schema.Tables = append(schema.Tables, *table)
+		} else {
+			schema.Views = append(schema.Views, *views[name])
+		}
+	}
+	return schema, nil
+}
+
+// Queries to get the schema of the current MySQL database
+const (
+	mysqlTablesQuery = `
+SELECT table_name, table_type, table_comment
+FROM information_schema.tables
+WHERE table_schema = DATABASE()
+ORDER BY table_name`
+
+	mysqlColumnsQuery = `
+SELECT table_name, column_name, column_type, is_nullable, column_comment
+FROM information_schema.columns
+WHERE table_schema = DATABASE()
+ORDER BY table_name, ordinal_position`
+
+	mysqlKeysQuery = `
+SELECT table_name, constraint_name, column_name, referenced_table_name, referenced_column_name
+FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE()
+    AND (constraint_name = 'PRIMARY' OR referenced_table_name IS NOT NULL)
+ORDER BY table_name, constraint_name, ordinal_position`
+)
@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
+	"strconv"
 
+	_ "github.com/lib/pq"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
 	"github.com/shahariaazam/smart-insights/internal/dbinterface"
 	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
 )
 
 // PostgresCredentials implements dbinterface.Credentials for PostgreSQL
@@ -20,6 +24,28 @@ type PostgresCredentials struct {
 	SSLMode  string
 }
 
+// NewCredentials builds PostgreSQL credentials from a stored database configuration
+func NewCredentials(config *models.DatabaseConfig) (*PostgresCredentials, error) {
+	port, err := strconv.Atoi(config.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", config.Port, err)
+	}
+
+	var options models.PostgresConfig
+	if err := sqlutil.DecodeOptions(config, &options); err != nil {
+		return nil, err
+	}
+
+	return &PostgresCredentials{
+		Host:     config.Host,
+		Port:     port,
+		User:     config.Username,
+		Password: config.Password,
+		DBName:   config.DBName,
+		SSLMode:  options.SSLMode,
+	}, nil
+}
+
 func (c *PostgresCredentials) Validate() error {
 	if c.Host == "" {
 		return fmt.Errorf("host is required")
@@ -51,7 +77,12 @@ func NewPostgresProvider() *PostgresProvider {
 }
 
 func init() {
-	dbregistry.RegisterProvider("postgresql", NewPostgresProvider())
+	dbregistry.RegisterProvider(string(models.PostgreSQL), NewPostgresProvider())
+}
+
+// NewCredentials implements dbinterface.Provider
+func (p *PostgresProvider) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return NewCredentials(config)
 }
 
 func (p *PostgresProvider) Connect(ctx context.Context, creds dbinterface.Credentials) error {
@@ -70,14 +101,9 @@ func (p *PostgresProvider) Connect(ctx context.Context, creds dbinterface.Creden
 		defaultString(pgCreds.SSLMode, "disable"),
 	)
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := sqlutil.Open(ctx, "postgres", connStr)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
-	}
-
-	if err := db.PingContext(ctx); err != nil {
-		db.Close()
-		return fmt.Errorf("failed to ping database: %w", err)
+		return err
 	}
 
 	p.db = db
@@ -91,70 +117,14 @@ func (p *PostgresProvider) Close(ctx context.Context) error {
 	return nil
 }
 
+// ExecuteQuery executes a SQL query inside a READ ONLY transaction and returns the results.
+// When ctx is cancelled the driver sends a cancel request to the server, so the query
+// is aborted there as well instead of running on as an orphan.
 func (p *PostgresProvider) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
 	if p.db == nil {
 		return nil, fmt.Errorf("database connection not initialized")
 	}
-
-	rows, err := p.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
-	defer rows.Close()
-
-	columns, err := rows.Columns()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
-	}
-
-	result := &dbinterface.QueryResult{
-		Columns: columns,
-		Rows:    make([]map[string]interface{}, 0),
-	}
-
-	colTypes, err := rows.ColumnTypes()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get column types: %w", err)
-	}
-
-	for rows.Next() {
-		row := make(map[string]interface{})
-		scanArgs := make([]interface{}, len(columns))
-		values := make([]interface{}, len(columns))
-
-		for i := range values {
-			scanArgs[i] = &values[i]
-		}
-
-		if err := rows.Scan(scanArgs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		for i, col := range columns {
-			val := values[i]
-
-			// Handle PostgreSQL specific types
-			switch v := val.(type) {
-			case []byte:
-				// Convert byte arrays to string for text types
-				if isTextType(colTypes[i].DatabaseTypeName()) {
-					row[col] = string(v)
-				} else {
-					row[col] = v
-				}
-			default:
-				row[col] = v
-			}
-		}
-
-		result.Rows = append(result.Rows, row)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return result, nil
+	return sqlutil.QueryReadOnly(ctx, p.db, query, args...)
 }
 
 func (p *PostgresProvider) Ping(ctx context.Context) error {
@@ -164,6 +134,11 @@ func (p *PostgresProvider) Ping(ctx context.Context) error {
 	return p.db.PingContext(ctx)
 }
 
+// Dialect implements dbinterface.Provider
+func (p *PostgresProvider) Dialect() string {
+	return prompt.PostgreSQL
+}
+
 // Clone creates a new instance of the PostgreSQL provider
 func (p *PostgresProvider) Clone() dbinterface.Provider {
 	return NewPostgresProvider()
@@ -176,18 +151,3 @@ func defaultString(s, def string) string {
 	}
 	return s
 }
-
-func isTextType(dbType string) bool {
-	textTypes := map[string]bool{
-		"TEXT":       true,
-		"VARCHAR":    true,
-		"CHAR":       true,
-		"JSON":       true,
-		"JSONB":      true,
-		"UUID":       true,
-		"TIMESTAMP":  true,
-		"TIMESTAMPZ": true,
-		"DATE":       true,
-	}
-	return textTypes[strings.ToUpper(dbType)]
-}
@@ -24,18 +24,18 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 	query := `
 		SELECT 
 			t.table_name,
+			obj_description(format('%I.%I', t.table_schema, t.table_name)::regclass, 'pg_class') as table_description,
 			array_agg(c.column_name ORDER BY c.ordinal_position) as columns,
 			array_agg(c.data_type ORDER BY c.ordinal_position) as data_types,
 			array_agg(c.is_nullable ORDER BY c.ordinal_position) as nullable,
 			array_agg(c.column_default ORDER BY c.ordinal_position) as defaults,
 			array_agg(c.character_maximum_length ORDER BY c.ordinal_position) as char_lengths,
-			array_agg(pgd.description ORDER BY c.ordinal_position) as descriptions
+			array_agg(col_description(format('%I.%I', t.table_schema, t.table_name)::regclass, c.ordinal_position) ORDER BY c.ordinal_position) as descriptions
 		FROM information_schema.tables t
-		JOIN information_schema.columns c ON c.table_name = t.table_name
-		LEFT JOIN pg_catalog.pg_statio_all_tables st ON st.relname = t.table_name
-		LEFT JOIN pg_catalog.pg_description pgd ON pgd.objoid = st.relid AND pgd.objsubid = c.ordinal_position
+		JOIN information_schema.columns c ON c.table_schema = t.table_schema AND c.table_name = t.table_name
 		WHERE t.table_schema = 'public' AND t.table_type = 'BASE TABLE'
-		GROUP BY t.table_name
+		GROUP BY t.table_schema, t.table_name
+		ORDER BY t.table_name
 	`
 
 	rows, err := p.db.QueryContext(ctx, query)
@@ -46,12 +46,14 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 
 	for rows.Next() {
 		var table dbinterface.TableInfo
+		var tableDescription sql.NullString
 		var columnNames, dataTypes, nullables, defaults []sql.NullString
 		var charLengths []sql.NullInt64
 		var descriptions []sql.NullString
 
 		err := rows.Scan(
 			&table.Name,
+			&tableDescription,
 			pq.Array(&columnNames),
 			pq.Array(&dataTypes),
 			pq.Array(&nullables),
@@ -62,6 +64,7 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan table info: %w", err)
 		}
+		table.Description = tableDescription.String
 
 		table.Columns = make([]dbinterface.ColumnInfo, len(columnNames))
 		for i := range columnNames {
@@ -104,6 +107,9 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 
 		schema.Tables = append(schema.Tables, table)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
 
 	// Get views
 	viewQuery := `
@@ -113,13 +119,12 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 			array_agg(c.data_type ORDER BY c.ordinal_position) as data_types,
 			array_agg(c.is_nullable ORDER BY c.ordinal_position) as nullable,
 			v.view_definition,
-			pgd.description
+			obj_description(format('%I.%I', v.table_schema, v.table_name)::regclass, 'pg_class') as description
 		FROM information_schema.views v
-		JOIN information_schema.columns c ON c.table_name = v.table_name
-		LEFT JOIN pg_catalog.pg_statio_all_tables st ON st.relname = v.table_name
-		LEFT JOIN pg_catalog.pg_description pgd ON pgd.objoid = st.relid AND pgd.objsubid = 0
+		JOIN information_schema.columns c ON c.table_schema = v.table_schema AND c.table_name = v.table_name
 		WHERE v.table_schema = 'public'
-		GROUP BY v.table_name, v.view_definition, pgd.description
+		GROUP BY v.table_schema, v.table_name, v.view_definition
+		ORDER BY v.table_name
 	`
 
 	viewRows, err := p.db.QueryContext(ctx, viewQuery)
@@ -158,6 +163,9 @@ func (p *PostgresProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaIn
 
 		schema.Views = append(schema.Views, view)
 	}
+	if err := viewRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating views: %w", err)
+	}
 
 	return schema, nil
 }
@@ -167,8 +175,9 @@ func (p *PostgresProvider) getTablePrimaryKeys(ctx context.Context, tableName st
 		SELECT a.attname
 		FROM pg_index i
 		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
-		WHERE i.indrelid = $1::regclass
-		AND i.indisprimary;
+		WHERE i.indrelid = format('public.%I', $1::text)::regclass
+		AND i.indisprimary
+		ORDER BY array_position(i.indkey, a.attnum);
 	`
 
 	rows, err := p.db.QueryContext(ctx, query, tableName)
@@ -185,6 +194,9 @@ func (p *PostgresProvider) getTablePrimaryKeys(ctx context.Context, tableName st
 		}
 		primaryKeys = append(primaryKeys, columnName)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating primary keys: %w", err)
+	}
 
 	return primaryKeys, nil
 }
@@ -219,7 +231,8 @@ func (p *PostgresProvider) getTableForeignKeys(ctx context.Context, tableName st
 	}
 	defer rows.Close()
 
-	fkMap := make(map[string]*dbinterface.ForeignKeyInfo)
+	// Rows are ordered by constraint, so the columns of a foreign key are adjacent
+	var result []dbinterface.ForeignKeyInfo
 	for rows.Next() {
 		var (
 			constraintName string
@@ -241,26 +254,22 @@ func (p *PostgresProvider) getTableForeignKeys(ctx context.Context, tableName st
 			return nil, err
 		}
 
-		fk, exists := fkMap[constraintName]
-		if !exists {
-			fk = &dbinterface.ForeignKeyInfo{
-				Name:           constraintName,
-				ColumnNames:    make([]string, 0),
-				RefTableName:   refTableName,
-				RefColumnNames: make([]string, 0),
-				OnUpdate:       updateRule,
-				OnDelete:       deleteRule,
-			}
-			fkMap[constraintName] = fk
+		last := len(result) - 1
+		if last < 0 || result[last].Name != constraintName {
+			result = append(result, dbinterface.ForeignKeyInfo{
+				Name:         constraintName,
+				RefTableName: refTableName,
+				OnUpdate:     updateRule,
+				OnDelete:     deleteRule,
+			})
+			last++
 		}
 
-		fk.ColumnNames = append(fk.ColumnNames, columnName)
-		fk.RefColumnNames = append(fk.RefColumnNames, refColumnName)
+		result[last].ColumnNames = append(result[last].ColumnNames, columnName)
+		result[last].RefColumnNames = append(result[last].RefColumnNames, refColumnName)
 	}
-
-	result := make([]dbinterface.ForeignKeyInfo, 0, len(fkMap))
-	for _, fk := range fkMap {
-		result = append(result, *fk)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
 	}
 
 	return result, nil
@@ -281,7 +290,8 @@ func (p *PostgresProvider) getTableIndexes(ctx context.Context, tableName string
 		WHERE t.relname = $1
 		AND t.relkind = 'r'
 		AND ix.indisprimary = false  -- Exclude primary keys
-		GROUP BY i.relname, ix.indisunique, am.amname;
+		GROUP BY i.relname, ix.indisunique, am.amname
+		ORDER BY i.relname;
 	`
 
 	rows, err := p.db.QueryContext(ctx, query, tableName)
@@ -302,6 +312,9 @@ func (p *PostgresProvider) getTableIndexes(ctx context.Context, tableName string
 		index.ColumnNames = columnNames
 		indexes = append(indexes, index)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
 
 	return indexes, nil
 }
@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database/sqlutil"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/dbregistry"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+)
+
+// SQLiteCredentials implements dbinterface.Credentials for a SQLite database file
+type SQLiteCredentials struct {
+	Path string
+}
+
+// NewCredentials builds SQLite credentials from a stored database configuration
+func NewCredentials(config *models.DatabaseConfig) (*SQLiteCredentials, error) {
+	return &SQLiteCredentials{Path: config.Path}, nil
+}
+
+func (c *SQLiteCredentials) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+func (c *SQLiteCredentials) Type() string {
+	return string(models.SQLite)
+}
+
+// dsn builds the go-sqlite3 DSN opening the database file at path read-only
+func dsn(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	// Without this check SQLite would report the missing file as "unable to open database file"
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("failed to open database file: %w", err)
+	}
+
+	// mode=ro opens the file read-only and query_only makes SQLite refuse writes
+	// to any database attached later on
+	dsn := url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro&_query_only=true"}
+	return dsn.String(), nil
+}
+
+// SQLiteProvider implements dbinterface.Provider for a SQLite database file
+type SQLiteProvider struct {
+	db *sql.DB
+}
+
+// NewSQLiteProvider creates a new SQLite provider
+func NewSQLiteProvider() *SQLiteProvider {
+	return &SQLiteProvider{}
+}
+
+func init() {
+	dbregistry.RegisterProvider(string(models.SQLite), NewSQLiteProvider())
+}
+
+// NewCredentials implements dbinterface.Provider
+func (s *SQLiteProvider) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return NewCredentials(config)
+}
+
+func (s *SQLiteProvider) Connect(ctx context.Context, creds dbinterface.Credentials) error {
+	sqliteCreds, ok := creds.(*SQLiteCredentials)
+	if !ok {
+		return fmt.Errorf("invalid credentials type for SQLite")
+	}
+
+	dataSource, err := dsn(sqliteCreds.Path)
+	if err != nil {
+		return err
+	}
+	db, err := sqlutil.Open(ctx, "sqlite3", dataSource)
+	if err != nil {
+		return err
+	}
+
+	s.db = db
+	return nil
+}
+
+func (s *SQLiteProvider) Close(ctx context.Context) error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// ExecuteQuery executes a SQL query and returns the results. The database is open
+// read-only, so any write the query validator may have missed is refused. When ctx
+// is cancelled the driver interrupts the query.
+func (s *SQLiteProvider) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	return sqlutil.ReadRows(rows)
+}
+
+func (s *SQLiteProvider) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	return s.db.PingContext(ctx)
+}
+
+// Dialect implements dbinterface.Provider
+func (s *SQLiteProvider) Dialect() string {
+	return prompt.SQLite
+}
+
+// Clone creates a new instance of the SQLite provider
+func (s *SQLiteProvider) Clone() dbinterface.Provider {
+	return NewSQLiteProvider()
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY,
+    customer_id INTEGER REFERENCES customers,
+    total REAL
+);
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100;
+INSERT INTO customers VALUES (1, 'Ada'), (2, 'Grace');
+INSERT INTO orders VALUES (1, 1, 250.5), (2, 1, 20), (3, 2, 120);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	ctx := context.Background()
+	provider := NewSQLiteProvider()
+	creds, err := provider.NewCredentials(&models.DatabaseConfig{Type: models.SQLite, Path: path})
+	require.NoError(t, err)
+	require.NoError(t, provider.Connect(ctx, creds))
+	defer provider.Close(ctx)
+	assert.Equal(t, prompt.SQLite, provider.Dialect())
+
+	schema, err := provider.GetSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `Database Schema:
+
+Table: customers
+Columns:
+  - id INTEGER
+  - name TEXT
+Primary Key: id
+
+Table: orders
+Columns:
+  - id INTEGER
+  - customer_id INTEGER
+  - total REAL
+Primary Key: id
+Foreign Keys:
+  - (customer_id) references customers(id)
+
+View: big_orders
+Columns:
+  - id INTEGER
+  - customer_id INTEGER
+  - total REAL
+
+`, prompt.FormatSchema(provider.Dialect(), schema))
+
+	result, err := provider.ExecuteQuery(ctx, "SELECT c.name, count(*) AS orders FROM big_orders o JOIN customers c ON c.id = o.customer_id GROUP BY c.name ORDER BY c.name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "orders"}, result.Columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "Ada", "orders": int64(1)},
+		{"name": "Grace", "orders": int64(1)},
+	}, result.Rows)
+
+	// The file is open read-only
+	_, err = provider.ExecuteQuery(ctx, "DELETE FROM orders")
+	assert.Error(t, err)
+}
+
+func TestSQLiteMissingFile(t *testing.T) {
+	_, err := dsn(filepath.Join(t.TempDir(), "missing.sqlite"))
+	assert.ErrorContains(t, err, "failed to open database file")
+}
@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+)
+
+// GetSchema reads the tables and views of the main database with their columns,
+// primary keys and foreign keys from sqlite_master and the table_info and
+// foreign_key_list pragmas
+func (s *SQLiteProvider) GetSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	tableRows, err := s.db.QueryContext(ctx, sqliteTablesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer tableRows.Close()
+
+	var names []string
+	tables := make(map[string]*dbinterface.TableInfo)
+	views := make(map[string]*dbinterface.ViewInfo)
+	for tableRows.Next() {
+		var name, tableType string
+		if err := tableRows.Scan(&name, &tableType); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		names = append(names, name)
+		if tableType == "view" {
+			views[name] = &dbinterface.ViewInfo{Name: name}
+		} else {
+			tables[name] = &dbinterface.TableInfo{Name: name}
+		}
+	}
+	if err := tableRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	columnRows, err := s.db.QueryContext(ctx, sqliteColumnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer columnRows.Close()
+
+	// primaryKeys maps the tables to their key columns by position in the key
+	primaryKeys := make(map[string]map[int]string)
+	for columnRows.Next() {
+		var tableName string
+		var notNull bool
+		var primaryKey int
+		var column dbinterface.ColumnInfo
+		if err := columnRows.Scan(&tableName, &column.Name, &column.DataType, &notNull, &primaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		column.IsNullable = !notNull
+		if column.DataType == "" {
+			// Columns declared without a type take any value
+			column.DataType = "ANY"
+		}
+
+		if table, ok := tables[tableName]; ok {
+			table.Columns = append(table.Columns, column)
+			if primaryKey > 0 {
+				if primaryKeys[tableName] == nil {
+					primaryKeys[tableName] = make(map[int]string)
+				}
+				primaryKeys[tableName][primaryKey] = column.Name
+			}
+		} else if view, ok := views[tableName]; ok {
+			view.Columns = append(view.Columns, column)
+		}
+	}
+	if err := columnRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+	for tableName, columns := range primaryKeys {
+		for position := 1; position <= len(columns); position++ {
+			tables[tableName].PrimaryKey = append(tables[tableName].PrimaryKey, columns[position])
+		}
+	}
+
+	keyRows, err := s.db.QueryContext(ctx, sqliteForeignKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer keyRows.Close()
+
+	for keyRows.Next() {
+		var tableName, columnName, refTable string
+		var id int
+		var refColumn sql.NullString
+		if err := keyRows.Scan(&tableName, &id, &columnName, &refTable, &refColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		table, ok := tables[tableName]
+		if !ok {
+			continue
+		}
+
+		// SQLite foreign keys have no name; id tells them apart within a table
+		name := fmt.Sprintf("%s_fk_%d", tableName, id)
+		last := len(table.ForeignKeys) - 1
+		if last < 0 || table.ForeignKeys[last].Name != name {
+			table.ForeignKeys = append(table.ForeignKeys, dbinterface.ForeignKeyInfo{
+				Name:         name,
+				RefTableName: refTable,
+			})
+			last++
+		}
+		table.ForeignKeys[last].ColumnNames = append(table.ForeignKeys[last].ColumnNames, columnName)
+		// A key without target columns references the primary key of the other table
+		table.ForeignKeys[last].RefColumnNames = append(table.ForeignKeys[last].RefColumnNames, refColumn.String)
+	}
+	if err := keyRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
+	}
+
+	schema := &dbinterface.SchemaInfo{
+		Tables: make([]dbinterface.TableInfo, 0, len(tables)),
+		Views:  make([]dbinterface.ViewInfo, 0, len(views)),
+	}
+	for _, name := range names {
+		if table, ok := tables[name]; ok {
+			for i, fk := range table.ForeignKeys {
+				table.ForeignKeys[i].RefColumnNames = referencedColumns(fk, tables)
+			}
+			schema.Tables = append(schema.Tables, *table)
+		} else {
+			schema.Views = append(schema.Views, *views[name])
+		}
+	}
+	return schema, nil
+}
+
+// referencedColumns returns the columns a SQLite foreign key references, filling
+// in the primary key of the referenced table when the key does not name them
+func referencedColumns(fk dbinterface.ForeignKeyInfo, tables map[string]*dbinterface.TableInfo) []string {
+	for _, column := range fk.RefColumnNames {
+		if column != "" {
+			return fk.RefColumnNames
+		}
+	}
+	if ref, ok := tables[fk.RefTableName]; ok && len(ref.PrimaryKey) == len(fk.ColumnNames) {
+		return ref.PrimaryKey
+	}
+	return fk.RefColumnNames
+}
+
+// Queries to get the schema of a SQLite database
+const (
+	sqliteTablesQuery = `
+SELECT name, type
+FROM sqlite_master
+WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+ORDER BY name`
+
+	sqliteColumnsQuery = `
+SELECT m.name, p.name, p.type, p."notnull", p.pk
+FROM sqlite_master m
+JOIN pragma_table_info(m.name) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'
+ORDER BY m.name, p.cid`
+
+	sqliteForeignKeysQuery = `
+SELECT m.name, f.id, f."from", f."table", f."to"
+FROM sqlite_master m
+JOIN pragma_foreign_key_list(m.name) f
+WHERE m.type = 'table'
+ORDER BY m.name, f.id, f.seq`
+)
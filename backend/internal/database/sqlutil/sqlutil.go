@@ -0,0 +1,129 @@
+// Package sqlutil holds the helpers shared by the providers of database/sql databases
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+)
+
+// Open opens and verifies a connection pool for a database/sql driver
+func Open(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Configure pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+
+	// Verify connection
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// TxBeginner is implemented by *sql.DB and *sql.Conn
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// QueryReadOnly executes a query inside a READ ONLY transaction and returns the
+// results. The transaction is always rolled back; it only exists so that the
+// server refuses any write the query validator may have missed.
+func QueryReadOnly(ctx context.Context, db TxBeginner, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	return ReadRows(rows)
+}
+
+// ReadRows reads rows into one map per row, keyed by column name. Raw bytes are
+// returned as strings.
+func ReadRows(rows *sql.Rows) (*dbinterface.QueryResult, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	result := &dbinterface.QueryResult{
+		Columns: columns,
+		Rows:    make([]map[string]interface{}, 0),
+	}
+	for rows.Next() {
+		// Create value holders for this row
+		values := make([]interface{}, len(columns))
+		valuePointers := make([]interface{}, len(columns))
+		for i := range values {
+			valuePointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		// Convert row to map
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = value
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return result, nil
+}
+
+// DecodeOptions decodes the type-specific options of a database configuration
+// into options, e.g. a *models.MySQLConfig
+func DecodeOptions(config *models.DatabaseConfig, options interface{}) error {
+	if config.Options == nil {
+		return nil
+	}
+	data, err := json.Marshal(config.Options)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	if err := json.Unmarshal(data, options); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
+}
+
+// QuoteIdentifier quotes name as a standard SQL identifier
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral quotes value as a SQL string literal
+func QuoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
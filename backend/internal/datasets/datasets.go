@@ -0,0 +1,553 @@
+// Package datasets loads uploaded CSV and Excel files into DuckDB databases that
+// are asked about like any other database config.
+package datasets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format, expected a .csv or .xlsx file")
+	ErrInvalidName       = errors.New("invalid dataset name: use a letter followed by up to 62 letters, digits or underscores")
+	ErrInvalidFile       = errors.New("invalid file")
+)
+
+// namePattern matches dataset names. The rows of a dataset are loaded into a
+// table of the same name, so names are kept to identifiers that need no quoting.
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,62}$`)
+
+// maxUnzippedSize bounds the size of an Excel file once unzipped, so a small
+// upload cannot expand into gigabytes of XML
+const maxUnzippedSize = 1 << 30
+
+// Manager creates and deletes datasets. Each dataset is a DuckDB file in dir,
+// registered in storage as a read-only DuckDB database config named after it.
+type Manager struct {
+	dir      string
+	storage  storage.Storage
+	registry *source.Registry
+	// mu serializes changes to datasets and guards creating
+	mu sync.Mutex
+	// creating holds the names of the datasets whose files are being loaded
+	creating map[string]bool
+}
+
+// NewManager creates a Manager keeping its DuckDB files in dir, which is created
+// if it does not exist
+func NewManager(dir string, storage storage.Storage, registry *source.Registry) (*Manager, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dataset directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dataset directory: %w", err)
+	}
+
+	return &Manager{
+		dir:      dir,
+		storage:  storage,
+		registry: registry,
+		creating: make(map[string]bool),
+	}, nil
+}
+
+// Create loads the CSV or Excel file fileName read from file into a new dataset
+// that expires after ttl. When name is empty the dataset is named after the file.
+// The column types are inferred from the values; of an Excel file only the first
+// worksheet is loaded. Create returns storage.ErrConfigExists when a database
+// config of the same name exists or a dataset of that name is being created.
+func (m *Manager) Create(ctx context.Context, name, fileName string, file io.Reader, ttl time.Duration) (*models.Dataset, error) {
+	var format models.DatasetFormat
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		format = models.CSV
+	case ".xlsx":
+		format = models.XLSX
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if name == "" {
+		name = nameFromFile(fileName)
+	}
+	if !namePattern.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	// Loading a large file takes a while, so the name is only reserved meanwhile
+	if err := m.reserve(ctx, name); err != nil {
+		return nil, err
+	}
+	defer m.release(name)
+
+	uploadDir, err := os.MkdirTemp(m.dir, ".upload-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	defer os.RemoveAll(uploadDir)
+
+	dataset := &models.Dataset{
+		Name:     name,
+		FileName: filepath.Base(fileName),
+		Format:   format,
+	}
+
+	uploadPath := filepath.Join(uploadDir, "upload."+string(format))
+	if dataset.Size, err = saveFile(uploadPath, file); err != nil {
+		return nil, err
+	}
+
+	csvPath := uploadPath
+	if format == models.XLSX {
+		csvPath = filepath.Join(uploadDir, "sheet.csv")
+		if dataset.Sheet, err = convertWorkbook(uploadPath, csvPath); err != nil {
+			return nil, err
+		}
+	}
+
+	loadedPath := filepath.Join(uploadDir, "dataset.duckdb")
+	if dataset.Columns, dataset.Rows, err = loadCSV(ctx, csvPath, loadedPath, name, format == models.XLSX); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	dataset.CreatedAt = now
+	dataset.ExpiresAt = now.Add(ttl)
+
+	if err := m.save(ctx, dataset, loadedPath); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// reserve claims name for a dataset being created. It returns
+// storage.ErrConfigExists when a database config of the same name exists or is
+// being created.
+func (m *Manager) reserve(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.creating[name] {
+		return storage.ErrConfigExists
+	}
+	if err := m.checkAvailable(ctx, name); err != nil {
+		return err
+	}
+	m.creating[name] = true
+	return nil
+}
+
+// release gives up the name reserved by reserve
+func (m *Manager) release(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.creating, name)
+}
+
+// checkAvailable returns storage.ErrConfigExists when a database config named
+// name exists
+func (m *Manager) checkAvailable(ctx context.Context, name string) error {
+	if _, err := m.storage.LoadDatabaseConfig(ctx, name); err == nil {
+		return storage.ErrConfigExists
+	} else if !errors.Is(err, storage.ErrConfigNotFound) {
+		return fmt.Errorf("failed to load database config: %w", err)
+	}
+	return nil
+}
+
+// save moves the DuckDB file loadedPath of dataset into place and registers it
+// with its database config. Everything is undone when a step fails.
+func (m *Manager) save(ctx context.Context, dataset *models.Dataset, loadedPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A database config of the same name may have been added during the upload
+	name := dataset.Name
+	if err := m.checkAvailable(ctx, name); err != nil {
+		return err
+	}
+
+	// A pool may still be open on the file of a config that was deleted
+	if err := m.registry.Evict(name); err != nil {
+		return fmt.Errorf("failed to close previous database: %w", err)
+	}
+	path := m.path(name)
+	if err := removeDatabase(path); err != nil {
+		return err
+	}
+	if err := os.Rename(loadedPath, path); err != nil {
+		return fmt.Errorf("failed to move database file: %w", err)
+	}
+
+	config := models.DatabaseConfig{Name: name, Type: models.DuckDB, Path: path}
+	if err := m.storage.SaveDatabaseConfig(ctx, config); err != nil {
+		return errors.Join(fmt.Errorf("failed to save database config: %w", err), removeDatabase(path))
+	}
+	if err := m.storage.SaveDataset(ctx, *dataset); err != nil {
+		err = fmt.Errorf("failed to save dataset: %w", err)
+		// Undo even when the request was cancelled
+		if deleteErr := m.storage.DeleteDatabaseConfig(context.WithoutCancel(ctx), name); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to delete database config: %w", deleteErr))
+		}
+		return errors.Join(err, removeDatabase(path))
+	}
+	return nil
+}
+
+// Delete removes a dataset with its database config and DuckDB file. It returns
+// storage.ErrConfigNotFound when there is no such dataset.
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.storage.LoadDataset(ctx, name); err != nil {
+		return err
+	}
+	return m.delete(ctx, name)
+}
+
+// DeleteExpired removes the datasets that expired by now and returns how many
+// were removed
+func (m *Manager) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	datasets, err := m.storage.GetDatasets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load datasets: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	var errs []error
+	for _, dataset := range datasets {
+		if dataset.ExpiresAt.After(now) {
+			continue
+		}
+		if err := m.delete(ctx, dataset.Name); err != nil {
+			errs = append(errs, fmt.Errorf("dataset %s: %w", dataset.Name, err))
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// delete removes a dataset; m.mu must be held
+func (m *Manager) delete(ctx context.Context, name string) error {
+	if err := m.registry.Evict(name); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	// The config may have been deleted, or replaced by one that is not the dataset's
+	path := m.path(name)
+	config, err := m.storage.LoadDatabaseConfig(ctx, name)
+	if err == nil && config.Type == models.DuckDB && config.Path == path {
+		err = m.storage.DeleteDatabaseConfig(ctx, name)
+	}
+	if err != nil && !errors.Is(err, storage.ErrConfigNotFound) {
+		return fmt.Errorf("failed to delete database config: %w", err)
+	}
+
+	if err := removeDatabase(path); err != nil {
+		return err
+	}
+	if err := m.storage.DeleteDataset(ctx, name); err != nil && !errors.Is(err, storage.ErrConfigNotFound) {
+		return fmt.Errorf("failed to delete dataset: %w", err)
+	}
+	return nil
+}
+
+// path is the DuckDB file of the dataset name
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name+".duckdb")
+}
+
+// nameFromFile derives a dataset name from the name of the uploaded file, e.g.
+// sales_2024 from "Sales 2024.csv"
+func nameFromFile(fileName string) string {
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+
+	var name strings.Builder
+	for _, r := range strings.ToLower(base) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			name.WriteRune(r)
+		} else if name.Len() > 0 && !strings.HasSuffix(name.String(), "_") {
+			name.WriteByte('_')
+		}
+	}
+
+	result := strings.TrimSuffix(name.String(), "_")
+	if result != "" && result[0] >= '0' && result[0] <= '9' {
+		result = "dataset_" + result
+	}
+	if len(result) > 63 {
+		result = strings.TrimSuffix(result[:63], "_")
+	}
+	return result
+}
+
+// saveFile copies file to path and returns the number of bytes written
+func saveFile(path string, file io.Reader) (int64, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer out.Close()
+
+	size, err := io.Copy(out, file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save upload: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("failed to save upload: %w", err)
+	}
+	return size, nil
+}
+
+// convertWorkbook writes the first worksheet of the Excel file at path to csvPath
+// and returns the name of the worksheet. The first row is read as the header and
+// blank rows are skipped. Cells are written as their raw values, so numbers are
+// not rounded or grouped by their display format, except that the serial numbers
+// of date columns are written as ISO 8601 dates and times.
+func convertWorkbook(path, csvPath string) (string, error) {
+	workbook, err := excelize.OpenFile(path, excelize.Options{UnzipSizeLimit: maxUnzippedSize})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no worksheets", ErrInvalidFile)
+	}
+	sheet := sheets[0]
+
+	props, err := workbook.GetWorkbookProps()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	date1904 := props.Date1904 != nil && *props.Date1904
+
+	rows, err := workbook.Rows(sheet)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	defer rows.Close()
+
+	out, err := os.Create(csvPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer out.Close()
+	writer := csv.NewWriter(out)
+
+	width := 0
+	var dates map[int]bool
+	for line := 1; rows.Next(); line++ {
+		cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return "", fmt.Errorf("%w: row %d: %v", ErrInvalidFile, line, err)
+		}
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue
+		}
+
+		// Trailing empty cells are left out of rows, so rows are padded to the header
+		if width == 0 {
+			width = len(cells)
+		} else {
+			if len(cells) > width {
+				return "", fmt.Errorf("%w: row %d of worksheet %s has more cells than the header", ErrInvalidFile, line, sheet)
+			}
+			if dates == nil {
+				if dates, err = dateColumns(workbook, sheet, line, width); err != nil {
+					return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+				}
+			}
+			for column := range dates {
+				if column < len(cells) {
+					cells[column] = formatDate(cells[column], date1904)
+				}
+			}
+		}
+		for len(cells) < width {
+			cells = append(cells, "")
+		}
+
+		if err := writer.Write(cells); err != nil {
+			return "", fmt.Errorf("failed to write CSV file: %w", err)
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if width == 0 {
+		return "", fmt.Errorf("%w: worksheet %s is empty", ErrInvalidFile, sheet)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	return sheet, nil
+}
+
+// dateColumns returns the columns whose cell in row is formatted as a date or a
+// time. Excel stores dates as numbers, so the format of the first row of values
+// tells which columns hold them.
+func dateColumns(workbook *excelize.File, sheet string, row, width int) (map[int]bool, error) {
+	dates := make(map[int]bool)
+	for column := 0; column < width; column++ {
+		cell, err := excelize.CoordinatesToCellName(column+1, row)
+		if err != nil {
+			return nil, err
+		}
+		styleID, err := workbook.GetCellStyle(sheet, cell)
+		if err != nil {
+			return nil, err
+		}
+		style, err := workbook.GetStyle(styleID)
+		if err != nil {
+			return nil, err
+		}
+		if isDateFormat(style) {
+			dates[column] = true
+		}
+	}
+	return dates, nil
+}
+
+// numFmtLiterals matches the parts of a number format that are shown as they are:
+// quoted text, escaped characters, padding and bracketed colors and locales
+var numFmtLiterals = regexp.MustCompile(`"[^"]*"|\\.|_.|\*.|\[[^\]]*\]`)
+
+// isDateFormat reports whether style displays numbers as dates or times
+func isDateFormat(style *excelize.Style) bool {
+	if style.CustomNumFmt == nil {
+		// The built-in date and time formats
+		return (style.NumFmt >= 14 && style.NumFmt <= 22) || (style.NumFmt >= 45 && style.NumFmt <= 47)
+	}
+	code := numFmtLiterals.ReplaceAllString(*style.CustomNumFmt, "")
+	return strings.ContainsAny(strings.ToLower(code), "ymdhs")
+}
+
+// formatDate formats the serial number of a date cell in ISO 8601. Values that
+// are not serial numbers are returned as they are.
+func formatDate(value string, date1904 bool) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	t, err := excelize.ExcelDateToTime(serial, date1904)
+	if err != nil {
+		return value
+	}
+
+	switch {
+	case serial < 1:
+		return t.Format("15:04:05")
+	case t.Equal(t.Truncate(24 * time.Hour)):
+		return t.Format("2006-01-02")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// loadCSV creates the DuckDB database path with the rows of the CSV file csvPath
+// in table, and returns the columns of the table and its number of rows. The
+// whole file is sampled to infer the column types. The delimiter of a converted
+// workbook is known; that of an uploaded file is detected.
+func loadCSV(ctx context.Context, csvPath, path, table string, converted bool) ([]models.DatasetColumn, int64, error) {
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create database: %w", err)
+	}
+	defer db.Close()
+
+	options := "header = true, sample_size = -1"
+	if converted {
+		options += ", delim = ','"
+	}
+	statement := fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM read_csv(%s, %s)", quoteIdentifier(table), quoteLiteral(csvPath), options)
+	if _, err := db.ExecContext(ctx, statement); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT column_name, data_type FROM duckdb_columns() WHERE table_name = ? ORDER BY column_index", table)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []models.DatasetColumn
+	for rows.Next() {
+		var column models.DatasetColumn
+		if err := rows.Scan(&column.Name, &column.Type); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	var count int64
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+quoteIdentifier(table)).Scan(&count); err != nil {
+		return nil, 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	if count == 0 {
+		return nil, 0, fmt.Errorf("%w: the file has no rows", ErrInvalidFile)
+	}
+
+	// Write the table to the database file, so it can be moved without a log
+	if _, err := db.ExecContext(ctx, "CHECKPOINT"); err != nil {
+		return nil, 0, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+
+	if err := db.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to close database: %w", err)
+	}
+	return columns, count, nil
+}
+
+// removeDatabase removes the DuckDB file path and its write-ahead log
+func removeDatabase(path string) error {
+	for _, file := range []string{path, path + ".wal"} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove database file: %w", err)
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes name as a SQL identifier
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes value as a SQL string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
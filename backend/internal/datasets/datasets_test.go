@@ -0,0 +1,253 @@
+package datasets
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func newTestManager(t *testing.T) (*Manager, *source.Registry, storage.Storage) {
+	t.Helper()
+	store := memory.NewMemoryStorage()
+	registry := source.NewRegistry(store)
+	t.Cleanup(func() { registry.Close() })
+
+	manager, err := NewManager(t.TempDir(), store, registry)
+	require.NoError(t, err)
+	return manager, registry, store
+}
+
+func TestCreateCSV(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, store := newTestManager(t)
+
+	csv := "region;amount;sold_on\nnorth;10.5;2024-01-02\nsouth;32;2024-01-03\nnorth;5;2024-02-01\n"
+	dataset, err := manager.Create(ctx, "", "Sales 2024.csv", strings.NewReader(csv), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "sales_2024", dataset.Name)
+	assert.Equal(t, "Sales 2024.csv", dataset.FileName)
+	assert.Equal(t, models.CSV, dataset.Format)
+	assert.Equal(t, int64(len(csv)), dataset.Size)
+	assert.Equal(t, int64(3), dataset.Rows)
+	assert.Equal(t, []models.DatasetColumn{
+		{Name: "region", Type: "VARCHAR"},
+		{Name: "amount", Type: "DOUBLE"},
+		{Name: "sold_on", Type: "DATE"},
+	}, dataset.Columns)
+	assert.Equal(t, time.Hour, dataset.ExpiresAt.Sub(dataset.CreatedAt))
+
+	saved, err := store.LoadDataset(ctx, "sales_2024")
+	require.NoError(t, err)
+	assert.Equal(t, dataset.Columns, saved.Columns)
+
+	// The dataset is asked about through its database config
+	provider, err := registry.LoadSource(ctx, "sales_2024")
+	require.NoError(t, err)
+	result, err := provider.ExecuteQuery(ctx, "SELECT region, sum(amount) AS total FROM sales_2024 GROUP BY region ORDER BY region")
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"region": "north", "total": 15.5},
+		{"region": "south", "total": 32.0},
+	}, result.Rows)
+
+	_, err = manager.Create(ctx, "sales_2024", "other.csv", strings.NewReader("a\n1\n"), time.Hour)
+	assert.ErrorIs(t, err, storage.ErrConfigExists)
+}
+
+func TestCreateXLSX(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, _ := newTestManager(t)
+
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+	rows := [][]interface{}{
+		{"employee", "salary", "hired", "reviewed"},
+		{"Ada", 5200, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)},
+		{},
+		{"Grace", 6100.5},
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, err)
+		require.NoError(t, workbook.SetSheetRow("Sheet1", cell, &row))
+	}
+	// Display formats do not change the values loaded
+	currency := `#,##0.00 "USD"`
+	style, err := workbook.NewStyle(&excelize.Style{CustomNumFmt: &currency})
+	require.NoError(t, err)
+	require.NoError(t, workbook.SetColStyle("Sheet1", "B", style))
+	timestamp := "dd/mm/yyyy hh:mm"
+	style, err = workbook.NewStyle(&excelize.Style{CustomNumFmt: &timestamp})
+	require.NoError(t, err)
+	require.NoError(t, workbook.SetCellStyle("Sheet1", "D2", "D2", style))
+	_, err = workbook.NewSheet("Ignored")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "staff.xlsx")
+	require.NoError(t, workbook.SaveAs(path))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	dataset, err := manager.Create(ctx, "staff", "Staff.XLSX", file, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, models.XLSX, dataset.Format)
+	assert.Equal(t, "Sheet1", dataset.Sheet)
+	assert.Equal(t, int64(2), dataset.Rows)
+	assert.Equal(t, []models.DatasetColumn{
+		{Name: "employee", Type: "VARCHAR"},
+		{Name: "salary", Type: "DOUBLE"},
+		{Name: "hired", Type: "DATE"},
+		{Name: "reviewed", Type: "TIMESTAMP"},
+	}, dataset.Columns)
+
+	provider, err := registry.LoadSource(ctx, "staff")
+	require.NoError(t, err)
+	result, err := provider.ExecuteQuery(ctx, "SELECT employee, salary, CAST(hired AS VARCHAR) AS hired, CAST(reviewed AS VARCHAR) AS reviewed FROM staff ORDER BY employee")
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"employee": "Ada", "salary": 5200.0, "hired": "2021-03-01", "reviewed": "2024-06-03 09:30:00"},
+		{"employee": "Grace", "salary": 6100.5, "hired": nil, "reviewed": nil},
+	}, result.Rows)
+}
+
+func TestCreateErrors(t *testing.T) {
+	ctx := context.Background()
+	manager, _, store := newTestManager(t)
+
+	_, err := manager.Create(ctx, "", "report.pdf", strings.NewReader("%PDF"), time.Hour)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = manager.Create(ctx, "drop table", "report.csv", strings.NewReader("a\n1\n"), time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = manager.Create(ctx, "", "report.xlsx", strings.NewReader("not a workbook"), time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = manager.Create(ctx, "", "empty.csv", strings.NewReader(""), time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	// Names of other database configs are taken
+	require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "crm", Type: models.PostgreSQL}))
+	_, err = manager.Create(ctx, "crm", "crm.csv", strings.NewReader("a\n1\n"), time.Hour)
+	assert.ErrorIs(t, err, storage.ErrConfigExists)
+
+	// Failed uploads leave nothing behind
+	entries, err := os.ReadDir(manager.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	datasets, err := store.GetDatasets(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, datasets)
+}
+
+func TestCreateDuringUpload(t *testing.T) {
+	ctx := context.Background()
+	manager, _, store := newTestManager(t)
+
+	// The first upload stalls until the pipe is written
+	reader, writer := io.Pipe()
+	type created struct {
+		dataset *models.Dataset
+		err     error
+	}
+	done := make(chan created, 1)
+	go func() {
+		dataset, err := manager.Create(ctx, "slow", "slow.csv", reader, time.Hour)
+		done <- created{dataset, err}
+	}()
+	require.Eventually(t, func() bool {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		return manager.creating["slow"]
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Other datasets are created and deleted meanwhile, but the name is taken
+	_, err := manager.Create(ctx, "fast", "fast.csv", strings.NewReader("a\n1\n"), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, manager.Delete(ctx, "fast"))
+	_, err = manager.Create(ctx, "slow", "other.csv", strings.NewReader("a\n1\n"), time.Hour)
+	assert.ErrorIs(t, err, storage.ErrConfigExists)
+
+	_, err = io.WriteString(writer, "a\n1\n2\n")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	result := <-done
+	require.NoError(t, result.err)
+	assert.Equal(t, int64(2), result.dataset.Rows)
+	assert.FileExists(t, manager.path("slow"))
+	_, err = store.LoadDatabaseConfig(ctx, "slow")
+	require.NoError(t, err)
+	assert.Empty(t, manager.creating)
+
+	// A config added while the file was loading wins over the upload
+	reader, writer = io.Pipe()
+	go func() {
+		dataset, err := manager.Create(ctx, "late", "late.csv", reader, time.Hour)
+		done <- created{dataset, err}
+	}()
+	require.Eventually(t, func() bool {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		return manager.creating["late"]
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "late", Type: models.PostgreSQL}))
+	_, err = io.WriteString(writer, "a\n1\n")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	result = <-done
+	assert.ErrorIs(t, result.err, storage.ErrConfigExists)
+	assert.NoFileExists(t, manager.path("late"))
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, store := newTestManager(t)
+
+	_, err := manager.Create(ctx, "old", "old.csv", strings.NewReader("a\n1\n"), time.Minute)
+	require.NoError(t, err)
+	_, err = manager.Create(ctx, "current", "current.csv", strings.NewReader("a\n1\n"), time.Hour)
+	require.NoError(t, err)
+	_, err = registry.LoadSource(ctx, "old")
+	require.NoError(t, err)
+
+	deleted, err := manager.DeleteExpired(ctx, time.Now().Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = store.LoadDataset(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrConfigNotFound)
+	_, err = store.LoadDatabaseConfig(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrConfigNotFound)
+	assert.NoFileExists(t, manager.path("old"))
+	_, err = registry.LoadSource(ctx, "old")
+	assert.Error(t, err)
+
+	require.NoError(t, manager.Delete(ctx, "current"))
+	assert.NoFileExists(t, manager.path("current"))
+	assert.ErrorIs(t, manager.Delete(ctx, "current"), storage.ErrConfigNotFound)
+}
+
+func TestNameFromFile(t *testing.T) {
+	for fileName, name := range map[string]string{
+		"sales.csv":            "sales",
+		"Q3 Revenue (EU).xlsx": "q3_revenue_eu",
+		"2024-budget.csv":      "dataset_2024_budget",
+		"/tmp/uploads/x.csv":   "x",
+		"日本.csv":               "",
+	} {
+		assert.Equal(t, name, nameFromFile(fileName), fileName)
+	}
+}
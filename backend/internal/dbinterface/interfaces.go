@@ -2,6 +2,8 @@ package dbinterface
 
 import (
 	"context"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
 )
 
 // Credentials represents the generic database credentials interface
@@ -26,6 +28,8 @@ type QueryResult struct {
 
 // Provider defines the interface that all database providers must implement
 type Provider interface {
+	// NewCredentials builds the credentials Connect takes from a stored database configuration
+	NewCredentials(config *models.DatabaseConfig) (Credentials, error)
 	// Connect establishes a connection to the database using the provided credentials
 	Connect(ctx context.Context, creds Credentials) error
 	// Close closes the database connection
@@ -36,6 +40,8 @@ type Provider interface {
 	GetSchema(ctx context.Context) (*SchemaInfo, error)
 	// Ping checks if the database connection is alive
 	Ping(ctx context.Context) error
+	// Dialect names the query language the prompts ask for, e.g. prompt.MySQL
+	Dialect() string
 	// Clone creates a new instance of the provider
 	Clone() Provider
 }
@@ -54,6 +60,7 @@ type TableInfo struct {
 	PrimaryKey  []string
 	ForeignKeys []ForeignKeyInfo
 	Indexes     []IndexInfo
+	Description string
 }
 
 // ColumnInfo represents information about a database column
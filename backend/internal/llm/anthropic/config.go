@@ -0,0 +1,52 @@
+package anthropic
+
+import "fmt"
+
+// DefaultBaseURL is the Anthropic API endpoint used when no base URL is configured
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// Config implements llminterface.Config for Anthropic
+type Config struct {
+	Name        string
+	APIKey      string
+	Model       string  // e.g., "claude-3-5-sonnet-latest"
+	MaxTokens   int     // Default max tokens if not specified in request
+	Temperature float64 // Default temperature if not specified in request
+	TopK        int     // Only sample from the top K options, disabled when 0
+	BaseURL     string  // Defaults to DefaultBaseURL
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.APIKey == "" {
+		return fmt.Errorf("API key is required")
+	}
+	if c.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must not be negative")
+	}
+	if c.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) Type() string {
+	return "anthropic"
+}
+
+// NewConfig creates a new Anthropic configuration
+func NewConfig(name, apiKey, model string, maxTokens int, temperature float64, topK int) *Config {
+	return &Config{
+		Name:        name,
+		APIKey:      apiKey,
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		TopK:        topK,
+	}
+}
@@ -0,0 +1,313 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+const (
+	// apiVersion is the Messages API version the request and response types follow
+	apiVersion = "2023-06-01"
+	// defaultMaxTokens is used when neither the request nor the config set a limit,
+	// since the Messages API requires one
+	defaultMaxTokens = 4096
+)
+
+// Provider implements llminterface.Provider for Anthropic over the Messages API
+type Provider struct {
+	config *Config
+	client *http.Client
+}
+
+func NewProvider() llminterface.Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	cfg, ok := config.(*Config)
+	if !ok {
+		return fmt.Errorf("invalid config type for Anthropic provider")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	p.config = cfg
+	p.client = &http.Client{}
+	return nil
+}
+
+// messagesRequest is the body of POST /v1/messages
+type messagesRequest struct {
+	Model       string           `json:"model"`
+	MaxTokens   int              `json:"max_tokens"`
+	System      string           `json:"system,omitempty"`
+	Messages    []message        `json:"messages"`
+	Temperature *float64         `json:"temperature,omitempty"`
+	TopK        int              `json:"top_k,omitempty"`
+	Tools       []toolDefinition `json:"tools,omitempty"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a text, tool_use or tool_result block
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type toolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type messagesResponse struct {
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	body, err := p.messagesRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.config.APIKey)
+	httpReq.Header.Set("anthropic-version", apiVersion)
+
+	// Make the API call
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "anthropic",
+			Code:      "request_failed",
+			Message:   err.Error(),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "anthropic",
+			Code:      "request_failed",
+			Message:   fmt.Sprintf("failed to read response: %v", err),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, apiError(httpResp, respBody)
+	}
+
+	var completion messagesResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, &llminterface.Error{
+			Provider: "anthropic",
+			Code:     "invalid_response",
+			Message:  fmt.Sprintf("failed to decode response: %v", err),
+		}
+	}
+
+	// Convert the response to our format
+	var content strings.Builder
+	response := &llminterface.CompletionResponse{
+		Metadata: map[string]interface{}{
+			"model":       completion.Model,
+			"id":          completion.ID,
+			"stop_reason": completion.StopReason,
+		},
+	}
+	for _, block := range completion.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			response.ToolCalls = append(response.ToolCalls, llminterface.ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			})
+		}
+	}
+	response.Content = content.String()
+
+	if response.Content == "" && len(response.ToolCalls) == 0 {
+		return nil, &llminterface.Error{
+			Provider: "anthropic",
+			Code:     "no_completion",
+			Message:  "no content returned",
+		}
+	}
+
+	response.Usage.PromptTokens = completion.Usage.InputTokens
+	response.Usage.CompletionTokens = completion.Usage.OutputTokens
+	response.Usage.TotalTokens = completion.Usage.InputTokens + completion.Usage.OutputTokens
+
+	return response, nil
+}
+
+// messagesRequest converts a completion request to a Messages API request. System
+// messages become the top-level system prompt, and tool results are sent as
+// tool_result blocks of a user message.
+func (p *Provider) messagesRequest(req llminterface.CompletionRequest) (*messagesRequest, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = p.config.MaxTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	model := req.Model
+	if model == "" {
+		model = p.config.Model
+	}
+
+	body := &messagesRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		TopK:      p.config.TopK,
+	}
+
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = p.config.Temperature
+	}
+	if temperature != 0 {
+		body.Temperature = &temperature
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "user":
+			body.Messages = appendBlocks(body.Messages, "user", contentBlock{Type: "text", Text: msg.Content})
+		case "assistant":
+			var blocks []contentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if len(strings.TrimSpace(call.Arguments)) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, contentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			body.Messages = appendBlocks(body.Messages, "assistant", blocks...)
+		case "tool":
+			body.Messages = appendBlocks(body.Messages, "user", contentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, toolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+
+	return body, nil
+}
+
+// appendBlocks adds content blocks to the conversation, merging them into the last
+// message when it has the same role, since the Messages API expects the roles to
+// alternate
+func appendBlocks(messages []message, role string, blocks ...contentBlock) []message {
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, message{Role: role, Content: blocks})
+}
+
+// apiError converts an error response of the Messages API. Rate limits, overload
+// and server errors are retryable.
+func apiError(resp *http.Response, body []byte) *llminterface.Error {
+	statusCode := resp.StatusCode
+	var errResp errorResponse
+	code := "api_error"
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Type != "" {
+		code = errResp.Error.Type
+		message = errResp.Error.Message
+	}
+
+	return &llminterface.Error{
+		Provider:   "anthropic",
+		Code:       code,
+		Message:    fmt.Sprintf("anthropic API returned %d: %s", statusCode, message),
+		Retryable:  statusCode == http.StatusTooManyRequests || statusCode >= 500,
+		StatusCode: statusCode,
+		RetryAfter: llminterface.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *Provider) Clone() llminterface.Provider {
+	return NewProvider()
+}
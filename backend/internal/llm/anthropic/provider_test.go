@@ -0,0 +1,176 @@
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/internal/llmtest"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProvider points a provider at a stand-in Messages API answering with the
+// given status and body
+func newTestProvider(t *testing.T, status int, response string) (*Provider, *llmtest.Server) {
+	t.Helper()
+
+	server := llmtest.NewServer(t, llmtest.Response{Status: status, Body: response})
+	config := NewConfig("test", "test-key", "claude-3-5-sonnet-latest", 1024, 0.2, 40)
+	config.BaseURL = server.URL
+
+	provider := &Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	return provider, server
+}
+
+func TestProviderComplete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("text response", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{
+			"id": "msg_1",
+			"model": "claude-3-5-sonnet-latest",
+			"stop_reason": "end_turn",
+			"content": [{"type": "text", "text": "SELECT 1"}],
+			"usage": {"input_tokens": 12, "output_tokens": 3}
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "system", Content: "You are a SQL expert."},
+				{Role: "user", Content: "Count the users"},
+			},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.Equal(t, "/v1/messages", server.Request().URL.Path)
+		assert.Equal(t, "test-key", server.Request().Header.Get("x-api-key"))
+		assert.Equal(t, apiVersion, server.Request().Header.Get("anthropic-version"))
+
+		assert.Equal(t, "claude-3-5-sonnet-latest", body["model"])
+		assert.Equal(t, "You are a SQL expert.", body["system"])
+		assert.Equal(t, float64(1024), body["max_tokens"])
+		assert.Equal(t, float64(40), body["top_k"])
+		assert.Equal(t, 0.2, body["temperature"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"role":    "user",
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "Count the users"}},
+			},
+		}, body["messages"])
+
+		assert.Equal(t, "SELECT 1", resp.Content)
+		assert.Equal(t, 12, resp.Usage.PromptTokens)
+		assert.Equal(t, 3, resp.Usage.CompletionTokens)
+		assert.Equal(t, 15, resp.Usage.TotalTokens)
+		assert.Equal(t, "msg_1", resp.Metadata["id"])
+	})
+
+	t.Run("request overrides config", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{"content": [{"type": "text", "text": "ok"}]}`)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages:    []llminterface.Message{{Role: "user", Content: "hi"}},
+			Model:       "claude-3-5-haiku-latest",
+			MaxTokens:   256,
+			Temperature: 0.7,
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.Equal(t, "claude-3-5-haiku-latest", body["model"])
+		assert.Equal(t, float64(256), body["max_tokens"])
+		assert.Equal(t, 0.7, body["temperature"])
+		assert.NotContains(t, body, "system")
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{
+			"content": [
+				{"type": "text", "text": "Let me look."},
+				{"type": "tool_use", "id": "toolu_2", "name": "describe_table", "input": {"table": "users"}}
+			]
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "user", Content: "Count the users"},
+				{Role: "assistant", ToolCalls: []llminterface.ToolCall{
+					{ID: "toolu_1", Name: "list_tables", Arguments: "{}"},
+				}},
+				{Role: "tool", ToolCallID: "toolu_1", Content: "users"},
+				{Role: "user", Content: "Keep going"},
+			},
+			Tools: []llminterface.Tool{{
+				Name:        "describe_table",
+				Description: "Describe a table",
+				Parameters:  map[string]interface{}{"type": "object"},
+			}},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, llminterface.ToolCall{ID: "toolu_2", Name: "describe_table", Arguments: `{"table": "users"}`}, resp.ToolCalls[0])
+		assert.Equal(t, "Let me look.", resp.Content)
+
+		messages := body["messages"].([]interface{})
+		require.Len(t, messages, 3)
+
+		assistant := messages[1].(map[string]interface{})
+		assert.Equal(t, "assistant", assistant["role"])
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"type":  "tool_use",
+			"id":    "toolu_1",
+			"name":  "list_tables",
+			"input": map[string]interface{}{},
+		}}, assistant["content"])
+
+		// The tool result and the next question are merged into one user message
+		user := messages[2].(map[string]interface{})
+		assert.Equal(t, "user", user["role"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "users"},
+			map[string]interface{}{"type": "text", "text": "Keep going"},
+		}, user["content"])
+
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"name":         "describe_table",
+			"description":  "Describe a table",
+			"input_schema": map[string]interface{}{"type": "object"},
+		}}, body["tools"])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			status    int
+			errType   string
+			retryable bool
+		}{
+			{"rate limit", http.StatusTooManyRequests, "rate_limit_error", true},
+			{"overloaded", 529, "overloaded_error", true},
+			{"invalid request", http.StatusBadRequest, "invalid_request_error", false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				provider, _ := newTestProvider(t, tt.status, `{"type": "error", "error": {"type": "`+tt.errType+`", "message": "failed"}}`)
+
+				_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+					Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+				})
+
+				var llmErr *llminterface.Error
+				require.True(t, errors.As(err, &llmErr))
+				assert.Equal(t, "anthropic", llmErr.Provider)
+				assert.Equal(t, tt.errType, llmErr.Code)
+				assert.Equal(t, tt.retryable, llmErr.Retryable)
+			})
+		}
+	})
+}
@@ -0,0 +1,90 @@
+// Package audit wraps an llminterface.Provider to report every call with its
+// request, response, error and latency, so prompts and raw completions can be
+// inspected after the fact
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Call is a finished call to the wrapped provider
+type Call struct {
+	Request llminterface.CompletionRequest
+	// Response is nil when the call failed before producing one
+	Response *llminterface.CompletionResponse
+	Err      error
+	Started  time.Time
+	Latency  time.Duration
+}
+
+// Provider implements llminterface.Provider by delegating to another provider and
+// passing every finished call to a record function
+type Provider struct {
+	provider llminterface.Provider
+	record   func(ctx context.Context, call Call)
+	// now returns the current time; tests replace it
+	now func() time.Time
+}
+
+// Wrap returns provider reporting each Complete and CompleteStream call to record
+func Wrap(provider llminterface.Provider, record func(ctx context.Context, call Call)) *Provider {
+	return &Provider{
+		provider: provider,
+		record:   record,
+		now:      time.Now,
+	}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return p.provider.Initialize(ctx, config)
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	started := p.now()
+	response, err := p.provider.Complete(ctx, req)
+	p.finish(ctx, req, response, err, started)
+	return response, err
+}
+
+// CompleteStream implements llminterface.StreamingProvider. A stream is recorded
+// once it has ended, with the full completion.
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	started := p.now()
+	response, err := llminterface.CompleteStream(ctx, p.provider, req, handler)
+	p.finish(ctx, req, response, err, started)
+	return response, err
+}
+
+func (p *Provider) finish(ctx context.Context, req llminterface.CompletionRequest, response *llminterface.CompletionResponse, err error, started time.Time) {
+	if p.record == nil {
+		return
+	}
+	// The call is recorded even when the caller has given up on it
+	p.record(context.WithoutCancel(ctx), Call{
+		Request:  req,
+		Response: response,
+		Err:      err,
+		Started:  started,
+		Latency:  p.now().Sub(started),
+	})
+}
+
+// SupportsStructuredOutput implements llminterface.StructuredOutputProvider for
+// the wrapped provider
+func (p *Provider) SupportsStructuredOutput() bool {
+	return llminterface.SupportsStructuredOutput(p.provider)
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	return p.provider.Close(ctx)
+}
+
+// Clone wraps a clone of the provider with the same record function
+func (p *Provider) Clone() llminterface.Provider {
+	clone := Wrap(p.provider.Clone(), p.record)
+	clone.now = p.now
+	return clone
+}
@@ -0,0 +1,81 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Config implements llminterface.Config for AWS Bedrock
+type Config struct {
+	Name string
+	// APIKey holds the AWS credentials as "access_key_id:secret_access_key" with an
+	// optional ":session_token"
+	APIKey        string
+	Model         string // Model or inference profile ID, e.g., "anthropic.claude-3-5-sonnet-20240620-v1:0"
+	Region        string
+	ModelProvider string // Model family, inferred from the model ID when empty
+	Endpoint      string // Defaults to the Bedrock Runtime endpoint of the region
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := c.credentials(); err != nil {
+		return err
+	}
+	if c.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if _, err := c.family(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Config) Type() string {
+	return "bedrock"
+}
+
+// credentials parses the AWS credentials from the API key
+func (c *Config) credentials() (aws.Credentials, error) {
+	parts := strings.Split(c.APIKey, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return aws.Credentials{}, fmt.Errorf("API key must be access_key_id:secret_access_key[:session_token]")
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     parts[0],
+		SecretAccessKey: parts[1],
+		Source:          "LLMConfig",
+	}
+	if len(parts) == 3 {
+		creds.SessionToken = parts[2]
+	}
+	return creds, nil
+}
+
+// endpoint returns the Bedrock Runtime endpoint requests are sent to
+func (c *Config) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimRight(c.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", c.Region)
+}
+
+// NewConfig creates a new Bedrock configuration
+func NewConfig(name, apiKey, model, region, modelProvider, endpoint string) *Config {
+	return &Config{
+		Name:          name,
+		APIKey:        apiKey,
+		Model:         model,
+		Region:        region,
+		ModelProvider: modelProvider,
+		Endpoint:      endpoint,
+	}
+}
@@ -0,0 +1,90 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// family describes how the models of one provider on Bedrock take part in a
+// Converse conversation. Older models of most families take neither a system
+// prompt nor tools, so they are listed by model ID prefix.
+type family struct {
+	// noSystemPrompt lists the models that reject a system prompt; it is sent as
+	// part of the first user message instead
+	noSystemPrompt []string
+	// noTools lists the models that do not support tool use
+	noTools []string
+}
+
+// families maps the model_provider option to the model families it covers
+var families = map[string]family{
+	"anthropic": {},
+	"amazon": {
+		noSystemPrompt: []string{"amazon.titan-text"},
+		noTools:        []string{"amazon.titan-text"},
+	},
+	"meta": {
+		noTools: []string{"meta.llama2", "meta.llama3-8b", "meta.llama3-70b"},
+	},
+	"mistral": {
+		noSystemPrompt: []string{"mistral.mistral-7b-instruct", "mistral.mixtral-8x7b-instruct"},
+		noTools:        []string{"mistral.mistral-7b-instruct", "mistral.mixtral-8x7b-instruct"},
+	},
+	"cohere": {
+		noSystemPrompt: []string{"cohere.command-text", "cohere.command-light-text"},
+		noTools:        []string{"cohere.command-text", "cohere.command-light-text"},
+	},
+	"ai21": {
+		noSystemPrompt: []string{"ai21.j2"},
+		noTools:        []string{"ai21.j2"},
+	},
+}
+
+// inferenceProfilePrefixes are the geography prefixes of cross-region inference
+// profile IDs, such as "us.anthropic.claude-3-5-sonnet-20240620-v1:0"
+var inferenceProfilePrefixes = []string{"us.", "eu.", "apac.", "us-gov.", "global."}
+
+// baseModelID strips an ARN and an inference profile prefix from a model ID
+func baseModelID(model string) string {
+	if i := strings.LastIndex(model, "/"); i != -1 {
+		model = model[i+1:]
+	}
+	for _, prefix := range inferenceProfilePrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return strings.TrimPrefix(model, prefix)
+		}
+	}
+	return model
+}
+
+// family returns the model family of the configured model, either the configured
+// model_provider or the provider prefix of the model ID
+func (c *Config) family() (family, error) {
+	name := c.ModelProvider
+	if name == "" {
+		name, _, _ = strings.Cut(baseModelID(c.Model), ".")
+	}
+
+	f, ok := families[name]
+	if !ok {
+		return family{}, fmt.Errorf("unsupported model provider: %s", name)
+	}
+	return f, nil
+}
+
+func (f family) supportsSystemPrompt(model string) bool {
+	return !hasAnyPrefix(baseModelID(model), f.noSystemPrompt)
+}
+
+func (f family) supportsTools(model string) bool {
+	return !hasAnyPrefix(baseModelID(model), f.noTools)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,380 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// signingName is the service name Bedrock Runtime requests are signed for
+const signingName = "bedrock"
+
+// Provider implements llminterface.Provider for AWS Bedrock over the Bedrock
+// Runtime Converse API
+type Provider struct {
+	config      *Config
+	credentials aws.Credentials
+	family      family
+	signer      *v4.Signer
+	client      *http.Client
+	now         func() time.Time
+}
+
+func NewProvider() llminterface.Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	cfg, ok := config.(*Config)
+	if !ok {
+		return fmt.Errorf("invalid config type for Bedrock provider")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Validate has checked both already
+	p.credentials, _ = cfg.credentials()
+	p.family, _ = cfg.family()
+
+	p.config = cfg
+	p.signer = v4.NewSigner()
+	p.client = &http.Client{}
+	p.now = time.Now
+	return nil
+}
+
+// converseRequest is the body of POST /model/{modelId}/converse
+type converseRequest struct {
+	Messages        []message        `json:"messages"`
+	System          []contentBlock   `json:"system,omitempty"`
+	InferenceConfig *inferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *toolConfig      `json:"toolConfig,omitempty"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a text, toolUse or toolResult block
+type contentBlock struct {
+	Text       string      `json:"text,omitempty"`
+	ToolUse    *toolUse    `json:"toolUse,omitempty"`
+	ToolResult *toolResult `json:"toolResult,omitempty"`
+}
+
+type toolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type toolResult struct {
+	ToolUseID string         `json:"toolUseId"`
+	Content   []contentBlock `json:"content"`
+}
+
+type inferenceConfig struct {
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type toolConfig struct {
+	Tools []toolSpecification `json:"tools"`
+}
+
+type toolSpecification struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON map[string]interface{} `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+type converseResponse struct {
+	Output struct {
+		Message message `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+		TotalTokens  int `json:"totalTokens"`
+	} `json:"usage"`
+	Metrics struct {
+		LatencyMs int `json:"latencyMs"`
+	} `json:"metrics"`
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	model := req.Model
+	if model == "" {
+		model = p.config.Model
+	}
+
+	body, err := p.converseRequest(model, req)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint, err := url.Parse(p.config.endpoint())
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	// Model IDs contain colons, which are escaped in the path that gets signed
+	basePath := endpoint.EscapedPath()
+	endpoint.Path += "/model/" + model + "/converse"
+	endpoint.RawPath = basePath + "/model/" + escapePathSegment(model) + "/converse"
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	hash := sha256.Sum256(payload)
+	if err := p.signer.SignHTTP(ctx, p.credentials, httpReq, hex.EncodeToString(hash[:]), signingName, p.config.Region, p.now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	// Make the API call
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "bedrock",
+			Code:      "request_failed",
+			Message:   err.Error(),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "bedrock",
+			Code:      "request_failed",
+			Message:   fmt.Sprintf("failed to read response: %v", err),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, apiError(httpResp, respBody)
+	}
+
+	var completion converseResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, &llminterface.Error{
+			Provider: "bedrock",
+			Code:     "invalid_response",
+			Message:  fmt.Sprintf("failed to decode response: %v", err),
+		}
+	}
+
+	// Convert the response to our format
+	var content strings.Builder
+	response := &llminterface.CompletionResponse{
+		Metadata: map[string]interface{}{
+			"model":       model,
+			"stop_reason": completion.StopReason,
+			"latency_ms":  completion.Metrics.LatencyMs,
+		},
+	}
+	for _, block := range completion.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			response.ToolCalls = append(response.ToolCalls, llminterface.ToolCall{
+				ID:        block.ToolUse.ToolUseID,
+				Name:      block.ToolUse.Name,
+				Arguments: string(block.ToolUse.Input),
+			})
+		default:
+			content.WriteString(block.Text)
+		}
+	}
+	response.Content = content.String()
+
+	if response.Content == "" && len(response.ToolCalls) == 0 {
+		return nil, &llminterface.Error{
+			Provider: "bedrock",
+			Code:     "no_completion",
+			Message:  fmt.Sprintf("no content returned (stop reason %s)", completion.StopReason),
+		}
+	}
+
+	response.Usage.PromptTokens = completion.Usage.InputTokens
+	response.Usage.CompletionTokens = completion.Usage.OutputTokens
+	response.Usage.TotalTokens = completion.Usage.TotalTokens
+
+	return response, nil
+}
+
+// converseRequest converts a completion request to a Converse request for the
+// given model. System messages go into the system field, or into the first user
+// message for models that reject a system prompt, and tool results are sent as
+// toolResult blocks of a user message.
+func (p *Provider) converseRequest(model string, req llminterface.CompletionRequest) (*converseRequest, error) {
+	if len(req.Tools) > 0 && !p.family.supportsTools(model) {
+		return nil, fmt.Errorf("model %s does not support tool use", model)
+	}
+
+	body := &converseRequest{}
+
+	config := &inferenceConfig{MaxTokens: req.MaxTokens}
+	if req.Temperature != 0 {
+		temperature := req.Temperature
+		config.Temperature = &temperature
+	}
+	if config.MaxTokens != 0 || config.Temperature != nil {
+		body.InferenceConfig = config
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "user":
+			body.Messages = appendBlocks(body.Messages, "user", contentBlock{Text: msg.Content})
+		case "assistant":
+			var blocks []contentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if len(strings.TrimSpace(call.Arguments)) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, contentBlock{ToolUse: &toolUse{ToolUseID: call.ID, Name: call.Name, Input: input}})
+			}
+			body.Messages = appendBlocks(body.Messages, "assistant", blocks...)
+		case "tool":
+			body.Messages = appendBlocks(body.Messages, "user", contentBlock{ToolResult: &toolResult{
+				ToolUseID: msg.ToolCallID,
+				Content:   []contentBlock{{Text: msg.Content}},
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+
+	if len(system) > 0 {
+		if p.family.supportsSystemPrompt(model) {
+			for _, text := range system {
+				body.System = append(body.System, contentBlock{Text: text})
+			}
+		} else {
+			prompt := contentBlock{Text: strings.Join(system, "\n\n")}
+			if len(body.Messages) > 0 && body.Messages[0].Role == "user" {
+				body.Messages[0].Content = append([]contentBlock{prompt}, body.Messages[0].Content...)
+			} else {
+				body.Messages = append([]message{{Role: "user", Content: []contentBlock{prompt}}}, body.Messages...)
+			}
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		body.ToolConfig = &toolConfig{}
+		for _, tool := range req.Tools {
+			var spec toolSpecification
+			spec.ToolSpec.Name = tool.Name
+			spec.ToolSpec.Description = tool.Description
+			spec.ToolSpec.InputSchema.JSON = tool.Parameters
+			body.ToolConfig.Tools = append(body.ToolConfig.Tools, spec)
+		}
+	}
+
+	return body, nil
+}
+
+// appendBlocks adds content blocks to the conversation, merging them into the last
+// message when it has the same role, since Converse expects the roles to alternate
+func appendBlocks(messages []message, role string, blocks ...contentBlock) []message {
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, message{Role: role, Content: blocks})
+}
+
+// escapePathSegment percent-encodes everything but the unreserved characters, the
+// way the AWS SDKs encode path parameters
+func escapePathSegment(segment string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) != -1 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// apiError converts an error response of Bedrock Runtime. Throttling, model
+// readiness, timeouts and server errors are retryable.
+func apiError(resp *http.Response, body []byte) *llminterface.Error {
+	statusCode := resp.StatusCode
+	// The error type header may carry a URI after a colon
+	code, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+	if code == "" {
+		code = "api_error"
+	}
+
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+		message = errResp.Message
+	}
+
+	return &llminterface.Error{
+		Provider:   "bedrock",
+		Code:       code,
+		Message:    fmt.Sprintf("bedrock API returned %d: %s", statusCode, message),
+		Retryable:  statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout || statusCode >= 500,
+		StatusCode: statusCode,
+		RetryAfter: llminterface.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *Provider) Clone() llminterface.Provider {
+	return NewProvider()
+}
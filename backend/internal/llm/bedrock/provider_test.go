@@ -0,0 +1,255 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/shahariaazam/smart-insights/internal/llm/internal/llmtest"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var signingTime = time.Date(2024, 11, 5, 10, 30, 0, 0, time.UTC)
+
+// newTestProvider points a provider at a stand-in Bedrock Runtime endpoint
+// answering with the given status, error type and body
+func newTestProvider(t *testing.T, apiKey, model, modelProvider string, status int, errorType, response string) (*Provider, *llmtest.Server) {
+	t.Helper()
+
+	header := http.Header{}
+	if errorType != "" {
+		header.Set("X-Amzn-ErrorType", errorType)
+	}
+	server := llmtest.NewServer(t, llmtest.Response{Status: status, Header: header, Body: response})
+
+	provider := &Provider{}
+	config := NewConfig("test", apiKey, model, "us-east-1", modelProvider, server.URL)
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	provider.now = func() time.Time { return signingTime }
+	return provider, server
+}
+
+// expectedAuthorization signs the last request the server received again, from
+// what actually arrived, to check the signature the provider sent
+func expectedAuthorization(t *testing.T, provider *Provider, server *llmtest.Server) string {
+	t.Helper()
+
+	r, body := server.Request(), server.RawBody()
+	resigned, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.EscapedPath(), bytes.NewReader(body))
+	require.NoError(t, err)
+	resigned.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	resigned.Header.Set("Accept", r.Header.Get("Accept"))
+	hash := sha256.Sum256(body)
+	require.NoError(t, v4.NewSigner().SignHTTP(context.Background(), provider.credentials, resigned, hex.EncodeToString(hash[:]), "bedrock", "us-east-1", signingTime))
+	return resigned.Header.Get("Authorization")
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			config: NewConfig("test", "AKID:secret", "anthropic.claude-3-5-sonnet-20240620-v1:0", "us-east-1", "", ""),
+		},
+		{
+			name:   "inference profile",
+			config: NewConfig("test", "AKID:secret:token", "us.meta.llama3-1-70b-instruct-v1:0", "us-east-1", "", ""),
+		},
+		{
+			name:    "missing secret",
+			config:  NewConfig("test", "AKID", "anthropic.claude-3-5-sonnet-20240620-v1:0", "us-east-1", "", ""),
+			wantErr: "API key must be",
+		},
+		{
+			name:    "missing region",
+			config:  NewConfig("test", "AKID:secret", "anthropic.claude-3-5-sonnet-20240620-v1:0", "", "", ""),
+			wantErr: "region is required",
+		},
+		{
+			name:    "unknown model provider",
+			config:  NewConfig("test", "AKID:secret", "custom-model", "us-east-1", "", ""),
+			wantErr: "unsupported model provider: custom-model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderComplete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("signed text response", func(t *testing.T) {
+		provider, server := newTestProvider(t, "AKID:secret:token", "anthropic.claude-3-5-sonnet-20240620-v1:0", "", http.StatusOK, "", `{
+			"output": {"message": {"role": "assistant", "content": [{"text": "SELECT 1"}]}},
+			"stopReason": "end_turn",
+			"usage": {"inputTokens": 12, "outputTokens": 3, "totalTokens": 15},
+			"metrics": {"latencyMs": 120}
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "system", Content: "You are a SQL expert."},
+				{Role: "user", Content: "Count the users"},
+			},
+			MaxTokens:   256,
+			Temperature: 0.3,
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.Equal(t, "/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/converse", server.Request().URL.EscapedPath())
+		assert.Contains(t, server.Request().Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20241105/us-east-1/bedrock/aws4_request")
+		assert.Equal(t, expectedAuthorization(t, provider, server), server.Request().Header.Get("Authorization"))
+		assert.Equal(t, "token", server.Request().Header.Get("X-Amz-Security-Token"))
+
+		assert.Equal(t, []interface{}{map[string]interface{}{"text": "You are a SQL expert."}}, body["system"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"role": "user", "content": []interface{}{map[string]interface{}{"text": "Count the users"}}},
+		}, body["messages"])
+		assert.Equal(t, map[string]interface{}{"maxTokens": float64(256), "temperature": 0.3}, body["inferenceConfig"])
+
+		assert.Equal(t, "SELECT 1", resp.Content)
+		assert.Equal(t, 12, resp.Usage.PromptTokens)
+		assert.Equal(t, 3, resp.Usage.CompletionTokens)
+		assert.Equal(t, 15, resp.Usage.TotalTokens)
+		assert.Equal(t, "end_turn", resp.Metadata["stop_reason"])
+	})
+
+	t.Run("tool use", func(t *testing.T) {
+		provider, server := newTestProvider(t, "AKID:secret", "us.anthropic.claude-3-5-sonnet-20240620-v1:0", "anthropic", http.StatusOK, "", `{
+			"output": {"message": {"role": "assistant", "content": [
+				{"toolUse": {"toolUseId": "tooluse_2", "name": "describe_table", "input": {"table": "users"}}}
+			]}},
+			"stopReason": "tool_use"
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "user", Content: "Count the users"},
+				{Role: "assistant", ToolCalls: []llminterface.ToolCall{
+					{ID: "tooluse_1", Name: "list_tables", Arguments: "{}"},
+				}},
+				{Role: "tool", ToolCallID: "tooluse_1", Content: "users"},
+			},
+			Tools: []llminterface.Tool{{
+				Name:        "describe_table",
+				Description: "Describe a table",
+				Parameters:  map[string]interface{}{"type": "object"},
+			}},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, llminterface.ToolCall{ID: "tooluse_2", Name: "describe_table", Arguments: `{"table": "users"}`}, resp.ToolCalls[0])
+
+		messages := body["messages"].([]interface{})
+		require.Len(t, messages, 3)
+		assert.Equal(t, map[string]interface{}{
+			"role": "assistant",
+			"content": []interface{}{map[string]interface{}{
+				"toolUse": map[string]interface{}{"toolUseId": "tooluse_1", "name": "list_tables", "input": map[string]interface{}{}},
+			}},
+		}, messages[1])
+		assert.Equal(t, map[string]interface{}{
+			"role": "user",
+			"content": []interface{}{map[string]interface{}{
+				"toolResult": map[string]interface{}{
+					"toolUseId": "tooluse_1",
+					"content":   []interface{}{map[string]interface{}{"text": "users"}},
+				},
+			}},
+		}, messages[2])
+
+		assert.Equal(t, map[string]interface{}{
+			"tools": []interface{}{map[string]interface{}{
+				"toolSpec": map[string]interface{}{
+					"name":        "describe_table",
+					"description": "Describe a table",
+					"inputSchema": map[string]interface{}{"json": map[string]interface{}{"type": "object"}},
+				},
+			}},
+		}, body["toolConfig"])
+	})
+
+	t.Run("model without system prompt", func(t *testing.T) {
+		provider, server := newTestProvider(t, "AKID:secret", "mistral.mistral-7b-instruct-v0:2", "", http.StatusOK, "", `{
+			"output": {"message": {"role": "assistant", "content": [{"text": "ok"}]}}
+		}`)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "system", Content: "You are a SQL expert."},
+				{Role: "user", Content: "Count the users"},
+			},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.NotContains(t, body, "system")
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"text": "You are a SQL expert."},
+				map[string]interface{}{"text": "Count the users"},
+			}},
+		}, body["messages"])
+
+		_, err = provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+			Tools:    []llminterface.Tool{{Name: "list_tables"}},
+		})
+		assert.ErrorContains(t, err, "does not support tool use")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			status    int
+			errorType string
+			wantCode  string
+			retryable bool
+		}{
+			{"throttling", http.StatusTooManyRequests, "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/", "ThrottlingException", true},
+			{"unavailable", http.StatusServiceUnavailable, "ServiceUnavailableException", "ServiceUnavailableException", true},
+			{"validation", http.StatusBadRequest, "ValidationException", "ValidationException", false},
+			{"access denied", http.StatusForbidden, "AccessDeniedException", "AccessDeniedException", false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				provider, _ := newTestProvider(t, "AKID:secret", "anthropic.claude-3-5-sonnet-20240620-v1:0", "", tt.status, tt.errorType, `{"message": "failed"}`)
+
+				_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+					Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+				})
+
+				var llmErr *llminterface.Error
+				require.True(t, errors.As(err, &llmErr))
+				assert.Equal(t, "bedrock", llmErr.Provider)
+				assert.Equal(t, tt.wantCode, llmErr.Code)
+				assert.Equal(t, tt.retryable, llmErr.Retryable)
+				assert.Contains(t, llmErr.Message, "failed")
+			})
+		}
+	})
+}
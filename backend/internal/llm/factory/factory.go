@@ -1,17 +1,38 @@
 package factory
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/shahariaazam/smart-insights/internal/llm/anthropic"
+	"github.com/shahariaazam/smart-insights/internal/llm/bedrock"
+	"github.com/shahariaazam/smart-insights/internal/llm/fake"
+	"github.com/shahariaazam/smart-insights/internal/llm/gemini"
+	"github.com/shahariaazam/smart-insights/internal/llm/httpjson"
 	"github.com/shahariaazam/smart-insights/internal/llm/openai"
 	"github.com/shahariaazam/smart-insights/internal/llminterface"
 )
 
+// FakeProviderType is the test-only provider type that answers without a network
+// connection. It is not offered through the API, but lets tests run whole
+// orchestrations from scripted rules or recorded cassettes.
+const FakeProviderType = "fake"
+
 // CreateProvider creates a new LLM provider instance based on the provider type
 func CreateProvider(providerType string) (llminterface.Provider, error) {
 	switch providerType {
-	case "openai":
+	case "openai", "openai_compatible":
 		return openai.NewProvider(), nil
+	case "anthropic":
+		return anthropic.NewProvider(), nil
+	case "gemini":
+		return gemini.NewProvider(), nil
+	case "bedrock":
+		return bedrock.NewProvider(), nil
+	case "http":
+		return httpjson.NewProvider(), nil
+	case FakeProviderType:
+		return fake.NewProvider(), nil
 	// Add cases for other providers here
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
@@ -23,23 +44,183 @@ func CreateConfig(providerType string, name, apiKey, model string, options map[s
 	switch providerType {
 	case "openai":
 		return &openai.Config{
-			Name:      name,
-			APIKey:    apiKey,
-			Model:     model,
-			MaxTokens: getMaxTokens(options),
+			Name:             name,
+			APIKey:           apiKey,
+			Model:            model,
+			MaxTokens:        getMaxTokens(options),
+			StructuredOutput: true,
+		}, nil
+	case "openai_compatible":
+		baseURL := getString(options, "base_url")
+		if baseURL == "" {
+			return nil, fmt.Errorf("base_url is required for an OpenAI-compatible provider")
+		}
+		return &openai.Config{
+			Name:       name,
+			APIKey:     apiKey,
+			Model:      model,
+			MaxTokens:  getMaxTokens(options),
+			BaseURL:    baseURL,
+			Headers:    getStringMap(options, "headers"),
+			APIVersion: getString(options, "api_version"),
+			// Compatible servers only get response formats when they are known to
+			// support them
+			StructuredOutput: getBool(options, "structured_output"),
 		}, nil
+	case "anthropic":
+		return anthropic.NewConfig(
+			name,
+			apiKey,
+			model,
+			getInt(options, "max_tokens_to_sample", 0),
+			getFloat(options, "temperature", 0),
+			getInt(options, "top_k", 0),
+		), nil
+	case "gemini":
+		return gemini.NewConfig(
+			name,
+			apiKey,
+			model,
+			getFloat(options, "temperature", 0),
+			getInt(options, "max_output_tokens", 0),
+		), nil
+	case "bedrock":
+		return bedrock.NewConfig(
+			name,
+			apiKey,
+			model,
+			getString(options, "region"),
+			getString(options, "model_provider"),
+			getString(options, "endpoint"),
+		), nil
+	case "http":
+		return &httpjson.Config{
+			Name:                 name,
+			APIKey:               apiKey,
+			Model:                model,
+			MaxTokens:            getInt(options, "max_tokens", 0),
+			URL:                  getString(options, "url"),
+			Method:               getString(options, "method"),
+			Headers:              getStringMap(options, "headers"),
+			RequestTemplate:      getString(options, "request_template"),
+			ContentPath:          getString(options, "content_path"),
+			PromptTokensPath:     getString(options, "prompt_tokens_path"),
+			CompletionTokensPath: getString(options, "completion_tokens_path"),
+			TotalTokensPath:      getString(options, "total_tokens_path"),
+			ErrorPath:            getString(options, "error_path"),
+		}, nil
+	case FakeProviderType:
+		return createFakeConfig(name, model, options)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
 }
 
+// fakeOptions are the options of the fake provider. Upstream describes the provider
+// that records the exchanges missing from a cassette.
+type fakeOptions struct {
+	Mode     string      `json:"mode"`
+	Rules    []fake.Rule `json:"rules"`
+	Cassette string      `json:"cassette"`
+	Upstream *struct {
+		Type    string                 `json:"type"`
+		APIKey  string                 `json:"api_key"`
+		Model   string                 `json:"model"`
+		Options map[string]interface{} `json:"options"`
+	} `json:"upstream"`
+}
+
+func createFakeConfig(name, model string, options map[string]interface{}) (llminterface.Config, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fake provider options: %w", err)
+	}
+	var opts fakeOptions
+	if err := json.Unmarshal(encoded, &opts); err != nil {
+		return nil, fmt.Errorf("invalid fake provider options: %w", err)
+	}
+
+	config := &fake.Config{
+		Name:         name,
+		Model:        model,
+		Mode:         opts.Mode,
+		Rules:        opts.Rules,
+		CassettePath: opts.Cassette,
+	}
+
+	if opts.Upstream != nil {
+		if opts.Upstream.Type == FakeProviderType {
+			return nil, fmt.Errorf("the upstream of a fake provider cannot be fake")
+		}
+		config.Upstream, err = CreateProvider(opts.Upstream.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream provider: %w", err)
+		}
+		config.UpstreamConfig, err = CreateConfig(opts.Upstream.Type, name, opts.Upstream.APIKey, opts.Upstream.Model, opts.Upstream.Options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream provider: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
 // Helper function to extract max tokens from options
 func getMaxTokens(options map[string]interface{}) int {
-	if options == nil {
-		return 3000 // Default value
+	maxTokens := getInt(options, "max_tokens", 0)
+	if maxTokens == 0 {
+		return 3000 // Default if not specified or invalid
 	}
-	if maxTokens, ok := options["max_tokens"].(float64); ok {
-		return int(maxTokens)
+	return maxTokens
+}
+
+// getInt reads a numeric option, which is a float64 when it was decoded from JSON
+func getInt(options map[string]interface{}, key string, defaultValue int) int {
+	switch value := options[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	default:
+		return defaultValue
+	}
+}
+
+func getFloat(options map[string]interface{}, key string, defaultValue float64) float64 {
+	switch value := options[key].(type) {
+	case float64:
+		return value
+	case int:
+		return float64(value)
+	default:
+		return defaultValue
+	}
+}
+
+func getString(options map[string]interface{}, key string) string {
+	value, _ := options[key].(string)
+	return value
+}
+
+func getBool(options map[string]interface{}, key string) bool {
+	value, _ := options[key].(bool)
+	return value
+}
+
+// getStringMap reads an object option of string values, such as HTTP headers
+func getStringMap(options map[string]interface{}, key string) map[string]string {
+	switch value := options[key].(type) {
+	case map[string]string:
+		return value
+	case map[string]interface{}:
+		values := make(map[string]string, len(value))
+		for k, v := range value {
+			if s, ok := v.(string); ok {
+				values[k] = s
+			}
+		}
+		return values
+	default:
+		return nil
 	}
-	return 3000 // Default if not specified or invalid
 }
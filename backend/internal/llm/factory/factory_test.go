@@ -0,0 +1,58 @@
+package factory
+
+import (
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateConfigOpenAICompatible(t *testing.T) {
+	t.Run("self-hosted without API key", func(t *testing.T) {
+		config, err := CreateConfig("openai_compatible", "local", "", "llama3", map[string]interface{}{
+			"base_url": "http://localhost:11434/v1",
+			"headers":  map[string]interface{}{"X-Gateway": "insights"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, config.Validate())
+
+		cfg := config.(*openai.Config)
+		assert.Equal(t, "http://localhost:11434/v1", cfg.BaseURL)
+		assert.Equal(t, map[string]string{"X-Gateway": "insights"}, cfg.Headers)
+		assert.Equal(t, 3000, cfg.MaxTokens)
+		assert.False(t, cfg.StructuredOutput)
+
+		provider, err := CreateProvider("openai_compatible")
+		require.NoError(t, err)
+		assert.IsType(t, &openai.Provider{}, provider)
+	})
+
+	t.Run("azure deployment", func(t *testing.T) {
+		config, err := CreateConfig("openai_compatible", "azure", "key", "gpt-4o", map[string]interface{}{
+			"base_url":          "https://example.openai.azure.com/openai/deployments/gpt-4o",
+			"api_version":       "2024-06-01",
+			"max_tokens":        float64(1000),
+			"structured_output": true,
+		})
+		require.NoError(t, err)
+		require.NoError(t, config.Validate())
+
+		cfg := config.(*openai.Config)
+		assert.Equal(t, "2024-06-01", cfg.APIVersion)
+		assert.Equal(t, 1000, cfg.MaxTokens)
+		assert.True(t, cfg.StructuredOutput)
+	})
+
+	t.Run("missing base URL", func(t *testing.T) {
+		_, err := CreateConfig("openai_compatible", "local", "", "llama3", nil)
+		assert.ErrorContains(t, err, "base_url is required")
+	})
+
+	t.Run("OpenAI still requires an API key", func(t *testing.T) {
+		config, err := CreateConfig("openai", "openai", "", "gpt-4o", nil)
+		require.NoError(t, err)
+		assert.ErrorContains(t, config.Validate(), "API key is required")
+		assert.True(t, config.(*openai.Config).StructuredOutput)
+	})
+}
@@ -0,0 +1,73 @@
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// cassette is the file format of recorded exchanges
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	// Key identifies the request, so requests are matched however the file is edited
+	Key      string                          `json:"key"`
+	Request  llminterface.CompletionRequest  `json:"request"`
+	Response llminterface.CompletionResponse `json:"response"`
+}
+
+// requestKey hashes everything that may change the completion of a request
+func requestKey(req llminterface.CompletionRequest) (string, error) {
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// loadCassette reads a cassette file; a missing file is an empty cassette
+func loadCassette(path string) (*cassette, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &cassette{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// save writes the cassette through a temporary file, so an interrupted recording
+// does not leave a truncated cassette behind
+func (c *cassette) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
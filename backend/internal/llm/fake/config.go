@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Modes of the fake provider
+const (
+	// ModeScripted answers with the response of the first rule matching the prompt
+	ModeScripted = "scripted"
+	// ModeCassette replays exchanges from a cassette file, recording the ones it
+	// does not have through the upstream provider when there is one
+	ModeCassette = "cassette"
+)
+
+// Rule is a canned completion for prompts matching Pattern
+type Rule struct {
+	// Pattern is a regular expression matched against the prompt
+	Pattern string `json:"pattern"`
+	// Response is the completion content; $1 or ${name} expand to the groups
+	// captured by Pattern
+	Response  string                  `json:"response"`
+	ToolCalls []llminterface.ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Config implements llminterface.Config for the fake provider
+type Config struct {
+	Name  string
+	Model string
+	Mode  string // Defaults to ModeScripted
+
+	// Rules are tried in order in scripted mode
+	Rules []Rule
+
+	// CassettePath is the file exchanges are replayed from in cassette mode
+	CassettePath string
+	// Upstream answers and records the requests missing from the cassette. Without
+	// it, a missing request is an error.
+	Upstream       llminterface.Provider
+	UpstreamConfig llminterface.Config
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch c.Mode {
+	case "", ModeScripted:
+		if len(c.Rules) == 0 {
+			return fmt.Errorf("at least one rule is required in scripted mode")
+		}
+		for i, rule := range c.Rules {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern in rule %d: %w", i, err)
+			}
+		}
+	case ModeCassette:
+		if c.CassettePath == "" {
+			return fmt.Errorf("cassette path is required in cassette mode")
+		}
+		if (c.Upstream == nil) != (c.UpstreamConfig == nil) {
+			return fmt.Errorf("upstream provider and config must be set together")
+		}
+	default:
+		return fmt.Errorf("unsupported mode: %s", c.Mode)
+	}
+	return nil
+}
+
+func (c *Config) Type() string {
+	return "fake"
+}
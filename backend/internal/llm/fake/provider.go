@@ -0,0 +1,245 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Provider implements llminterface.Provider without a network connection, for
+// tests that run whole orchestrations. It answers from scripted rules or replays a
+// cassette of recorded exchanges.
+type Provider struct {
+	config   *Config
+	patterns []*regexp.Regexp
+
+	mu       sync.Mutex
+	cassette *cassette
+	// replayed counts how often each request key has been answered, so repeated
+	// requests replay their recordings in order
+	replayed map[string]int
+	requests []llminterface.CompletionRequest
+}
+
+func NewProvider() llminterface.Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	cfg, ok := config.(*Config)
+	if !ok {
+		return fmt.Errorf("invalid config type for fake provider")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Validate has compiled the patterns already
+	p.patterns = make([]*regexp.Regexp, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		p.patterns[i] = regexp.MustCompile(rule.Pattern)
+	}
+
+	if cfg.Mode == ModeCassette {
+		c, err := loadCassette(cfg.CassettePath)
+		if err != nil {
+			return err
+		}
+		p.cassette = c
+		p.replayed = make(map[string]int)
+
+		if cfg.Upstream != nil {
+			if err := cfg.Upstream.Initialize(ctx, cfg.UpstreamConfig); err != nil {
+				return fmt.Errorf("failed to initialize upstream provider: %w", err)
+			}
+		}
+	}
+
+	p.config = cfg
+	return nil
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	if p.config == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+
+	if p.config.Mode == ModeCassette {
+		return p.replay(ctx, req)
+	}
+	return p.script(req)
+}
+
+var streamPiece = regexp.MustCompile(`\s*\S+`)
+
+// CompleteStream implements llminterface.StreamingProvider by handing the
+// completion to the handler one word at a time
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	response, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each piece is a word with the whitespace in front of it
+	content := response.Content
+	offset := 0
+	for _, piece := range streamPiece.FindAllStringIndex(content, -1) {
+		if err := handler(content[offset:piece[1]]); err != nil {
+			return nil, err
+		}
+		offset = piece[1]
+	}
+	if offset < len(content) {
+		if err := handler(content[offset:]); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// Requests returns the requests the provider has received, in order
+func (p *Provider) Requests() []llminterface.CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]llminterface.CompletionRequest(nil), p.requests...)
+}
+
+// Prompt returns the text scripted rules are matched against: the last user or
+// tool message of the request
+func Prompt(req llminterface.CompletionRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if role := req.Messages[i].Role; role == "user" || role == "tool" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// script answers with the first rule matching the prompt
+func (p *Provider) script(req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	prompt := Prompt(req)
+	for i, pattern := range p.patterns {
+		match := pattern.FindStringSubmatchIndex(prompt)
+		if match == nil {
+			continue
+		}
+
+		rule := p.config.Rules[i]
+		content := string(pattern.ExpandString(nil, rule.Response, prompt, match))
+		response := &llminterface.CompletionResponse{
+			Content:   content,
+			ToolCalls: append([]llminterface.ToolCall(nil), rule.ToolCalls...),
+			Metadata: map[string]interface{}{
+				"model": p.model(req),
+				"rule":  i,
+			},
+		}
+		response.Usage.PromptTokens = countTokens(req)
+		response.Usage.CompletionTokens = len(strings.Fields(content))
+		response.Usage.TotalTokens = response.Usage.PromptTokens + response.Usage.CompletionTokens
+		return response, nil
+	}
+
+	return nil, &llminterface.Error{
+		Provider: "fake",
+		Code:     "no_match",
+		Message:  fmt.Sprintf("no scripted response matches the prompt: %.200s", prompt),
+	}
+}
+
+// replay answers from the cassette, recording the exchange through the upstream
+// provider when the cassette does not have it
+func (p *Provider) replay(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := 0
+	var last *llminterface.CompletionResponse
+	for i := range p.cassette.Interactions {
+		interaction := &p.cassette.Interactions[i]
+		if interaction.Key != key {
+			continue
+		}
+		if seen == p.replayed[key] {
+			p.replayed[key]++
+			response := interaction.Response
+			return &response, nil
+		}
+		seen++
+		last = &interaction.Response
+	}
+
+	if p.config.Upstream == nil {
+		// Without an upstream, a request made more often than it was recorded gets
+		// the last recording again
+		if last != nil {
+			response := *last
+			return &response, nil
+		}
+		return nil, &llminterface.Error{
+			Provider: "fake",
+			Code:     "not_recorded",
+			Message:  fmt.Sprintf("request %s is not in cassette %s", key, p.config.CassettePath),
+		}
+	}
+
+	response, err := p.config.Upstream.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction{
+		Key:      key,
+		Request:  req,
+		Response: *response,
+	})
+	p.replayed[key]++
+	if err := p.cassette.save(p.config.CassettePath); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (p *Provider) model(req llminterface.CompletionRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.config.Model
+}
+
+// countTokens approximates the prompt tokens by the words of all messages
+func countTokens(req llminterface.CompletionRequest) int {
+	tokens := 0
+	for _, msg := range req.Messages {
+		tokens += len(strings.Fields(msg.Content))
+	}
+	return tokens
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	if p.config != nil && p.config.Upstream != nil {
+		return p.config.Upstream.Close(ctx)
+	}
+	return nil
+}
+
+func (p *Provider) Clone() llminterface.Provider {
+	return NewProvider()
+}
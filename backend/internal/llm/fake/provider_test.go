@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userRequest(prompt string) llminterface.CompletionRequest {
+	return llminterface.CompletionRequest{
+		Messages: []llminterface.Message{
+			{Role: "system", Content: "You are a SQL expert."},
+			{Role: "user", Content: prompt},
+		},
+	}
+}
+
+func newScripted(t *testing.T, rules ...Rule) *Provider {
+	t.Helper()
+	provider := &Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), &Config{
+		Name:  "fake",
+		Model: "fake-model",
+		Rules: rules,
+	}))
+	return provider
+}
+
+func TestScripted(t *testing.T) {
+	ctx := context.Background()
+
+	provider := newScripted(t,
+		Rule{Pattern: `(?i)how many (\w+)`, Response: "<sql>SELECT count(*) FROM $1</sql>"},
+		Rule{Pattern: `describe`, ToolCalls: []llminterface.ToolCall{{ID: "call_1", Name: "list_tables", Arguments: "{}"}}},
+		Rule{Pattern: `Query Results`, Response: "<markdown>There are 42 users.</markdown>"},
+	)
+
+	t.Run("first matching rule with expansion", func(t *testing.T) {
+		resp, err := provider.Complete(ctx, userRequest("How many users signed up?"))
+		require.NoError(t, err)
+		assert.Equal(t, "<sql>SELECT count(*) FROM users</sql>", resp.Content)
+		assert.Equal(t, 0, resp.Metadata["rule"])
+		assert.Equal(t, 10, resp.Usage.PromptTokens)
+		assert.Equal(t, 4, resp.Usage.CompletionTokens)
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		resp, err := provider.Complete(ctx, userRequest("describe the database"))
+		require.NoError(t, err)
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, "list_tables", resp.ToolCalls[0].Name)
+	})
+
+	t.Run("matches the last user or tool message", func(t *testing.T) {
+		req := userRequest("describe the database")
+		req.Messages = append(req.Messages,
+			llminterface.Message{Role: "assistant", ToolCalls: []llminterface.ToolCall{{ID: "call_1", Name: "list_tables"}}},
+			llminterface.Message{Role: "tool", ToolCallID: "call_1", Content: "How many orders"},
+		)
+		resp, err := provider.Complete(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "<sql>SELECT count(*) FROM orders</sql>", resp.Content)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		_, err := provider.Complete(ctx, userRequest("Tell me a joke"))
+
+		var llmErr *llminterface.Error
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, "no_match", llmErr.Code)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var pieces []string
+		resp, err := provider.CompleteStream(ctx, userRequest("Query Results: 42"), func(delta string) error {
+			pieces = append(pieces, delta)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"<markdown>There", " are", " 42", " users.</markdown>"}, pieces)
+		assert.Equal(t, resp.Content, strings.Join(pieces, ""))
+	})
+
+	t.Run("requests are recorded", func(t *testing.T) {
+		requests := provider.Requests()
+		require.Len(t, requests, 5)
+		assert.Equal(t, "How many users signed up?", Prompt(requests[0]))
+	})
+}
+
+func TestCassette(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassettes", "ask.json")
+
+	// The upstream is scripted here; in practice it is a real provider
+	upstream := &Provider{}
+	upstreamConfig := &Config{
+		Name:  "upstream",
+		Rules: []Rule{{Pattern: `users`, Response: "<sql>SELECT count(*) FROM users</sql>"}},
+	}
+
+	t.Run("record", func(t *testing.T) {
+		recorder := &Provider{}
+		require.NoError(t, recorder.Initialize(ctx, &Config{
+			Name:           "fake",
+			Mode:           ModeCassette,
+			CassettePath:   path,
+			Upstream:       upstream,
+			UpstreamConfig: upstreamConfig,
+		}))
+
+		resp, err := recorder.Complete(ctx, userRequest("How many users?"))
+		require.NoError(t, err)
+		assert.Equal(t, "<sql>SELECT count(*) FROM users</sql>", resp.Content)
+		assert.Len(t, upstream.Requests(), 1)
+
+		// A recorded request is replayed without calling the upstream again
+		recorder2 := &Provider{}
+		require.NoError(t, recorder2.Initialize(ctx, &Config{
+			Name:           "fake",
+			Mode:           ModeCassette,
+			CassettePath:   path,
+			Upstream:       upstream,
+			UpstreamConfig: upstreamConfig,
+		}))
+		_, err = recorder2.Complete(ctx, userRequest("How many users?"))
+		require.NoError(t, err)
+		assert.Len(t, upstream.Requests(), 1)
+
+		_, err = os.Stat(path)
+		assert.NoError(t, err)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		player := &Provider{}
+		require.NoError(t, player.Initialize(ctx, &Config{
+			Name:         "fake",
+			Mode:         ModeCassette,
+			CassettePath: path,
+		}))
+
+		resp, err := player.Complete(ctx, userRequest("How many users?"))
+		require.NoError(t, err)
+		assert.Equal(t, "<sql>SELECT count(*) FROM users</sql>", resp.Content)
+
+		// Replayed more often than recorded, the last recording is repeated
+		resp, err = player.Complete(ctx, userRequest("How many users?"))
+		require.NoError(t, err)
+		assert.Equal(t, "<sql>SELECT count(*) FROM users</sql>", resp.Content)
+
+		_, err = player.Complete(ctx, userRequest("How many orders?"))
+		var llmErr *llminterface.Error
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, "not_recorded", llmErr.Code)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"scripted without rules", Config{Name: "fake"}, "at least one rule"},
+		{"invalid pattern", Config{Name: "fake", Rules: []Rule{{Pattern: "("}}}, "invalid pattern in rule 0"},
+		{"cassette without path", Config{Name: "fake", Mode: ModeCassette}, "cassette path is required"},
+		{"upstream without config", Config{Name: "fake", Mode: ModeCassette, CassettePath: "a.json", Upstream: &Provider{}}, "must be set together"},
+		{"unknown mode", Config{Name: "fake", Mode: "live"}, "unsupported mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.config.Validate(), tt.wantErr)
+		})
+	}
+}
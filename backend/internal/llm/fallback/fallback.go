@@ -0,0 +1,131 @@
+// Package fallback combines several providers into one that tries them in order,
+// so an outage of one LLM does not fail every ask
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Entry is one provider of a chain
+type Entry struct {
+	// Name identifies the entry in callbacks and errors, e.g. "openai/gpt-4o"
+	Name     string
+	Provider llminterface.Provider
+}
+
+// Options configures the provider returned by New
+type Options struct {
+	// OnFallback is called when from failed and to is tried next
+	OnFallback func(ctx context.Context, from, to Entry, err error)
+	// OnAnswer is called with the entry that produced each completion
+	OnAnswer func(ctx context.Context, entry Entry, response *llminterface.CompletionResponse)
+}
+
+// Provider implements llminterface.Provider by calling its entries in order until
+// one of them answers. Every call starts with the first entry again, so a chain
+// returns to its primary LLM as soon as that recovers.
+//
+// Entries are expected to retry transient errors themselves, so any error an entry
+// returns moves on to the next one.
+type Provider struct {
+	entries []Entry
+	options Options
+}
+
+// New returns a provider for the given initialized entries
+func New(entries []Entry, opts Options) *Provider {
+	return &Provider{
+		entries: entries,
+		options: opts,
+	}
+}
+
+// Initialize is not supported: the entries are initialized with their own configs
+// before they are chained
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return fmt.Errorf("fallback provider is initialized through its entries")
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	return p.do(ctx, func(provider llminterface.Provider) (*llminterface.CompletionResponse, bool, error) {
+		response, err := provider.Complete(ctx, req)
+		return response, true, err
+	})
+}
+
+// CompleteStream implements llminterface.StreamingProvider. The chain only falls
+// through while no delta has reached the handler, so the handler never sees the
+// answers of two entries mixed.
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	return p.do(ctx, func(provider llminterface.Provider) (*llminterface.CompletionResponse, bool, error) {
+		delivered := false
+		response, err := llminterface.CompleteStream(ctx, provider, req, func(delta string) error {
+			delivered = true
+			return handler(delta)
+		})
+		return response, !delivered, err
+	})
+}
+
+// do calls the entries in turn. The call reports whether the next entry may be
+// tried after a failure.
+func (p *Provider) do(ctx context.Context, call func(llminterface.Provider) (*llminterface.CompletionResponse, bool, error)) (*llminterface.CompletionResponse, error) {
+	if len(p.entries) == 0 {
+		return nil, fmt.Errorf("fallback chain has no entries")
+	}
+
+	var lastErr error
+	for i, entry := range p.entries {
+		if i > 0 && p.options.OnFallback != nil {
+			p.options.OnFallback(ctx, p.entries[i-1], entry, lastErr)
+		}
+
+		response, next, err := call(entry.Provider)
+		if err == nil {
+			if p.options.OnAnswer != nil {
+				p.options.OnAnswer(ctx, entry, response)
+			}
+			return response, nil
+		}
+		if !next || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// SupportsStructuredOutput implements llminterface.StructuredOutputProvider. The
+// response format is only relied on when every entry enforces it.
+func (p *Provider) SupportsStructuredOutput() bool {
+	for _, entry := range p.entries {
+		if !llminterface.SupportsStructuredOutput(entry.Provider) {
+			return false
+		}
+	}
+	return len(p.entries) > 0
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	var errs []error
+	for _, entry := range p.entries {
+		if err := entry.Provider.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Clone returns a chain of clones of the entries, which need to be initialized
+// before the chain is used
+func (p *Provider) Clone() llminterface.Provider {
+	entries := make([]Entry, len(p.entries))
+	for i, entry := range p.entries {
+		entries[i] = Entry{Name: entry.Name, Provider: entry.Provider.Clone()}
+	}
+	return New(entries, p.options)
+}
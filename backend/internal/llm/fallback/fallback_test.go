@@ -0,0 +1,122 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/fake"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider fails every call with err
+type failingProvider struct {
+	fake.Provider
+	err   error
+	calls int
+}
+
+func (p *failingProvider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	p.calls++
+	return nil, p.err
+}
+
+func (p *failingProvider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	p.calls++
+	if err := handler("partial"); err != nil {
+		return nil, err
+	}
+	return nil, p.err
+}
+
+func answering(t *testing.T, response string) *fake.Provider {
+	t.Helper()
+	provider := &fake.Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), &fake.Config{
+		Name:  "fake",
+		Model: "fake-model",
+		Rules: []fake.Rule{{Pattern: ".", Response: response}},
+	}))
+	return provider
+}
+
+func request() llminterface.CompletionRequest {
+	return llminterface.CompletionRequest{
+		Messages: []llminterface.Message{{Role: "user", Content: "How many users?"}},
+	}
+}
+
+func TestComplete(t *testing.T) {
+	ctx := context.Background()
+	outage := &llminterface.Error{Provider: "openai", Code: "api_error", StatusCode: 503, Retryable: true}
+
+	t.Run("falls through to the next entry", func(t *testing.T) {
+		primary := &failingProvider{err: outage}
+		var fallbacks []string
+		var answeredBy string
+		chain := New([]Entry{
+			{Name: "openai/primary", Provider: primary},
+			{Name: "anthropic/secondary", Provider: answering(t, "secondary")},
+		}, Options{
+			OnFallback: func(ctx context.Context, from, to Entry, err error) {
+				fallbacks = append(fallbacks, from.Name+" -> "+to.Name+": "+err.Error())
+			},
+			OnAnswer: func(ctx context.Context, entry Entry, response *llminterface.CompletionResponse) {
+				answeredBy = entry.Name
+			},
+		})
+
+		resp, err := chain.Complete(ctx, request())
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", resp.Content)
+		assert.Equal(t, "anthropic/secondary", answeredBy)
+		assert.Equal(t, []string{"openai/primary -> anthropic/secondary: " + outage.Message}, fallbacks)
+
+		// The next call starts with the primary again
+		_, err = chain.Complete(ctx, request())
+		require.NoError(t, err)
+		assert.Equal(t, 2, primary.calls)
+	})
+
+	t.Run("returns the error of the last entry", func(t *testing.T) {
+		last := errors.New("invalid config")
+		chain := New([]Entry{
+			{Name: "a", Provider: &failingProvider{err: outage}},
+			{Name: "b", Provider: &failingProvider{err: last}},
+		}, Options{})
+
+		_, err := chain.Complete(ctx, request())
+		assert.Equal(t, last, err)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		secondary := &failingProvider{err: outage}
+		chain := New([]Entry{
+			{Name: "a", Provider: &failingProvider{err: context.Canceled}},
+			{Name: "b", Provider: secondary},
+		}, Options{})
+
+		_, err := chain.Complete(ctx, request())
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, secondary.calls)
+	})
+
+	t.Run("streams do not fall through after a delta", func(t *testing.T) {
+		chain := New([]Entry{
+			{Name: "a", Provider: &failingProvider{err: outage}},
+			{Name: "b", Provider: answering(t, "secondary")},
+		}, Options{})
+
+		var deltas []string
+		_, err := chain.CompleteStream(ctx, request(), func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		assert.Equal(t, outage, err)
+		assert.Equal(t, []string{"partial"}, deltas)
+	})
+}
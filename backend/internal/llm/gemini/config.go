@@ -0,0 +1,47 @@
+package gemini
+
+import "fmt"
+
+// DefaultBaseURL is the Gemini API endpoint used when no base URL is configured
+const DefaultBaseURL = "https://generativelanguage.googleapis.com"
+
+// Config implements llminterface.Config for Google Gemini
+type Config struct {
+	Name            string
+	APIKey          string
+	Model           string  // e.g., "gemini-1.5-pro"
+	Temperature     float64 // Default temperature if not specified in request
+	MaxOutputTokens int     // Default max output tokens if not specified in request
+	BaseURL         string  // Defaults to DefaultBaseURL
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.APIKey == "" {
+		return fmt.Errorf("API key is required")
+	}
+	if c.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if c.MaxOutputTokens < 0 {
+		return fmt.Errorf("max output tokens must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) Type() string {
+	return "gemini"
+}
+
+// NewConfig creates a new Gemini configuration
+func NewConfig(name, apiKey, model string, temperature float64, maxOutputTokens int) *Config {
+	return &Config{
+		Name:            name,
+		APIKey:          apiKey,
+		Model:           model,
+		Temperature:     temperature,
+		MaxOutputTokens: maxOutputTokens,
+	}
+}
@@ -0,0 +1,384 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Provider implements llminterface.Provider for Google Gemini over the
+// generateContent REST API
+type Provider struct {
+	config *Config
+	client *http.Client
+}
+
+func NewProvider() llminterface.Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	cfg, ok := config.(*Config)
+	if !ok {
+		return fmt.Errorf("invalid config type for Gemini provider")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	p.config = cfg
+	p.client = &http.Client{}
+	return nil
+}
+
+// generateContentRequest is the body of POST /v1beta/models/{model}:generateContent
+type generateContentRequest struct {
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Contents          []content         `json:"contents"`
+	Tools             []tool            `json:"tools,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+// part is a text, functionCall or functionResponse part
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type functionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type functionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
+}
+
+type functionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+}
+
+type generateContentResponse struct {
+	ResponseID   string `json:"responseId"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type       string `json:"@type"`
+			RetryDelay string `json:"retryDelay"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	body, err := p.generateContentRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	model := req.Model
+	if model == "" {
+		model = p.config.Model
+	}
+
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent", strings.TrimRight(baseURL, "/"), url.PathEscape(model))
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.config.APIKey)
+
+	// Make the API call
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "gemini",
+			Code:      "request_failed",
+			Message:   err.Error(),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "gemini",
+			Code:      "request_failed",
+			Message:   fmt.Sprintf("failed to read response: %v", err),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, apiError(httpResp, respBody)
+	}
+
+	var completion generateContentResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, &llminterface.Error{
+			Provider: "gemini",
+			Code:     "invalid_response",
+			Message:  fmt.Sprintf("failed to decode response: %v", err),
+		}
+	}
+
+	if completion.PromptFeedback.BlockReason != "" {
+		return nil, &llminterface.Error{
+			Provider: "gemini",
+			Code:     "blocked",
+			Message:  fmt.Sprintf("prompt blocked: %s", completion.PromptFeedback.BlockReason),
+		}
+	}
+
+	if len(completion.Candidates) == 0 {
+		return nil, &llminterface.Error{
+			Provider: "gemini",
+			Code:     "no_completion",
+			Message:  "no completion choices returned",
+		}
+	}
+
+	// Convert the response to our format
+	candidate := completion.Candidates[0]
+	var text strings.Builder
+	response := &llminterface.CompletionResponse{
+		Metadata: map[string]interface{}{
+			"model":         completion.ModelVersion,
+			"id":            completion.ResponseID,
+			"finish_reason": candidate.FinishReason,
+		},
+	}
+	for i, answer := range candidate.Content.Parts {
+		switch {
+		case answer.FunctionCall != nil:
+			args := string(answer.FunctionCall.Args)
+			if args == "" {
+				args = "{}"
+			}
+			// Older models do not return call IDs, so the IDs are made up from the
+			// position of the call
+			id := answer.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d_%s", i, answer.FunctionCall.Name)
+			}
+			response.ToolCalls = append(response.ToolCalls, llminterface.ToolCall{
+				ID:        id,
+				Name:      answer.FunctionCall.Name,
+				Arguments: args,
+			})
+		default:
+			text.WriteString(answer.Text)
+		}
+	}
+	response.Content = text.String()
+
+	if response.Content == "" && len(response.ToolCalls) == 0 {
+		return nil, &llminterface.Error{
+			Provider: "gemini",
+			Code:     "no_completion",
+			Message:  fmt.Sprintf("no content returned (finish reason %s)", candidate.FinishReason),
+		}
+	}
+
+	response.Usage.PromptTokens = completion.UsageMetadata.PromptTokenCount
+	response.Usage.CompletionTokens = completion.UsageMetadata.CandidatesTokenCount
+	response.Usage.TotalTokens = completion.UsageMetadata.TotalTokenCount
+
+	return response, nil
+}
+
+// generateContentRequest converts a completion request to a generateContent request.
+// Gemini has no system role in the conversation, so system messages become the
+// system instruction. Assistant messages are sent with the "model" role, and tool
+// results as functionResponse parts of a user turn, named after the call they answer.
+func (p *Provider) generateContentRequest(req llminterface.CompletionRequest) (*generateContentRequest, error) {
+	body := &generateContentRequest{}
+
+	config := &generationConfig{
+		MaxOutputTokens: req.MaxTokens,
+	}
+	if config.MaxOutputTokens == 0 {
+		config.MaxOutputTokens = p.config.MaxOutputTokens
+	}
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = p.config.Temperature
+	}
+	if temperature != 0 {
+		config.Temperature = &temperature
+	}
+	if config.Temperature != nil || config.MaxOutputTokens != 0 {
+		body.GenerationConfig = config
+	}
+
+	var system []part
+	callNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, part{Text: msg.Content})
+		case "user":
+			body.Contents = appendParts(body.Contents, "user", part{Text: msg.Content})
+		case "assistant":
+			var parts []part
+			if msg.Content != "" {
+				parts = append(parts, part{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Name
+				args := json.RawMessage(call.Arguments)
+				if len(strings.TrimSpace(call.Arguments)) == 0 {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, part{FunctionCall: &functionCall{Name: call.Name, Args: args}})
+			}
+			body.Contents = appendParts(body.Contents, "model", parts...)
+		case "tool":
+			name, ok := callNames[msg.ToolCallID]
+			if !ok {
+				return nil, fmt.Errorf("tool result for unknown tool call: %s", msg.ToolCallID)
+			}
+			body.Contents = appendParts(body.Contents, "user", part{FunctionResponse: &functionResponse{
+				Name:     name,
+				Response: map[string]interface{}{"content": msg.Content},
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+	if len(system) > 0 {
+		body.SystemInstruction = &content{Parts: system}
+	}
+
+	if len(req.Tools) > 0 {
+		declarations := make([]functionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			declarations = append(declarations, functionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			})
+		}
+		body.Tools = []tool{{FunctionDeclarations: declarations}}
+	}
+
+	return body, nil
+}
+
+// appendParts adds parts to the conversation, merging them into the last turn when
+// it has the same role
+func appendParts(contents []content, role string, parts ...part) []content {
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, content{Role: role, Parts: parts})
+}
+
+// apiError converts an error response of the Gemini API. Rate limits and server
+// errors are retryable.
+func apiError(resp *http.Response, body []byte) *llminterface.Error {
+	statusCode := resp.StatusCode
+	retryAfter := llminterface.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+
+	var errResp errorResponse
+	code := "api_error"
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		if errResp.Error.Status != "" {
+			code = errResp.Error.Status
+		}
+		message = errResp.Error.Message
+
+		// Quota errors say when to retry in a RetryInfo detail rather than a header
+		for _, detail := range errResp.Error.Details {
+			if !strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+				continue
+			}
+			if delay, err := time.ParseDuration(detail.RetryDelay); err == nil && delay > 0 {
+				retryAfter = delay
+			}
+		}
+	}
+
+	return &llminterface.Error{
+		Provider:   "gemini",
+		Code:       code,
+		Message:    fmt.Sprintf("gemini API returned %d: %s", statusCode, message),
+		Retryable:  statusCode == http.StatusTooManyRequests || statusCode >= 500,
+		StatusCode: statusCode,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *Provider) Clone() llminterface.Provider {
+	return NewProvider()
+}
@@ -0,0 +1,207 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/internal/llmtest"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProvider points a provider at a stand-in generateContent endpoint
+// answering with the given status and body
+func newTestProvider(t *testing.T, status int, response string) (*Provider, *llmtest.Server) {
+	t.Helper()
+
+	server := llmtest.NewServer(t, llmtest.Response{Status: status, Body: response})
+	config := NewConfig("test", "test-key", "gemini-1.5-pro", 0.2, 512)
+	config.BaseURL = server.URL
+
+	provider := &Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	return provider, server
+}
+
+func TestProviderComplete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("text response", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{
+			"responseId": "resp_1",
+			"modelVersion": "gemini-1.5-pro-002",
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "SELECT "}, {"text": "1"}]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 3, "totalTokenCount": 15}
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "system", Content: "You are a SQL expert."},
+				{Role: "user", Content: "Count the users"},
+				{Role: "assistant", Content: "SELECT count(*) FROM users"},
+				{Role: "user", Content: "Only active ones"},
+			},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.Equal(t, "/v1beta/models/gemini-1.5-pro:generateContent", server.Request().URL.Path)
+		assert.Equal(t, "test-key", server.Request().Header.Get("x-goog-api-key"))
+
+		assert.Equal(t, map[string]interface{}{
+			"parts": []interface{}{map[string]interface{}{"text": "You are a SQL expert."}},
+		}, body["systemInstruction"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "Count the users"}}},
+			map[string]interface{}{"role": "model", "parts": []interface{}{map[string]interface{}{"text": "SELECT count(*) FROM users"}}},
+			map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "Only active ones"}}},
+		}, body["contents"])
+		assert.Equal(t, map[string]interface{}{
+			"temperature":     0.2,
+			"maxOutputTokens": float64(512),
+		}, body["generationConfig"])
+
+		assert.Equal(t, "SELECT 1", resp.Content)
+		assert.Equal(t, 12, resp.Usage.PromptTokens)
+		assert.Equal(t, 3, resp.Usage.CompletionTokens)
+		assert.Equal(t, 15, resp.Usage.TotalTokens)
+		assert.Equal(t, "resp_1", resp.Metadata["id"])
+		assert.Equal(t, "STOP", resp.Metadata["finish_reason"])
+	})
+
+	t.Run("function calls", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{
+			"candidates": [{
+				"content": {"role": "model", "parts": [
+					{"functionCall": {"name": "describe_table", "args": {"table": "users"}}}
+				]},
+				"finishReason": "STOP"
+			}]
+		}`)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "user", Content: "Count the users"},
+				{Role: "assistant", ToolCalls: []llminterface.ToolCall{
+					{ID: "call_0_list_tables", Name: "list_tables", Arguments: "{}"},
+				}},
+				{Role: "tool", ToolCallID: "call_0_list_tables", Content: "users"},
+			},
+			Tools: []llminterface.Tool{{
+				Name:        "describe_table",
+				Description: "Describe a table",
+				Parameters:  map[string]interface{}{"type": "object"},
+			}},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, llminterface.ToolCall{
+			ID:        "call_0_describe_table",
+			Name:      "describe_table",
+			Arguments: `{"table": "users"}`,
+		}, resp.ToolCalls[0])
+
+		contents := body["contents"].([]interface{})
+		require.Len(t, contents, 3)
+		assert.Equal(t, map[string]interface{}{
+			"role": "model",
+			"parts": []interface{}{map[string]interface{}{
+				"functionCall": map[string]interface{}{"name": "list_tables", "args": map[string]interface{}{}},
+			}},
+		}, contents[1])
+		assert.Equal(t, map[string]interface{}{
+			"role": "user",
+			"parts": []interface{}{map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     "list_tables",
+					"response": map[string]interface{}{"content": "users"},
+				},
+			}},
+		}, contents[2])
+
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"functionDeclarations": []interface{}{map[string]interface{}{
+				"name":        "describe_table",
+				"description": "Describe a table",
+				"parameters":  map[string]interface{}{"type": "object"},
+			}},
+		}}, body["tools"])
+	})
+
+	t.Run("unknown tool call", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusOK, `{}`)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "user", Content: "Count the users"},
+				{Role: "tool", ToolCallID: "missing", Content: "users"},
+			},
+		})
+		assert.ErrorContains(t, err, "unknown tool call")
+	})
+
+	t.Run("blocked prompt", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusOK, `{"promptFeedback": {"blockReason": "SAFETY"}}`)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+		})
+
+		var llmErr *llminterface.Error
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, "blocked", llmErr.Code)
+		assert.False(t, llmErr.Retryable)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			status    int
+			errStatus string
+			retryable bool
+		}{
+			{"rate limit", http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", true},
+			{"unavailable", http.StatusServiceUnavailable, "UNAVAILABLE", true},
+			{"invalid argument", http.StatusBadRequest, "INVALID_ARGUMENT", false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				provider, _ := newTestProvider(t, tt.status, `{"error": {"code": 1, "message": "failed", "status": "`+tt.errStatus+`"}}`)
+
+				_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+					Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+				})
+
+				var llmErr *llminterface.Error
+				require.True(t, errors.As(err, &llmErr))
+				assert.Equal(t, "gemini", llmErr.Provider)
+				assert.Equal(t, tt.errStatus, llmErr.Code)
+				assert.Equal(t, tt.retryable, llmErr.Retryable)
+				assert.Equal(t, tt.status, llmErr.StatusCode)
+			})
+		}
+	})
+
+	t.Run("retry delay", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusTooManyRequests, `{"error": {"code": 429, "message": "quota exceeded", "status": "RESOURCE_EXHAUSTED",
+			"details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "17s"}]}}`)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+		})
+
+		var llmErr *llminterface.Error
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, 17*time.Second, llmErr.RetryAfter)
+	})
+}
@@ -0,0 +1,78 @@
+package httpjson
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+)
+
+// Config implements llminterface.Config for a model gateway that takes and returns
+// JSON over HTTP
+type Config struct {
+	Name      string
+	APIKey    string // Available to the templates as {{.APIKey}}
+	Model     string
+	MaxTokens int // Default max tokens if not specified in request
+
+	URL    string
+	Method string // Defaults to POST
+	// Headers are sent with every request; their values are templates like the body
+	Headers map[string]string
+	// RequestTemplate is a text/template that renders the JSON request body from
+	// the completion request
+	RequestTemplate string
+
+	// Selectors pick values out of the JSON response, either as JSONPath-style
+	// "$.choices[0].text" or as gjson paths
+	ContentPath          string
+	PromptTokensPath     string
+	CompletionTokensPath string
+	TotalTokensPath      string
+	// ErrorPath selects the error message of a failed request
+	ErrorPath string
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if c.URL == "" {
+		return fmt.Errorf("URL is required")
+	}
+	if u, err := url.Parse(c.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid URL: %s", c.URL)
+	}
+	switch c.Method {
+	case "", http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("unsupported method: %s", c.Method)
+	}
+	if c.RequestTemplate == "" {
+		return fmt.Errorf("request template is required")
+	}
+	if _, err := parseTemplate("request", c.RequestTemplate); err != nil {
+		return fmt.Errorf("invalid request template: %w", err)
+	}
+	for name, value := range c.Headers {
+		if _, err := parseTemplate(name, value); err != nil {
+			return fmt.Errorf("invalid template for header %s: %w", name, err)
+		}
+	}
+	if c.ContentPath == "" {
+		return fmt.Errorf("content path is required")
+	}
+	return nil
+}
+
+func (c *Config) Type() string {
+	return "http"
+}
+
+// parseTemplate parses a request body or header template with the template functions
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
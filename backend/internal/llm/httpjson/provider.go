@@ -0,0 +1,256 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/tidwall/gjson"
+)
+
+// Provider implements llminterface.Provider for a model gateway described entirely
+// by its Config: the request body is rendered from a template and the completion is
+// read from the response with selectors
+type Provider struct {
+	config       *Config
+	bodyTemplate *template.Template
+	headers      map[string]*template.Template
+	client       *http.Client
+}
+
+func NewProvider() llminterface.Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	cfg, ok := config.(*Config)
+	if !ok {
+		return fmt.Errorf("invalid config type for HTTP provider")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Validate has parsed the templates already
+	p.bodyTemplate, _ = parseTemplate("request", cfg.RequestTemplate)
+	p.headers = make(map[string]*template.Template, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		p.headers[name], _ = parseTemplate(name, value)
+	}
+
+	p.config = cfg
+	p.client = &http.Client{}
+	return nil
+}
+
+// templateFuncs are available in the request and header templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value, so strings are quoted and escaped for the request body
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// requestData is what the templates are executed with
+type requestData struct {
+	Model       string
+	Messages    []llminterface.Message
+	MaxTokens   int
+	Temperature float64
+	APIKey      string
+	// System holds the system messages, for gateways that take them separately
+	System string
+	// Prompt is the last user message, for gateways that take a single prompt
+	Prompt string
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+	if len(req.Tools) > 0 {
+		return nil, fmt.Errorf("HTTP provider does not support tool calls")
+	}
+
+	data := p.requestData(req)
+
+	var body bytes.Buffer
+	if err := p.bodyTemplate.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render request body: %w", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, fmt.Errorf("request template did not render valid JSON")
+	}
+
+	method := p.config.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.config.URL, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, tmpl := range p.headers {
+		var value strings.Builder
+		if err := tmpl.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("failed to render header %s: %w", name, err)
+		}
+		httpReq.Header.Set(name, value.String())
+	}
+
+	// Make the API call
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "http",
+			Code:      "request_failed",
+			Message:   err.Error(),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, &llminterface.Error{
+			Provider:  "http",
+			Code:      "request_failed",
+			Message:   fmt.Sprintf("failed to read response: %v", err),
+			Retryable: ctx.Err() == nil,
+		}
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return nil, p.apiError(httpResp, respBody)
+	}
+
+	if !gjson.ValidBytes(respBody) {
+		return nil, &llminterface.Error{
+			Provider: "http",
+			Code:     "invalid_response",
+			Message:  "response is not valid JSON",
+		}
+	}
+
+	content := selectPath(respBody, p.config.ContentPath)
+	if !content.Exists() || content.String() == "" {
+		return nil, &llminterface.Error{
+			Provider: "http",
+			Code:     "no_completion",
+			Message:  fmt.Sprintf("no content at %s", p.config.ContentPath),
+		}
+	}
+
+	// Convert the response to our format
+	response := &llminterface.CompletionResponse{
+		Content: content.String(),
+		Metadata: map[string]interface{}{
+			"model": data.Model,
+		},
+	}
+	response.Usage.PromptTokens = selectInt(respBody, p.config.PromptTokensPath)
+	response.Usage.CompletionTokens = selectInt(respBody, p.config.CompletionTokensPath)
+	response.Usage.TotalTokens = selectInt(respBody, p.config.TotalTokensPath)
+	if response.Usage.TotalTokens == 0 {
+		response.Usage.TotalTokens = response.Usage.PromptTokens + response.Usage.CompletionTokens
+	}
+
+	return response, nil
+}
+
+// requestData fills in the template data, with the model and max tokens of the
+// config as defaults
+func (p *Provider) requestData(req llminterface.CompletionRequest) requestData {
+	data := requestData{
+		Model:       req.Model,
+		Messages:    req.Messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		APIKey:      p.config.APIKey,
+	}
+	if data.Model == "" {
+		data.Model = p.config.Model
+	}
+	if data.MaxTokens == 0 {
+		data.MaxTokens = p.config.MaxTokens
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "user":
+			data.Prompt = msg.Content
+		}
+	}
+	data.System = strings.Join(system, "\n\n")
+	return data
+}
+
+// apiError converts a failed response, taking the message from ErrorPath when it
+// is configured. Rate limits and server errors are retryable.
+func (p *Provider) apiError(resp *http.Response, body []byte) *llminterface.Error {
+	statusCode := resp.StatusCode
+	message := strings.TrimSpace(string(body))
+	if p.config.ErrorPath != "" {
+		if selected := selectPath(body, p.config.ErrorPath); selected.Exists() {
+			message = selected.String()
+		}
+	}
+
+	return &llminterface.Error{
+		Provider:   "http",
+		Code:       fmt.Sprintf("http_%d", statusCode),
+		Message:    fmt.Sprintf("gateway returned %d: %s", statusCode, message),
+		Retryable:  statusCode == http.StatusTooManyRequests || statusCode >= 500,
+		StatusCode: statusCode,
+		RetryAfter: llminterface.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+var indexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// gjsonPath converts a JSONPath-style selector like "$.choices[0].text" to the
+// gjson path "choices.0.text". gjson paths pass through unchanged.
+func gjsonPath(selector string) string {
+	path := strings.TrimPrefix(selector, "$")
+	path = indexPattern.ReplaceAllString(path, ".$1")
+	return strings.TrimPrefix(path, ".")
+}
+
+func selectPath(body []byte, selector string) gjson.Result {
+	return gjson.GetBytes(body, gjsonPath(selector))
+}
+
+// selectInt reads a token count, which is 0 when the selector is not configured or
+// matches nothing
+func selectInt(body []byte, selector string) int {
+	if selector == "" {
+		return 0
+	}
+	return int(selectPath(body, selector).Int())
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *Provider) Clone() llminterface.Provider {
+	return NewProvider()
+}
@@ -0,0 +1,192 @@
+package httpjson
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/internal/llmtest"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `{
+	"model": {{json .Model}},
+	"system": {{json .System}},
+	"messages": [{{range $i, $m := .Messages}}{{if $i}},{{end}}{"role": {{json $m.Role}}, "text": {{json $m.Content}}}{{end}}],
+	"max_tokens": {{.MaxTokens}}
+}`
+
+// newTestProvider points a provider at a stand-in gateway answering with the
+// given status and body
+func newTestProvider(t *testing.T, status int, response string, configure func(*Config)) (*Provider, *llmtest.Server) {
+	t.Helper()
+
+	server := llmtest.NewServer(t, llmtest.Response{Status: status, Body: response})
+	config := &Config{
+		Name:                 "gateway",
+		APIKey:               "secret",
+		Model:                "house-model",
+		MaxTokens:            512,
+		URL:                  server.URL + "/generate",
+		Headers:              map[string]string{"Authorization": "Bearer {{.APIKey}}"},
+		RequestTemplate:      testTemplate,
+		ContentPath:          "$.result.choices[0].text",
+		PromptTokensPath:     "$.usage.in",
+		CompletionTokensPath: "usage.out",
+		ErrorPath:            "$.error.detail",
+	}
+	if configure != nil {
+		configure(config)
+	}
+
+	provider := &Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	return provider, server
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Name:            "gateway",
+			Model:           "house-model",
+			URL:             "http://localhost:9000/generate",
+			RequestTemplate: `{"prompt": {{json .Prompt}}}`,
+			ContentPath:     "$.text",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"missing URL", func(c *Config) { c.URL = "" }, "URL is required"},
+		{"relative URL", func(c *Config) { c.URL = "/generate" }, "invalid URL"},
+		{"unsupported method", func(c *Config) { c.Method = http.MethodGet }, "unsupported method"},
+		{"broken template", func(c *Config) { c.RequestTemplate = `{"prompt": {{.Prompt}` }, "invalid request template"},
+		{"broken header", func(c *Config) { c.Headers = map[string]string{"X-Key": "{{.APIKey"} }, "invalid template for header X-Key"},
+		{"missing content path", func(c *Config) { c.ContentPath = "" }, "content path is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderComplete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("templated request and selected response", func(t *testing.T) {
+		provider, server := newTestProvider(t, http.StatusOK, `{
+			"result": {"choices": [{"text": "SELECT 1"}]},
+			"usage": {"in": 20, "out": 4}
+		}`, nil)
+
+		resp, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{
+				{Role: "system", Content: "You are a SQL expert."},
+				{Role: "user", Content: `Count the "active" users`},
+			},
+		})
+		require.NoError(t, err)
+		body := server.Body(t)
+
+		assert.Equal(t, http.MethodPost, server.Request().Method)
+		assert.Equal(t, "/generate", server.Request().URL.Path)
+		assert.Equal(t, "Bearer secret", server.Request().Header.Get("Authorization"))
+		assert.Equal(t, "application/json", server.Request().Header.Get("Content-Type"))
+
+		assert.Equal(t, "house-model", body["model"])
+		assert.Equal(t, "You are a SQL expert.", body["system"])
+		assert.Equal(t, float64(512), body["max_tokens"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"role": "system", "text": "You are a SQL expert."},
+			map[string]interface{}{"role": "user", "text": `Count the "active" users`},
+		}, body["messages"])
+
+		assert.Equal(t, "SELECT 1", resp.Content)
+		assert.Equal(t, 20, resp.Usage.PromptTokens)
+		assert.Equal(t, 4, resp.Usage.CompletionTokens)
+		assert.Equal(t, 24, resp.Usage.TotalTokens)
+	})
+
+	t.Run("missing content", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusOK, `{"result": {"choices": []}}`, nil)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+		})
+
+		var llmErr *llminterface.Error
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, "no_completion", llmErr.Code)
+	})
+
+	t.Run("invalid rendered body", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusOK, `{}`, func(c *Config) {
+			c.RequestTemplate = `{"prompt": {{.Prompt}}}`
+		})
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+		})
+		assert.ErrorContains(t, err, "did not render valid JSON")
+	})
+
+	t.Run("tool calls are not supported", func(t *testing.T) {
+		provider, _ := newTestProvider(t, http.StatusOK, `{}`, nil)
+
+		_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+			Tools:    []llminterface.Tool{{Name: "list_tables"}},
+		})
+		assert.ErrorContains(t, err, "does not support tool calls")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			status    int
+			retryable bool
+		}{
+			{"rate limit", http.StatusTooManyRequests, true},
+			{"bad gateway", http.StatusBadGateway, true},
+			{"bad request", http.StatusBadRequest, false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				provider, _ := newTestProvider(t, tt.status, `{"error": {"detail": "quota exceeded"}}`, nil)
+
+				_, err := provider.Complete(ctx, llminterface.CompletionRequest{
+					Messages: []llminterface.Message{{Role: "user", Content: "hi"}},
+				})
+
+				var llmErr *llminterface.Error
+				require.True(t, errors.As(err, &llmErr))
+				assert.Equal(t, "http", llmErr.Provider)
+				assert.Equal(t, tt.retryable, llmErr.Retryable)
+				assert.Contains(t, llmErr.Message, "quota exceeded")
+			})
+		}
+	})
+}
+
+func TestGJSONPath(t *testing.T) {
+	assert.Equal(t, "choices.0.message.content", gjsonPath("$.choices[0].message.content"))
+	assert.Equal(t, "output.1.2", gjsonPath("$.output[1][2]"))
+	assert.Equal(t, "choices.0.text", gjsonPath("choices.0.text"))
+}
@@ -0,0 +1,86 @@
+// Package llmtest provides the stand-in HTTP endpoints the provider tests run against
+package llmtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Response is what a Server answers every request with
+type Response struct {
+	// Status defaults to 200 OK
+	Status int
+	// Header is added to the default Content-Type: application/json
+	Header http.Header
+	Body   string
+}
+
+// Server is an httptest server that answers with a fixed response and records
+// the last request it received
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	request *http.Request
+	body    []byte
+}
+
+// NewServer starts a Server that is closed when the test ends
+func NewServer(t *testing.T, response Response) *Server {
+	t.Helper()
+
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		s.mu.Lock()
+		s.request = r
+		s.body = body
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		for key, values := range response.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response.Body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Request returns the last request received. Its body has already been read;
+// see RawBody and Body.
+func (s *Server) Request() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.request
+}
+
+// RawBody returns the body of the last request
+func (s *Server) RawBody() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body
+}
+
+// Body decodes the JSON body of the last request
+func (s *Server) Body(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(s.RawBody(), &body))
+	return body
+}
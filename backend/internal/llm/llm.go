@@ -10,9 +10,14 @@ import (
 type (
 	Config             = llminterface.Config
 	Message            = llminterface.Message
+	Tool               = llminterface.Tool
+	ToolCall           = llminterface.ToolCall
 	CompletionRequest  = llminterface.CompletionRequest
 	CompletionResponse = llminterface.CompletionResponse
 	Provider           = llminterface.Provider
+	StreamingProvider  = llminterface.StreamingProvider
+	ResponseFormat     = llminterface.ResponseFormat
+	StreamHandler      = llminterface.StreamHandler
 	Error              = llminterface.Error
 )
 
@@ -24,6 +29,15 @@ var (
 	SetStorage    = llmregistry.SetStorage
 )
 
+// Re-export the provider helpers
+var (
+	// CompleteStream streams a completion, falling back to Complete for providers
+	// that cannot stream
+	CompleteStream = llminterface.CompleteStream
+	// SupportsStructuredOutput reports whether a provider enforces ResponseFormat
+	SupportsStructuredOutput = llminterface.SupportsStructuredOutput
+)
+
 // Initialize function to be called at startup
 func Initialize(storage storage.Storage) {
 	SetStorage(storage)
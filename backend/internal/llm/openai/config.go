@@ -8,15 +8,30 @@ type Config struct {
 	APIKey    string
 	Model     string // e.g., "gpt-4", "gpt-3.5-turbo"
 	MaxTokens int    // Default max tokens if not specified in request
+
+	// BaseURL points the client at an OpenAI-compatible endpoint instead of the
+	// OpenAI API
+	BaseURL string
+	// Headers are added to every request, e.g., for a gateway in front of the model
+	Headers map[string]string
+	// APIVersion selects Azure OpenAI, with BaseURL as the deployment URL
+	APIVersion string
+	// StructuredOutput sends response formats as strict JSON schemas. The OpenAI API
+	// supports them, but many compatible servers reject or ignore them.
+	StructuredOutput bool
 }
 
 func (c *Config) Validate() error {
 	if c.Name == "" {
 		return fmt.Errorf("name is required")
 	}
-	if c.APIKey == "" {
+	// Self-hosted servers often run without authentication
+	if c.APIKey == "" && c.BaseURL == "" {
 		return fmt.Errorf("API key is required")
 	}
+	if c.APIVersion != "" && c.BaseURL == "" {
+		return fmt.Errorf("base URL is required with an API version")
+	}
 	if c.Model == "" {
 		return fmt.Errorf("model is required")
 	}
@@ -30,9 +45,10 @@ func (c *Config) Type() string {
 // NewConfig creates a new OpenAI configuration
 func NewConfig(name, apiKey, model string, maxTokens int) *Config {
 	return &Config{
-		Name:      name,
-		APIKey:    apiKey,
-		Model:     model,
-		MaxTokens: maxTokens,
+		Name:             name,
+		APIKey:           apiKey,
+		Model:            model,
+		MaxTokens:        maxTokens,
+		StructuredOutput: true,
 	}
 }
@@ -2,7 +2,13 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -30,15 +36,138 @@ func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) e
 	}
 
 	p.config = cfg
-	p.client = openai.NewClient(option.WithAPIKey(cfg.APIKey))
+	p.client = openai.NewClient(clientOptions(cfg)...)
 	return nil
 }
 
+// clientOptions configures the client for the OpenAI API or, with a base URL, for
+// any endpoint that speaks the OpenAI chat API
+func clientOptions(cfg *Config) []option.RequestOption {
+	// Retries are left to the retry middleware, which knows about the circuit
+	// breaker and reports attempts on the ask
+	opts := []option.RequestOption{option.WithMaxRetries(0)}
+	if cfg.BaseURL != "" {
+		// Paths are resolved relative to the base URL, so it has to end in a slash
+		opts = append(opts, option.WithBaseURL(strings.TrimRight(cfg.BaseURL, "/")+"/"))
+	}
+
+	switch {
+	case cfg.APIVersion != "":
+		// Azure OpenAI takes the key in its own header and the version as a query
+		// parameter of the deployment URL
+		opts = append(opts, option.WithQuery("api-version", cfg.APIVersion))
+		if cfg.APIKey != "" {
+			opts = append(opts, option.WithHeader("api-key", cfg.APIKey))
+		}
+	case cfg.APIKey != "":
+		opts = append(opts, option.WithAPIKey(cfg.APIKey))
+	}
+
+	for key, value := range cfg.Headers {
+		opts = append(opts, option.WithHeader(key, value))
+	}
+	return opts
+}
+
 func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("provider not initialized")
 	}
 
+	params, err := p.completionParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the API call
+	completion, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, &llminterface.Error{
+			Provider: "openai",
+			Code:     "no_completion",
+			Message:  "no completion choices returned",
+		}
+	}
+
+	// Convert the response to our format
+	message := completion.Choices[0].Message
+	response := completionResponse(message.Content, completion.Model, completion.ID, completion.Usage)
+	for _, call := range message.ToolCalls {
+		response.ToolCalls = append(response.ToolCalls, llminterface.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
+	return response, nil
+}
+
+// CompleteStream implements llminterface.StreamingProvider
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	params, err := p.completionParams(req)
+	if err != nil {
+		return nil, err
+	}
+	// Ask for a final chunk carrying the token usage of the whole completion
+	params.StreamOptions = openai.F(openai.ChatCompletionStreamOptionsParam{
+		IncludeUsage: openai.F(true),
+	})
+
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	var (
+		content   strings.Builder
+		model, id string
+		usage     openai.CompletionUsage
+		received  bool
+	)
+	for stream.Next() {
+		chunk := stream.Current()
+		model, id = chunk.Model, chunk.ID
+		if chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		received = true
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		content.WriteString(delta)
+		if err := handler(delta); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, apiError(err)
+	}
+
+	if !received {
+		return nil, &llminterface.Error{
+			Provider: "openai",
+			Code:     "no_completion",
+			Message:  "no completion choices returned",
+		}
+	}
+
+	return completionResponse(content.String(), model, id, usage), nil
+}
+
+// completionParams converts a completion request to OpenAI's parameters
+func (p *Provider) completionParams(req llminterface.CompletionRequest) (openai.ChatCompletionNewParams, error) {
 	// Convert our messages to OpenAI's format
 	messages := make([]openai.ChatCompletionMessageParamUnion, len(req.Messages))
 	for i, msg := range req.Messages {
@@ -46,11 +175,17 @@ func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequ
 		case "user":
 			messages[i] = openai.UserMessage(msg.Content)
 		case "assistant":
-			messages[i] = openai.AssistantMessage(msg.Content)
+			if len(msg.ToolCalls) > 0 {
+				messages[i] = assistantToolCallMessage(msg.ToolCalls)
+			} else {
+				messages[i] = openai.AssistantMessage(msg.Content)
+			}
 		case "system":
 			messages[i] = openai.SystemMessage(msg.Content)
+		case "tool":
+			messages[i] = openai.ToolMessage(msg.ToolCallID, msg.Content)
 		default:
-			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+			return openai.ChatCompletionNewParams{}, fmt.Errorf("unsupported message role: %s", msg.Role)
 		}
 	}
 
@@ -72,38 +207,112 @@ func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequ
 		Temperature: openai.Float(float64(req.Temperature)),
 	}
 
-	// Make the API call
-	completion, err := p.client.Chat.Completions.New(ctx, params)
-	if err != nil {
-		return nil, &llminterface.Error{
-			Provider:  "openai",
-			Code:      "api_error",
-			Message:   err.Error(),
-			Retryable: isRetryableError(err),
+	if len(req.Tools) > 0 {
+		tools := make([]openai.ChatCompletionToolParam, len(req.Tools))
+		for i, tool := range req.Tools {
+			tools[i] = openai.ChatCompletionToolParam{
+				Type: openai.F(openai.ChatCompletionToolTypeFunction),
+				Function: openai.F(openai.FunctionDefinitionParam{
+					Name:        openai.String(tool.Name),
+					Description: openai.String(tool.Description),
+					Parameters:  openai.F(openai.FunctionParameters(tool.Parameters)),
+				}),
+			}
 		}
+		params.Tools = openai.F(tools)
 	}
 
-	if len(completion.Choices) == 0 {
-		return nil, &llminterface.Error{
-			Provider: "openai",
-			Code:     "no_completion",
-			Message:  "no completion choices returned",
+	if req.ResponseFormat != nil && p.config.StructuredOutput {
+		params.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](openai.ResponseFormatJSONSchemaParam{
+			Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:        openai.F(req.ResponseFormat.Name),
+				Description: openai.F(req.ResponseFormat.Description),
+				Schema:      openai.F[interface{}](req.ResponseFormat.Schema),
+				Strict:      openai.Bool(true),
+			}),
+		})
+	}
+
+	return params, nil
+}
+
+// assistantToolCallMessage replays the tool calls of an earlier assistant turn
+func assistantToolCallMessage(calls []llminterface.ToolCall) openai.ChatCompletionMessageParamUnion {
+	toolCalls := make([]openai.ChatCompletionMessageToolCallParam, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = openai.ChatCompletionMessageToolCallParam{
+			ID:   openai.F(call.ID),
+			Type: openai.F(openai.ChatCompletionMessageToolCallTypeFunction),
+			Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      openai.F(call.Name),
+				Arguments: openai.F(call.Arguments),
+			}),
 		}
 	}
 
-	// Convert the response to our format
+	return openai.ChatCompletionAssistantMessageParam{
+		Role:      openai.F(openai.ChatCompletionAssistantMessageParamRoleAssistant),
+		ToolCalls: openai.F(toolCalls),
+	}
+}
+
+func completionResponse(content, model, id string, usage openai.CompletionUsage) *llminterface.CompletionResponse {
 	response := &llminterface.CompletionResponse{
-		Content: completion.Choices[0].Message.Content,
+		Content: content,
 		Metadata: map[string]interface{}{
-			"model": completion.Model,
-			"id":    completion.ID,
+			"model": model,
+			"id":    id,
 		},
 	}
-	response.Usage.PromptTokens = int(completion.Usage.PromptTokens)
-	response.Usage.CompletionTokens = int(completion.Usage.CompletionTokens)
-	response.Usage.TotalTokens = int(completion.Usage.TotalTokens)
+	response.Usage.PromptTokens = int(usage.PromptTokens)
+	response.Usage.CompletionTokens = int(usage.CompletionTokens)
+	response.Usage.TotalTokens = int(usage.TotalTokens)
+	return response
+}
 
-	return response, nil
+func apiError(err error) *llminterface.Error {
+	llmErr := &llminterface.Error{
+		Provider:  "openai",
+		Code:      "api_error",
+		Message:   err.Error(),
+		Retryable: isRetryableError(err),
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		if code := errorCode(apiErr); code != "" {
+			llmErr.Code = code
+		}
+		llmErr.StatusCode = apiErr.StatusCode
+		if apiErr.Response != nil {
+			llmErr.RetryAfter = llminterface.ParseRetryAfter(apiErr.Response.Header.Get("Retry-After"), time.Now())
+		}
+	}
+	return llmErr
+}
+
+// errorCode returns the code of an API error. The API wraps the error object in an
+// "error" field, which the client does not unwrap.
+func errorCode(apiErr *openai.Error) string {
+	if apiErr.Code != "" {
+		return apiErr.Code
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(apiErr.JSON.RawJSON()), &body); err != nil {
+		return ""
+	}
+	return body.Error.Code
+}
+
+// SupportsStructuredOutput implements llminterface.StructuredOutputProvider. The
+// response format is sent as a strict JSON schema when the config enables it.
+func (p *Provider) SupportsStructuredOutput() bool {
+	return p.config != nil && p.config.StructuredOutput
 }
 
 func (p *Provider) Close(ctx context.Context) error {
@@ -115,9 +324,17 @@ func (p *Provider) Clone() llminterface.Provider {
 	return NewProvider()
 }
 
-// Helper function to identify retryable errors
+// isRetryableError reports whether a failed call may succeed when repeated: rate
+// limits, server errors and timeouts
 func isRetryableError(err error) bool {
-	// Add logic to identify retryable errors based on OpenAI's error types
-	// This is a simplified example
-	return false
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
@@ -0,0 +1,284 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llm/internal/llmtest"
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chatCompletion = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"created": 1700000000,
+	"model": "gpt-4o-2024-08-06",
+	"choices": [{"index": 0, "message": {"role": "assistant", "content": "SELECT 1"}, "finish_reason": "stop"}],
+	"usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}
+}`
+
+func initialized(t *testing.T, config *Config) *Provider {
+	provider := &Provider{}
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	return provider
+}
+
+func TestCompatibleEndpoint(t *testing.T) {
+	server := llmtest.NewServer(t, llmtest.Response{Body: chatCompletion})
+
+	// The trailing slash of the base URL does not end up doubled in the path
+	provider := initialized(t, &Config{
+		Name:    "local",
+		APIKey:  "local-key",
+		Model:   "llama3",
+		BaseURL: server.URL + "/v1/",
+		Headers: map[string]string{"X-Gateway": "insights"},
+	})
+	assert.False(t, provider.SupportsStructuredOutput())
+
+	response, err := provider.Complete(context.Background(), llminterface.CompletionRequest{
+		Messages:       []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+		ResponseFormat: &llminterface.ResponseFormat{Name: "sql_query", Schema: map[string]interface{}{"type": "object"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", response.Content)
+	assert.Equal(t, 15, response.Usage.TotalTokens)
+	assert.Equal(t, "gpt-4o-2024-08-06", response.Metadata["model"])
+
+	assert.Equal(t, "/v1/chat/completions", server.Request().URL.Path)
+	assert.Equal(t, "Bearer local-key", server.Request().Header.Get("Authorization"))
+	assert.Equal(t, "insights", server.Request().Header.Get("X-Gateway"))
+	body := server.Body(t)
+	assert.Equal(t, "llama3", body["model"])
+	// Compatible servers only get a response format when the config enables it
+	assert.NotContains(t, body, "response_format")
+}
+
+func TestAzureDeployment(t *testing.T) {
+	server := llmtest.NewServer(t, llmtest.Response{Body: chatCompletion})
+
+	provider := initialized(t, &Config{
+		Name:       "azure",
+		APIKey:     "azure-key",
+		Model:      "gpt-4o",
+		BaseURL:    server.URL + "/openai/deployments/gpt-4o",
+		APIVersion: "2024-06-01",
+	})
+	_, err := provider.Complete(context.Background(), llminterface.CompletionRequest{
+		Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/openai/deployments/gpt-4o/chat/completions", server.Request().URL.Path)
+	assert.Equal(t, "2024-06-01", server.Request().URL.Query().Get("api-version"))
+	assert.Equal(t, "azure-key", server.Request().Header.Get("api-key"))
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		code       string
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"7"}},
+			body:       `{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`,
+			code:       "rate_limit_exceeded",
+			retryable:  true,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:      "server error",
+			status:    http.StatusServiceUnavailable,
+			body:      `{"error": {"message": "The server is overloaded"}}`,
+			code:      "api_error",
+			retryable: true,
+		},
+		{
+			name:   "bad request",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"message": "Invalid schema", "type": "invalid_request_error", "code": "invalid_schema"}}`,
+			code:   "invalid_schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := llmtest.NewServer(t, llmtest.Response{Status: tt.status, Header: tt.header, Body: tt.body})
+			provider := initialized(t, &Config{Name: "local", Model: "llama3", BaseURL: server.URL})
+
+			_, err := provider.Complete(context.Background(), llminterface.CompletionRequest{
+				Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+			})
+			var llmErr *llminterface.Error
+			require.ErrorAs(t, err, &llmErr)
+			assert.Equal(t, tt.code, llmErr.Code)
+			assert.Equal(t, tt.status, llmErr.StatusCode)
+			assert.Equal(t, tt.retryable, llmErr.Retryable)
+			assert.Equal(t, tt.retryAfter, llmErr.RetryAfter)
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		assert.True(t, isRetryableError(context.DeadlineExceeded))
+		assert.False(t, isRetryableError(context.Canceled))
+	})
+}
+
+func TestCompleteStream(t *testing.T) {
+	chunk := func(choices string, usage string) string {
+		return `data: {"id": "chatcmpl-2", "object": "chat.completion.chunk", "created": 1700000000, "model": "gpt-4o", "choices": ` +
+			choices + usage + "}\n\n"
+	}
+	delta := func(content string) string {
+		return chunk(`[{"index": 0, "delta": {"content": "`+content+`"}, "finish_reason": null}]`, "")
+	}
+	events := delta("There are ") + delta("**42**") + delta(" users.") +
+		chunk(`[]`, `, "usage": {"prompt_tokens": 30, "completion_tokens": 6, "total_tokens": 36}`) +
+		"data: [DONE]\n\n"
+	sse := http.Header{"Content-Type": {"text/event-stream"}}
+
+	t.Run("deltas in order and usage from the final chunk", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Response{Header: sse, Body: events})
+		provider := initialized(t, &Config{Name: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL})
+
+		var deltas []string
+		response, err := provider.CompleteStream(context.Background(), llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+		}, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"There are ", "**42**", " users."}, deltas)
+		assert.Equal(t, "There are **42** users.", response.Content)
+		assert.Equal(t, 30, response.Usage.PromptTokens)
+		assert.Equal(t, 6, response.Usage.CompletionTokens)
+		assert.Equal(t, 36, response.Usage.TotalTokens)
+		assert.Equal(t, "chatcmpl-2", response.Metadata["id"])
+
+		body := server.Body(t)
+		assert.Equal(t, true, body["stream"])
+		assert.Equal(t, map[string]interface{}{"include_usage": true}, body["stream_options"])
+	})
+
+	t.Run("handler error stops the stream", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Response{Header: sse, Body: events})
+		provider := initialized(t, &Config{Name: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL})
+
+		stop := errors.New("client went away")
+		var deltas []string
+		_, err := provider.CompleteStream(context.Background(), llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+		}, func(delta string) error {
+			deltas = append(deltas, delta)
+			if len(deltas) == 2 {
+				return stop
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, []string{"There are ", "**42**"}, deltas)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Response{
+			Status: http.StatusTooManyRequests,
+			Body:   `{"error": {"message": "Rate limit reached", "code": "rate_limit_exceeded"}}`,
+		})
+		provider := initialized(t, &Config{Name: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL})
+
+		_, err := provider.CompleteStream(context.Background(), llminterface.CompletionRequest{
+			Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+		}, func(string) error { return nil })
+		var llmErr *llminterface.Error
+		require.ErrorAs(t, err, &llmErr)
+		assert.True(t, llmErr.Retryable)
+	})
+}
+
+func TestToolCalls(t *testing.T) {
+	server := llmtest.NewServer(t, llmtest.Response{Body: `{
+		"id": "chatcmpl-3",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "gpt-4o",
+		"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_2", "type": "function", "function": {"name": "describe_table", "arguments": "{\"table\":\"orders\"}"}}
+		]}}],
+		"usage": {"prompt_tokens": 40, "completion_tokens": 8, "total_tokens": 48}
+	}`})
+	provider := initialized(t, &Config{Name: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL})
+
+	response, err := provider.Complete(context.Background(), llminterface.CompletionRequest{
+		Messages: []llminterface.Message{
+			{Role: "user", Content: "How many orders are there?"},
+			{Role: "assistant", ToolCalls: []llminterface.ToolCall{{ID: "call_1", Name: "list_tables", Arguments: "{}"}}},
+			{Role: "tool", ToolCallID: "call_1", Content: `["orders"]`},
+		},
+		Tools: []llminterface.Tool{{
+			Name:        "describe_table",
+			Description: "Describe the columns of a table",
+			Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"table": map[string]interface{}{"type": "string"}}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llminterface.ToolCall{{ID: "call_2", Name: "describe_table", Arguments: `{"table":"orders"}`}}, response.ToolCalls)
+
+	// Tools, earlier tool calls and their results are sent in OpenAI's shape
+	body := server.Body(t)
+	encoded, err := json.Marshal(map[string]interface{}{"messages": body["messages"], "tools": body["tools"]})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "How many orders are there?"}]},
+			{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "list_tables", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": [{"type": "text", "text": "[\"orders\"]"}]}
+		],
+		"tools": [{"type": "function", "function": {
+			"name": "describe_table",
+			"description": "Describe the columns of a table",
+			"parameters": {"type": "object", "properties": {"table": {"type": "string"}}}
+		}}]
+	}`, string(encoded))
+}
+
+func TestResponseFormat(t *testing.T) {
+	server := llmtest.NewServer(t, llmtest.Response{Body: chatCompletion})
+	provider := initialized(t, &Config{Name: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL, StructuredOutput: true})
+	assert.True(t, provider.SupportsStructuredOutput())
+
+	_, err := provider.Complete(context.Background(), llminterface.CompletionRequest{
+		Messages: []llminterface.Message{{Role: "user", Content: "How many users are there?"}},
+		ResponseFormat: &llminterface.ResponseFormat{
+			Name:        "sql_query",
+			Description: "SQL query answering the question",
+			Schema:      map[string]interface{}{"type": "object", "properties": map[string]interface{}{"sql": map[string]interface{}{"type": "string"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(server.Body(t)["response_format"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "json_schema",
+		"json_schema": {
+			"name": "sql_query",
+			"description": "SQL query answering the question",
+			"schema": {"type": "object", "properties": {"sql": {"type": "string"}}},
+			"strict": true
+		}
+	}`, string(encoded))
+}
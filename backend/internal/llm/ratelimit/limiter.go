@@ -0,0 +1,344 @@
+// Package ratelimit queues LLM calls behind token buckets per LLM config, so a
+// burst of asks on one API key waits its turn instead of running into the
+// provider's rate limits
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// DefaultMaxWait is how long a call waits in the queue when Limits.MaxWait is unset
+const DefaultMaxWait = time.Minute
+
+// QueueTimeoutCode is the llminterface.Error code of a call that gave up waiting
+const QueueTimeoutCode = "rate_limit_queue_timeout"
+
+// Limits are the rate limits of an LLM config. A zero limit is not enforced.
+type Limits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	// MaxWait bounds how long a call waits in the queue before it fails
+	MaxWait time.Duration
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.RequestsPerMinute > 0 || l.TokensPerMinute > 0
+}
+
+// LimitsFromOptions reads requests_per_minute, tokens_per_minute and
+// max_wait_seconds from the options of an LLM config
+func LimitsFromOptions(options map[string]interface{}) Limits {
+	limits := Limits{
+		RequestsPerMinute: optionInt(options, "requests_per_minute"),
+		TokensPerMinute:   optionInt(options, "tokens_per_minute"),
+		MaxWait:           time.Duration(optionInt(options, "max_wait_seconds")) * time.Second,
+	}
+	if limits.MaxWait <= 0 {
+		limits.MaxWait = DefaultMaxWait
+	}
+	return limits
+}
+
+// optionInt reads a numeric option, which is a float64 when it was decoded from JSON
+func optionInt(options map[string]interface{}, key string) int {
+	switch value := options[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	default:
+		return 0
+	}
+}
+
+// bucket holds up to capacity tokens and refills at capacity per minute. Its level
+// may drop below zero when a call needs more than the capacity, which delays the
+// calls after it.
+type bucket struct {
+	capacity float64
+	level    float64
+}
+
+func newBucket(perMinute int) bucket {
+	return bucket{capacity: float64(perMinute), level: float64(perMinute)}
+}
+
+func (b *bucket) enabled() bool {
+	return b.capacity > 0
+}
+
+func (b *bucket) refill(elapsed time.Duration) {
+	b.level = math.Min(b.capacity, b.level+b.capacity*elapsed.Minutes())
+}
+
+// need returns how many tokens must be available before n may be taken. A call
+// needing more than the capacity only waits for a full bucket.
+func (b *bucket) need(n float64) float64 {
+	return math.Min(n, b.capacity)
+}
+
+// wait returns how long it takes until n tokens can be taken
+func (b *bucket) wait(n float64) time.Duration {
+	missing := b.need(n) - b.level
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / b.capacity * float64(time.Minute))
+}
+
+// Limiter queues the calls of one LLM config until its buckets allow them. Calls
+// are served in arrival order. All methods are safe on a nil *Limiter, which
+// allows every call immediately.
+type Limiter struct {
+	name string
+
+	mu       sync.Mutex
+	limits   Limits
+	requests bucket
+	tokens   bucket
+	updated  time.Time
+	queue    []*waiter
+	// changed is closed and replaced whenever the head of the queue may proceed
+	changed chan struct{}
+
+	// now and after are replaced by tests
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+type waiter struct {
+	tokens float64
+}
+
+// NewLimiter creates a limiter enforcing limits
+func NewLimiter(name string, limits Limits) *Limiter {
+	l := &Limiter{
+		name:    name,
+		changed: make(chan struct{}),
+		now:     time.Now,
+		after:   time.After,
+	}
+	l.setLimits(limits)
+	return l
+}
+
+func (l *Limiter) setLimits(limits Limits) {
+	if limits.MaxWait <= 0 {
+		limits.MaxWait = DefaultMaxWait
+	}
+	l.limits = limits
+	l.requests = newBucket(limits.RequestsPerMinute)
+	l.tokens = newBucket(limits.TokensPerMinute)
+	l.updated = l.now()
+}
+
+// Wait blocks until a call using about tokens tokens may be made, and takes one
+// request and tokens from the buckets. It fails when the call would wait longer
+// than MaxWait, or past the deadline of ctx. It returns how long the call waited.
+func (l *Limiter) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	start := l.now()
+	w := &waiter{tokens: float64(tokens)}
+
+	l.mu.Lock()
+	deadline := start.Add(l.limits.MaxWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	l.queue = append(l.queue, w)
+	l.mu.Unlock()
+
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.refill(now)
+
+		var wait time.Duration
+		if l.queue[0] == w {
+			wait = max(l.requests.wait(1), l.tokens.wait(w.tokens))
+			if wait == 0 {
+				l.take(w)
+				l.mu.Unlock()
+				return now.Sub(start), nil
+			}
+		} else {
+			// Only the head of the queue may take; check again once it has
+			wait = -1
+		}
+
+		if wait >= 0 && now.Add(wait).After(deadline) {
+			// Do not hold up the queue for a call that cannot make its deadline
+			l.remove(w)
+			l.mu.Unlock()
+			return now.Sub(start), l.timeoutError(now.Sub(start), wait)
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		var timer <-chan time.Time
+		if wait >= 0 {
+			timer = l.after(wait)
+		} else {
+			timer = l.after(deadline.Sub(now))
+		}
+
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.remove(w)
+			l.mu.Unlock()
+			return l.now().Sub(start), ctx.Err()
+		case <-changed:
+		case <-timer:
+			if wait < 0 && !l.now().Before(deadline) {
+				l.mu.Lock()
+				l.remove(w)
+				l.mu.Unlock()
+				return l.now().Sub(start), l.timeoutError(l.now().Sub(start), 0)
+			}
+		}
+	}
+}
+
+// Settle corrects the tokens taken for a call once its actual usage is known. A
+// failed call without usage passes 0, returning the estimate to the bucket.
+func (l *Limiter) Settle(estimated, actual int) {
+	if l == nil || !l.tokens.enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(l.now())
+	l.tokens.level = math.Min(l.tokens.capacity, l.tokens.level+float64(estimated-actual))
+	l.notify()
+}
+
+// State is a snapshot of a limiter, exposed as metrics
+type State struct {
+	Name              string
+	Limits            Limits
+	Queued            int
+	AvailableRequests float64
+	AvailableTokens   float64
+}
+
+// State returns the current state of the limiter
+func (l *Limiter) State() State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(l.now())
+	return State{
+		Name:              l.name,
+		Limits:            l.limits,
+		Queued:            len(l.queue),
+		AvailableRequests: l.requests.level,
+		AvailableTokens:   l.tokens.level,
+	}
+}
+
+func (l *Limiter) refill(now time.Time) {
+	elapsed := now.Sub(l.updated)
+	if elapsed <= 0 {
+		return
+	}
+	l.updated = now
+	l.requests.refill(elapsed)
+	l.tokens.refill(elapsed)
+}
+
+func (l *Limiter) take(w *waiter) {
+	if l.requests.enabled() {
+		l.requests.level--
+	}
+	if l.tokens.enabled() {
+		l.tokens.level -= w.tokens
+	}
+	l.remove(w)
+}
+
+// remove takes w out of the queue, letting the next call check its turn
+func (l *Limiter) remove(w *waiter) {
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	l.notify()
+}
+
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *Limiter) timeoutError(waited, remaining time.Duration) error {
+	return &llminterface.Error{
+		Code: QueueTimeoutCode,
+		Message: fmt.Sprintf("rate limit of LLM config %s: gave up after waiting %s with %s still to wait",
+			l.name, waited.Round(time.Millisecond), remaining.Round(time.Millisecond)),
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*Limiter)
+)
+
+// LimiterFor returns the limiter shared by every ask that uses the LLM config
+// identified by key, or nil when limits are not enabled. A limiter whose limits
+// changed starts over with full buckets.
+func LimiterFor(key string, limits Limits) *Limiter {
+	if limits.MaxWait <= 0 {
+		limits.MaxWait = DefaultMaxWait
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	l, ok := limiters[key]
+	if !limits.Enabled() {
+		if ok {
+			delete(limiters, key)
+		}
+		return nil
+	}
+	if !ok {
+		l = NewLimiter(key, limits)
+		limiters[key] = l
+		return l
+	}
+
+	l.mu.Lock()
+	if l.limits != limits {
+		l.setLimits(limits)
+		l.notify()
+	}
+	l.mu.Unlock()
+	return l
+}
+
+// states returns the state of every shared limiter
+func states() []State {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	result := make([]State, 0, len(limiters))
+	for _, l := range limiters {
+		result = append(result, l.State())
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+var (
+	metricsMu   sync.Mutex
+	waitHist    api.Float64Histogram
+	timeoutsCnt api.Int64Counter
+)
+
+// RegisterMetrics publishes the state of every limiter on meter: queued calls,
+// available requests and tokens, how long calls waited and how many gave up
+func RegisterMetrics(meter api.Meter) error {
+	queued, err := meter.Int64ObservableGauge(
+		"llm_rate_limit_queued_calls",
+		api.WithDescription("Number of LLM calls waiting for their rate limit"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create queued calls gauge: %w", err)
+	}
+
+	requests, err := meter.Float64ObservableGauge(
+		"llm_rate_limit_available_requests",
+		api.WithDescription("Requests an LLM config may make before it has to wait"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create available requests gauge: %w", err)
+	}
+
+	tokens, err := meter.Float64ObservableGauge(
+		"llm_rate_limit_available_tokens",
+		api.WithDescription("Tokens an LLM config may use before it has to wait"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create available tokens gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer api.Observer) error {
+		for _, state := range states() {
+			attributes := api.WithAttributes(attribute.String("llm_config", state.Name))
+			observer.ObserveInt64(queued, int64(state.Queued), attributes)
+			if state.Limits.RequestsPerMinute > 0 {
+				observer.ObserveFloat64(requests, state.AvailableRequests, attributes)
+			}
+			if state.Limits.TokensPerMinute > 0 {
+				observer.ObserveFloat64(tokens, state.AvailableTokens, attributes)
+			}
+		}
+		return nil
+	}, queued, requests, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to register rate limit callback: %w", err)
+	}
+
+	hist, err := meter.Float64Histogram(
+		"llm_rate_limit_wait_seconds",
+		api.WithDescription("Time LLM calls waited for their rate limit"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create wait histogram: %w", err)
+	}
+
+	counter, err := meter.Int64Counter(
+		"llm_rate_limit_timeouts_total",
+		api.WithDescription("Number of LLM calls that gave up waiting for their rate limit"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create timeout counter: %w", err)
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	waitHist = hist
+	timeoutsCnt = counter
+	return nil
+}
+
+// recordWait records a call that waited for limiter name
+func recordWait(ctx context.Context, name string, waited time.Duration, err error) {
+	metricsMu.Lock()
+	hist, counter := waitHist, timeoutsCnt
+	metricsMu.Unlock()
+
+	attributes := api.WithAttributes(attribute.String("llm_config", name))
+	if hist != nil {
+		hist.Record(ctx, waited.Seconds(), attributes)
+	}
+	var llmErr *llminterface.Error
+	if counter != nil && errors.As(err, &llmErr) && llmErr.Code == QueueTimeoutCode {
+		counter.Add(ctx, 1, attributes)
+	}
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Provider implements llminterface.Provider by delegating to another provider once
+// the limiter allows the call
+type Provider struct {
+	provider llminterface.Provider
+	limiter  *Limiter
+}
+
+// Wrap returns provider with its calls queued behind limiter, which may be nil
+func Wrap(provider llminterface.Provider, limiter *Limiter) *Provider {
+	return &Provider{
+		provider: provider,
+		limiter:  limiter,
+	}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return p.provider.Initialize(ctx, config)
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	estimated := EstimateTokens(req)
+	if err := p.wait(ctx, estimated); err != nil {
+		return nil, err
+	}
+
+	response, err := p.provider.Complete(ctx, req)
+	p.settle(estimated, response)
+	return response, err
+}
+
+// CompleteStream implements llminterface.StreamingProvider
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	estimated := EstimateTokens(req)
+	if err := p.wait(ctx, estimated); err != nil {
+		return nil, err
+	}
+
+	response, err := llminterface.CompleteStream(ctx, p.provider, req, handler)
+	p.settle(estimated, response)
+	return response, err
+}
+
+func (p *Provider) wait(ctx context.Context, estimated int) error {
+	waited, err := p.limiter.Wait(ctx, estimated)
+	if p.limiter != nil {
+		recordWait(ctx, p.limiter.name, waited, err)
+	}
+	return err
+}
+
+// settle replaces the estimate with the tokens the call actually used. Providers
+// that do not report usage keep the estimate.
+func (p *Provider) settle(estimated int, response *llminterface.CompletionResponse) {
+	actual := 0
+	if response != nil {
+		actual = response.Usage.TotalTokens
+		if actual == 0 {
+			actual = estimated
+		}
+	}
+	p.limiter.Settle(estimated, actual)
+}
+
+// SupportsStructuredOutput implements llminterface.StructuredOutputProvider for
+// the wrapped provider
+func (p *Provider) SupportsStructuredOutput() bool {
+	return llminterface.SupportsStructuredOutput(p.provider)
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	return p.provider.Close(ctx)
+}
+
+// Clone wraps a clone of the provider with the same limiter
+func (p *Provider) Clone() llminterface.Provider {
+	return Wrap(p.provider.Clone(), p.limiter)
+}
+
+// EstimateTokens guesses the tokens a call uses before it is made: about four
+// characters per prompt token plus the completion limit
+func EstimateTokens(req llminterface.CompletionRequest) int {
+	chars := 0
+	for _, message := range req.Messages {
+		chars += len(message.Content)
+		for _, toolCall := range message.ToolCalls {
+			chars += len(toolCall.Name) + len(toolCall.Arguments)
+		}
+	}
+	for _, tool := range req.Tools {
+		chars += len(tool.Name) + len(tool.Description)
+	}
+	return chars/4 + 1 + req.MaxTokens
+}
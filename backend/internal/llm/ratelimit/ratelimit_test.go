@@ -0,0 +1,330 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// fakeClock moves time forward by every wait instead of sleeping
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// newTestLimiter returns a limiter running on a fake clock that starts now
+func newTestLimiter(limits Limits) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	l := &Limiter{
+		name:    "test",
+		changed: make(chan struct{}),
+		now:     clock.Now,
+		after:   clock.After,
+	}
+	l.setLimits(limits)
+	return l, clock
+}
+
+func requireTimeout(t *testing.T, err error) {
+	t.Helper()
+	var llmErr *llminterface.Error
+	require.True(t, errors.As(err, &llmErr), "expected an llminterface.Error, got %v", err)
+	assert.Equal(t, QueueTimeoutCode, llmErr.Code)
+	assert.False(t, llmErr.Retryable)
+}
+
+func TestLimiterRequests(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter(Limits{RequestsPerMinute: 2})
+
+	for i := 0; i < 2; i++ {
+		waited, err := l.Wait(ctx, 0)
+		require.NoError(t, err)
+		assert.Zero(t, waited)
+	}
+
+	waited, err := l.Wait(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, waited)
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.waits)
+
+	state := l.State()
+	assert.Zero(t, state.Queued)
+	assert.InDelta(t, 0, state.AvailableRequests, 1e-9)
+	assert.Equal(t, DefaultMaxWait, state.Limits.MaxWait)
+}
+
+func TestLimiterTokens(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("waits for tokens and settles actual usage", func(t *testing.T) {
+		l, _ := newTestLimiter(Limits{TokensPerMinute: 1000})
+
+		_, err := l.Wait(ctx, 800)
+		require.NoError(t, err)
+
+		waited, err := l.Wait(ctx, 800)
+		require.NoError(t, err)
+		assert.Equal(t, 36*time.Second, waited)
+		assert.InDelta(t, 0, l.State().AvailableTokens, 1e-6)
+
+		// The call used far fewer tokens than estimated
+		l.Settle(800, 100)
+		assert.InDelta(t, 700, l.State().AvailableTokens, 1e-6)
+
+		waited, err = l.Wait(ctx, 500)
+		require.NoError(t, err)
+		assert.Zero(t, waited)
+	})
+
+	t.Run("call larger than the bucket", func(t *testing.T) {
+		l, clock := newTestLimiter(Limits{TokensPerMinute: 1000})
+
+		// A call above the capacity only waits for a full bucket
+		waited, err := l.Wait(ctx, 3000)
+		require.NoError(t, err)
+		assert.Zero(t, waited)
+
+		// and pushes the calls after it back: 2100 tokens take over two minutes
+		_, err = l.Wait(ctx, 100)
+		requireTimeout(t, err)
+		assert.Empty(t, clock.waits, "a call that cannot make its deadline should fail without waiting")
+		assert.Zero(t, l.State().Queued)
+	})
+}
+
+func TestLimiterDeadline(t *testing.T) {
+	l, clock := newTestLimiter(Limits{RequestsPerMinute: 1, MaxWait: 5 * time.Minute})
+
+	_, err := l.Wait(context.Background(), 0)
+	require.NoError(t, err)
+
+	// The context deadline is shorter than MaxWait
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = l.Wait(ctx, 0)
+	requireTimeout(t, err)
+	assert.Empty(t, clock.waits)
+
+	// MaxWait is long enough without it
+	waited, err := l.Wait(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, waited)
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter("test", Limits{RequestsPerMinute: 1, MaxWait: 5 * time.Minute})
+
+	_, err := l.Wait(context.Background(), 0)
+	require.NoError(t, err)
+
+	// Two calls queue up for the next request
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := l.Wait(ctx1, 0)
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return l.State().Queued == 1 }, time.Second, time.Millisecond)
+	go func() {
+		_, err := l.Wait(ctx2, 0)
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return l.State().Queued == 2 }, time.Second, time.Millisecond)
+
+	// Giving up leaves the queue
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Equal(t, 1, l.State().Queued)
+
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Zero(t, l.State().Queued)
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	waited, err := l.Wait(context.Background(), 1000)
+	require.NoError(t, err)
+	assert.Zero(t, waited)
+	l.Settle(1000, 10)
+}
+
+func TestLimitsFromOptions(t *testing.T) {
+	limits := LimitsFromOptions(map[string]interface{}{
+		// Options read back from storage hold JSON numbers
+		"requests_per_minute": float64(60),
+		"tokens_per_minute":   90000,
+		"max_wait_seconds":    float64(30),
+		"max_tokens":          float64(1000),
+	})
+	assert.Equal(t, Limits{RequestsPerMinute: 60, TokensPerMinute: 90000, MaxWait: 30 * time.Second}, limits)
+	assert.True(t, limits.Enabled())
+
+	limits = LimitsFromOptions(nil)
+	assert.Equal(t, Limits{MaxWait: DefaultMaxWait}, limits)
+	assert.False(t, limits.Enabled())
+}
+
+func TestLimiterFor(t *testing.T) {
+	key := "openai/limiter-for"
+	limits := Limits{RequestsPerMinute: 10}
+
+	l := LimiterFor(key, limits)
+	require.NotNil(t, l)
+	assert.Same(t, l, LimiterFor(key, limits))
+
+	// Changed limits apply to the shared limiter
+	_, err := l.Wait(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Same(t, l, LimiterFor(key, Limits{RequestsPerMinute: 20}))
+	state := l.State()
+	assert.Equal(t, 20, state.Limits.RequestsPerMinute)
+	assert.InDelta(t, 20, state.AvailableRequests, 1e-6)
+
+	// Removing the limits drops the limiter
+	assert.Nil(t, LimiterFor(key, Limits{}))
+	for _, state := range states() {
+		assert.NotEqual(t, key, state.Name)
+	}
+}
+
+// usageProvider answers every call with the given token usage
+type usageProvider struct {
+	totalTokens int
+	err         error
+	calls       int
+}
+
+func (p *usageProvider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return nil
+}
+
+func (p *usageProvider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	response := &llminterface.CompletionResponse{Content: "ok"}
+	response.Usage.TotalTokens = p.totalTokens
+	return response, nil
+}
+
+func (p *usageProvider) Close(ctx context.Context) error { return nil }
+
+func (p *usageProvider) Clone() llminterface.Provider {
+	return &usageProvider{totalTokens: p.totalTokens}
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	req := llminterface.CompletionRequest{
+		Messages:  []llminterface.Message{{Role: "user", Content: strings.Repeat("a", 400)}},
+		MaxTokens: 100,
+	}
+	estimated := EstimateTokens(req)
+	assert.Equal(t, 201, estimated)
+
+	t.Run("settles the reported usage", func(t *testing.T) {
+		l, _ := newTestLimiter(Limits{TokensPerMinute: 1000})
+		provider := &usageProvider{totalTokens: 50}
+
+		response, err := Wrap(provider, l).Complete(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", response.Content)
+		assert.InDelta(t, 950, l.State().AvailableTokens, 1e-6)
+	})
+
+	t.Run("failed call returns its estimate", func(t *testing.T) {
+		l, _ := newTestLimiter(Limits{TokensPerMinute: 1000})
+		provider := &usageProvider{err: errors.New("boom")}
+
+		_, err := Wrap(provider, l).Complete(ctx, req)
+		require.Error(t, err)
+		assert.InDelta(t, 1000, l.State().AvailableTokens, 1e-6)
+	})
+
+	t.Run("call over the limit is not made", func(t *testing.T) {
+		l, _ := newTestLimiter(Limits{RequestsPerMinute: 1, MaxWait: time.Second})
+		provider := &usageProvider{}
+		wrapped := Wrap(provider, l)
+
+		_, err := wrapped.Complete(ctx, req)
+		require.NoError(t, err)
+		_, err = wrapped.Complete(ctx, req)
+		requireTimeout(t, err)
+		assert.Equal(t, 1, provider.calls)
+	})
+
+	t.Run("without a limiter", func(t *testing.T) {
+		provider := &usageProvider{}
+		_, err := Wrap(provider, nil).Complete(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, provider.calls)
+	})
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	require.NoError(t, RegisterMetrics(meter))
+
+	key := "openai/metrics"
+	l := LimiterFor(key, Limits{RequestsPerMinute: 10, TokensPerMinute: 1000})
+	defer LimiterFor(key, Limits{})
+	_, err := Wrap(&usageProvider{totalTokens: 100}, l).Complete(context.Background(), llminterface.CompletionRequest{})
+	require.NoError(t, err)
+
+	var data metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &data))
+
+	names := map[string]bool{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			names[m.Name] = true
+			if m.Name != "llm_rate_limit_available_requests" {
+				continue
+			}
+			gauge, ok := m.Data.(metricdata.Gauge[float64])
+			require.True(t, ok)
+			found := false
+			for _, point := range gauge.DataPoints {
+				if name, _ := point.Attributes.Value("llm_config"); name.AsString() == key {
+					found = true
+					assert.InDelta(t, 9, point.Value, 0.1)
+				}
+			}
+			assert.True(t, found, "no data point for %s", key)
+		}
+	}
+	assert.True(t, names["llm_rate_limit_queued_calls"])
+	assert.True(t, names["llm_rate_limit_available_tokens"])
+	assert.True(t, names["llm_rate_limit_wait_seconds"])
+}
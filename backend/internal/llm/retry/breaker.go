@@ -0,0 +1,165 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = "closed"
+	// StateOpen fails calls without making them
+	StateOpen State = "open"
+	// StateHalfOpen lets a single trial call through to see if the provider recovered
+	StateHalfOpen State = "half_open"
+)
+
+// Defaults of the breakers returned by BreakerFor
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenDuration     = 30 * time.Second
+)
+
+// Breaker stops calls to a provider after repeated failures, so an outage fails
+// asks quickly instead of making each of them wait through its retries
+type Breaker struct {
+	name string
+	// failureThreshold is the number of consecutive retryable failures that opens
+	// the circuit
+	failureThreshold int
+	// openDuration is how long the circuit stays open before a trial call
+	openDuration time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// trial is set while the trial call of the half-open state is in flight
+	trial bool
+}
+
+// NewBreaker returns a closed breaker
+func NewBreaker(name string, failureThreshold int, openDuration time.Duration) *Breaker {
+	return &Breaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		now:              time.Now,
+		state:            StateClosed,
+	}
+}
+
+var breakers = struct {
+	sync.Mutex
+	m map[string]*Breaker
+}{m: make(map[string]*Breaker)}
+
+// BreakerFor returns the breaker for key, usually the provider type and LLM config
+// name, so all asks using a config share it
+func BreakerFor(key string) *Breaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	if b, ok := breakers.m[key]; ok {
+		return b
+	}
+	b := NewBreaker(key, DefaultFailureThreshold, DefaultOpenDuration)
+	breakers.m[key] = b
+	return b
+}
+
+// State returns the current state, moving an open circuit to half-open once the
+// open duration has passed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+func (b *Breaker) currentState() State {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		b.state = StateHalfOpen
+		b.trial = false
+	}
+	return b.state
+}
+
+// Allow returns an error when a call must not be made now. A nil breaker allows
+// every call.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		return b.openError(b.openDuration - b.now().Sub(b.openedAt))
+	case StateHalfOpen:
+		if b.trial {
+			return b.openError(0)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *Breaker) openError(retryAfter time.Duration) *llminterface.Error {
+	return &llminterface.Error{
+		Code:       "circuit_open",
+		Message:    fmt.Sprintf("LLM %s is unavailable after %d consecutive failures", b.name, b.failureThreshold),
+		RetryAfter: retryAfter,
+	}
+}
+
+// Success records a call the provider answered, which closes the circuit
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = StateClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure records a call that failed with a retryable error. The circuit opens
+// once the threshold is reached, or again right away when the trial call failed.
+func (b *Breaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.failureThreshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+		b.trial = false
+	}
+}
+
+// Cancel records a call abandoned by the caller, which lets another trial call
+// through when it was the trial
+func (b *Breaker) Cancel() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
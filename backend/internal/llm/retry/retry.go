@@ -0,0 +1,232 @@
+// Package retry wraps an llminterface.Provider with retries and a circuit breaker,
+// so every provider gets the same handling of rate limits and outages
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+)
+
+// Policy controls how often and how quickly a failed call is retried
+type Policy struct {
+	// MaxAttempts is the number of calls made in total, including the first
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles with each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A provider asking to wait longer than this is not
+	// retried.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is used when Options.Policy is left empty
+var DefaultPolicy = Policy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// Attempt describes a failed call that is about to be retried
+type Attempt struct {
+	// Number is the attempt that failed, starting at 1
+	Number      int
+	MaxAttempts int
+	Err         error
+	// Delay is how long the provider waits before the next attempt
+	Delay time.Duration
+}
+
+// Options configures the wrapper returned by Wrap
+type Options struct {
+	Policy Policy
+	// Breaker is shared by all wrappers of the same LLM config; nil disables it
+	Breaker *Breaker
+	// OnRetry is called before waiting for each retry
+	OnRetry func(ctx context.Context, attempt Attempt)
+}
+
+// Provider implements llminterface.Provider by delegating to another provider and
+// retrying calls that failed with a retryable error
+type Provider struct {
+	provider llminterface.Provider
+	options  Options
+	// sleep waits between attempts; tests replace it to run without delays
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Wrap returns provider with retries and the circuit breaker of opts
+func Wrap(provider llminterface.Provider, opts Options) *Provider {
+	if opts.Policy.MaxAttempts <= 0 {
+		opts.Policy = DefaultPolicy
+	}
+	return &Provider{
+		provider: provider,
+		options:  opts,
+		sleep:    sleep,
+	}
+}
+
+func (p *Provider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return p.provider.Initialize(ctx, config)
+}
+
+func (p *Provider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	var response *llminterface.CompletionResponse
+	err := p.do(ctx, func() (bool, error) {
+		var err error
+		response, err = p.provider.Complete(ctx, req)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CompleteStream implements llminterface.StreamingProvider. A stream is only
+// retried while no delta has reached the handler, so the handler never sees the
+// same content twice.
+func (p *Provider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	var response *llminterface.CompletionResponse
+	err := p.do(ctx, func() (bool, error) {
+		delivered := false
+		var err error
+		response, err = llminterface.CompleteStream(ctx, p.provider, req, func(delta string) error {
+			delivered = true
+			return handler(delta)
+		})
+		return !delivered, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// do makes the call until it succeeds, fails for good or runs out of attempts. The
+// call reports whether it may be repeated after a failure.
+func (p *Provider) do(ctx context.Context, call func() (bool, error)) error {
+	policy := p.options.Policy
+	breaker := p.options.Breaker
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := breaker.Allow(); err != nil {
+			// When our own failures have just opened the circuit, the last error says
+			// more than the breaker does
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		repeatable, err := call()
+		switch {
+		case err == nil:
+			breaker.Success()
+			return nil
+		case ctx.Err() != nil:
+			// The caller gave up; that says nothing about the provider
+			breaker.Cancel()
+			return err
+		case IsRetryable(err):
+			breaker.Failure()
+		default:
+			// The provider answered, it just did not like the request
+			breaker.Success()
+			return err
+		}
+		lastErr = err
+
+		if !repeatable || attempt >= policy.MaxAttempts {
+			return err
+		}
+		delay, ok := policy.delay(attempt, err)
+		if !ok {
+			return err
+		}
+
+		if p.options.OnRetry != nil {
+			p.options.OnRetry(ctx, Attempt{
+				Number:      attempt,
+				MaxAttempts: policy.MaxAttempts,
+				Err:         err,
+				Delay:       delay,
+			})
+		}
+		if err := p.sleep(ctx, delay); err != nil {
+			return lastErr
+		}
+	}
+}
+
+// delay returns the backoff after the given failed attempt: exponential with
+// jitter, or what the provider asked for with Retry-After. It reports false when
+// the provider asked for a longer wait than MaxDelay.
+func (policy Policy) delay(attempt int, err error) (time.Duration, bool) {
+	var llmErr *llminterface.Error
+	if errors.As(err, &llmErr) && llmErr.RetryAfter > 0 {
+		return llmErr.RetryAfter, llmErr.RetryAfter <= policy.MaxDelay
+	}
+
+	backoff := policy.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > policy.MaxDelay {
+		backoff = policy.MaxDelay
+	}
+	// Half of the backoff is fixed and half random, so concurrent asks hitting the
+	// same rate limit spread out without retrying immediately
+	half := backoff / 2
+	if half <= 0 {
+		return backoff, true
+	}
+	return half + rand.N(half), true
+}
+
+// IsRetryable reports whether a failed call may succeed when repeated: errors the
+// provider marked retryable, rate limits, server errors and timeouts
+func IsRetryable(err error) bool {
+	var llmErr *llminterface.Error
+	if errors.As(err, &llmErr) {
+		return llmErr.Retryable || llmErr.StatusCode == http.StatusTooManyRequests || llmErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SupportsStructuredOutput implements llminterface.StructuredOutputProvider for
+// the wrapped provider
+func (p *Provider) SupportsStructuredOutput() bool {
+	return llminterface.SupportsStructuredOutput(p.provider)
+}
+
+func (p *Provider) Close(ctx context.Context) error {
+	return p.provider.Close(ctx)
+}
+
+// Clone wraps a clone of the provider with the same options, sharing the breaker
+func (p *Provider) Clone() llminterface.Provider {
+	clone := Wrap(p.provider.Clone(), p.options)
+	clone.sleep = p.sleep
+	return clone
+}
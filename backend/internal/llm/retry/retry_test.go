@@ -0,0 +1,222 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/llminterface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider fails with the given errors in turn and then succeeds
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) Initialize(ctx context.Context, config llminterface.Config) error {
+	return nil
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req llminterface.CompletionRequest) (*llminterface.CompletionResponse, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return nil, p.errs[p.calls-1]
+	}
+	return &llminterface.CompletionResponse{Content: "ok"}, nil
+}
+
+func (p *scriptedProvider) Close(ctx context.Context) error { return nil }
+
+func (p *scriptedProvider) Clone() llminterface.Provider { return &scriptedProvider{} }
+
+// streamingProvider streams a delta before failing with err
+type streamingProvider struct {
+	scriptedProvider
+	err error
+}
+
+func (p *streamingProvider) CompleteStream(ctx context.Context, req llminterface.CompletionRequest, handler llminterface.StreamHandler) (*llminterface.CompletionResponse, error) {
+	p.calls++
+	if err := handler("partial"); err != nil {
+		return nil, err
+	}
+	return nil, p.err
+}
+
+func rateLimited(retryAfter time.Duration) error {
+	return &llminterface.Error{Code: "rate_limited", StatusCode: 429, Retryable: true, RetryAfter: retryAfter}
+}
+
+func serverError() error {
+	return &llminterface.Error{Code: "api_error", StatusCode: 503, Retryable: true}
+}
+
+// wrap returns a wrapper that records its delays instead of sleeping
+func wrap(provider llminterface.Provider, opts Options) (*Provider, *[]time.Duration) {
+	wrapped := Wrap(provider, opts)
+	var delays []time.Duration
+	wrapped.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return wrapped, &delays
+}
+
+func TestComplete(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+	t.Run("retries until success", func(t *testing.T) {
+		provider := &scriptedProvider{errs: []error{serverError(), rateLimited(2 * time.Second)}}
+		var attempts []Attempt
+		wrapped, delays := wrap(provider, Options{
+			Policy:  policy,
+			OnRetry: func(ctx context.Context, attempt Attempt) { attempts = append(attempts, attempt) },
+		})
+
+		resp, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Content)
+		assert.Equal(t, 3, provider.calls)
+
+		require.Len(t, *delays, 2)
+		assert.GreaterOrEqual(t, (*delays)[0], 50*time.Millisecond)
+		assert.Less(t, (*delays)[0], 100*time.Millisecond)
+		// Retry-After wins over the backoff
+		assert.Equal(t, 2*time.Second, (*delays)[1])
+
+		require.Len(t, attempts, 2)
+		assert.Equal(t, 1, attempts[0].Number)
+		assert.Equal(t, 3, attempts[0].MaxAttempts)
+		assert.Equal(t, 2*time.Second, attempts[1].Delay)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		provider := &scriptedProvider{errs: []error{serverError(), serverError(), serverError(), serverError()}}
+		wrapped, _ := wrap(provider, Options{Policy: policy})
+
+		_, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+		assert.Equal(t, serverError(), err)
+		assert.Equal(t, 3, provider.calls)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		badRequest := &llminterface.Error{Code: "invalid_request", StatusCode: 400}
+		provider := &scriptedProvider{errs: []error{badRequest}}
+		wrapped, _ := wrap(provider, Options{Policy: policy})
+
+		_, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+		assert.Equal(t, badRequest, err)
+		assert.Equal(t, 1, provider.calls)
+	})
+
+	t.Run("does not wait longer than max delay", func(t *testing.T) {
+		provider := &scriptedProvider{errs: []error{rateLimited(time.Minute)}}
+		wrapped, delays := wrap(provider, Options{Policy: policy})
+
+		_, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+		assert.Error(t, err)
+		assert.Equal(t, 1, provider.calls)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		provider := &scriptedProvider{errs: []error{serverError(), serverError()}}
+		wrapped, _ := wrap(provider, Options{
+			Policy:  policy,
+			OnRetry: func(ctx context.Context, attempt Attempt) { cancel() },
+		})
+
+		_, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+		assert.Equal(t, serverError(), err)
+		assert.Equal(t, 1, provider.calls)
+	})
+
+	t.Run("streams are not retried after a delta", func(t *testing.T) {
+		provider := &streamingProvider{err: serverError()}
+		wrapped, _ := wrap(provider, Options{Policy: policy})
+
+		var deltas []string
+		_, err := wrapped.CompleteStream(ctx, llminterface.CompletionRequest{}, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, provider.calls)
+		assert.Equal(t, []string{"partial"}, deltas)
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit", &llminterface.Error{StatusCode: 429}, true},
+		{"server error", &llminterface.Error{StatusCode: 502}, true},
+		{"marked retryable", &llminterface.Error{Code: "request_failed", Retryable: true}, true},
+		{"client error", &llminterface.Error{StatusCode: 401}, false},
+		{"timeout", context.DeadlineExceeded, true},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("invalid config"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewBreaker("openai/prod", 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	provider := &scriptedProvider{errs: []error{serverError(), serverError(), serverError()}}
+	wrapped, _ := wrap(provider, Options{
+		Policy:  Policy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+		Breaker: breaker,
+	})
+
+	// Two failures open the circuit; the wrapper returns the provider's error
+	_, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+	assert.Equal(t, serverError(), err)
+	assert.Equal(t, 2, provider.calls)
+	assert.Equal(t, StateOpen, breaker.State())
+
+	// While open, calls fail fast
+	_, err = wrapped.Complete(ctx, llminterface.CompletionRequest{})
+	var llmErr *llminterface.Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "circuit_open", llmErr.Code)
+	assert.Equal(t, time.Minute, llmErr.RetryAfter)
+	assert.Equal(t, 2, provider.calls)
+
+	// After the open duration a failed trial opens the circuit again
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, breaker.State())
+	_, err = wrapped.Complete(ctx, llminterface.CompletionRequest{})
+	assert.Equal(t, serverError(), err)
+	assert.Equal(t, 3, provider.calls)
+	assert.Equal(t, StateOpen, breaker.State())
+
+	// A successful trial closes it
+	now = now.Add(time.Minute)
+	resp, err := wrapped.Complete(ctx, llminterface.CompletionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Content)
+	assert.Equal(t, StateClosed, breaker.State())
+}
+
+func TestBreakerFor(t *testing.T) {
+	assert.Same(t, BreakerFor("anthropic/a"), BreakerFor("anthropic/a"))
+	assert.NotSame(t, BreakerFor("anthropic/a"), BreakerFor("anthropic/b"))
+}
@@ -2,6 +2,10 @@ package llminterface
 
 import (
 	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config represents the base configuration interface that all LLM providers must implement
@@ -14,8 +18,25 @@ type Config interface {
 
 // Message represents a chat message in a conversation
 type Message struct {
-	Role    string // Role can be "system", "user", or "assistant"
-	Content string // The actual message content
+	Role       string     // Role can be "system", "user", "assistant" or "tool"
+	Content    string     // The actual message content
+	ToolCalls  []ToolCall // Tool calls requested by an assistant message
+	ToolCallID string     // The tool call a "tool" message answers
+}
+
+// Tool describes a function the model may call instead of answering directly
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object
+	Parameters map[string]interface{}
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON-encoded arguments object
 }
 
 // CompletionRequest represents a request for text completion
@@ -24,13 +45,27 @@ type CompletionRequest struct {
 	MaxTokens   int
 	Temperature float64
 	Model       string
+	Tools       []Tool                 // Tools the model may call
 	Options     map[string]interface{} // Provider-specific options
+	// ResponseFormat asks for the content to be a JSON object matching a schema.
+	// It is only honoured by providers that support structured output.
+	ResponseFormat *ResponseFormat
+}
+
+// ResponseFormat describes the JSON object a completion must consist of
+type ResponseFormat struct {
+	Name        string
+	Description string
+	// Schema is the JSON schema of the object
+	Schema map[string]interface{}
 }
 
 // CompletionResponse represents the response from an LLM
 type CompletionResponse struct {
 	Content string
-	Usage   struct {
+	// ToolCalls is set when the model asks for tools to be run before it answers
+	ToolCalls []ToolCall
+	Usage     struct {
 		PromptTokens     int
 		CompletionTokens int
 		TotalTokens      int
@@ -45,12 +80,35 @@ type Error struct {
 	Code      string // Provider-specific error code
 	Message   string // Human-readable error message
 	Retryable bool   // Whether the error is potentially retryable
+	// StatusCode is the HTTP status of the failed request, 0 when there was none
+	StatusCode int
+	// RetryAfter is how long the provider asked to wait before retrying
+	RetryAfter time.Duration
 }
 
 func (e *Error) Error() string {
 	return e.Message
 }
 
+// ParseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP
+// date. It returns 0 when the header is missing or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 // Provider defines the interface that all LLM providers must implement
 type Provider interface {
 	// Initialize sets up the provider with the given configuration
@@ -62,3 +120,48 @@ type Provider interface {
 	// Clone creates a new instance of the provider
 	Clone() Provider
 }
+
+// StructuredOutputProvider is implemented by providers that can constrain the
+// completion to the JSON schema of CompletionRequest.ResponseFormat
+type StructuredOutputProvider interface {
+	Provider
+	// SupportsStructuredOutput reports whether ResponseFormat is enforced
+	SupportsStructuredOutput() bool
+}
+
+// SupportsStructuredOutput reports whether provider enforces ResponseFormat
+func SupportsStructuredOutput(provider Provider) bool {
+	structured, ok := provider.(StructuredOutputProvider)
+	return ok && structured.SupportsStructuredOutput()
+}
+
+// StreamHandler receives the content deltas of a streamed completion in order.
+// Returning an error aborts the stream.
+type StreamHandler func(delta string) error
+
+// StreamingProvider is implemented by providers that can stream completions
+type StreamingProvider interface {
+	Provider
+	// CompleteStream generates a completion, passing each content delta to handler as
+	// it is produced, and returns the full completion once the stream has ended
+	CompleteStream(ctx context.Context, req CompletionRequest, handler StreamHandler) (*CompletionResponse, error)
+}
+
+// CompleteStream streams a completion from provider when it supports streaming, and
+// otherwise falls back to Complete, passing the whole content to handler at once
+func CompleteStream(ctx context.Context, provider Provider, req CompletionRequest, handler StreamHandler) (*CompletionResponse, error) {
+	if streaming, ok := provider.(StreamingProvider); ok {
+		return streaming.CompleteStream(ctx, req, handler)
+	}
+
+	response, err := provider.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if response.Content != "" {
+		if err := handler(response.Content); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
@@ -3,18 +3,14 @@
 package llmregistry
 
 import (
-	"context"
 	"fmt"
-	"sync"
 
-	"github.com/shahariaazam/smart-insights/internal/api/models"
 	"github.com/shahariaazam/smart-insights/internal/llm/factory"
 	"github.com/shahariaazam/smart-insights/internal/llminterface"
 	"github.com/shahariaazam/smart-insights/internal/storage"
 )
 
 type Registry struct {
-	mu      sync.RWMutex
 	storage storage.Storage
 }
 
@@ -25,55 +21,19 @@ func NewRegistry(storage storage.Storage) *Registry {
 	}
 }
 
+// GetProvider returns a new provider of the named type. The provider is not
+// initialized: callers initialize it with the config they use, so a broken config
+// of the same type cannot get in the way.
 func (r *Registry) GetProvider(name string) (llminterface.Provider, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	if r.storage == nil {
-		return nil, fmt.Errorf("storage not initialized")
-	}
-
-	// Load configuration from storage
-	configs, err := r.storage.GetLLMConfigs(context.Background(), name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load LLM configs: %w", err)
-	}
-
-	if len(configs) == 0 {
-		return nil, fmt.Errorf("no configuration found for provider %s", name)
-	}
-
-	// Create new provider instance
 	provider, err := factory.CreateProvider(name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider: %w", err)
 	}
-
-	// Convert the config
-	if config, ok := configs[0].(models.LLMConfig); ok {
-		llmConfig, err := factory.CreateConfig(
-			name,
-			config.Name,
-			config.APIKey,
-			config.Model,
-			config.Options,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create config: %w", err)
-		}
-
-		// Initialize provider with the converted config
-		if err := provider.Initialize(context.Background(), llmConfig); err != nil {
-			return nil, fmt.Errorf("failed to initialize provider: %w", err)
-		}
-
-		return provider, nil
-	}
-	return nil, fmt.Errorf("invalid config type for provider %s", name)
+	return provider, nil
 }
 
 func (r *Registry) ListProviders() []string {
-	return []string{"openai"} // Add other providers as they become available
+	return []string{"openai", "anthropic", "gemini", "bedrock", "openai_compatible", "http"} // Add other providers as they become available
 }
 
 // Updated global registry to include storage
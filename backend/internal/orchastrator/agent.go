@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/llm"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/shahariaazam/smart-insights/internal/source"
+)
+
+// SQL generation modes
+const (
+	// ModePrompt sends the whole database schema with the question
+	ModePrompt = "prompt"
+	// ModeAgent lets the model explore the database through tool calls
+	ModeAgent = "agent"
+)
+
+// maxAgentSteps bounds the number of completions in the agent loop. The last step
+// offers no tools, so the model has to answer with a query.
+const maxAgentSteps = 8
+
+// generateSQLQueryWithTools runs the agent loop: the model calls schema tools on db
+// until it answers with a query. Every tool call and its result is logged on the ask.
+func (o *Orchestrator) generateSQLQueryWithTools(ctx context.Context, db dbinterface.Provider, question string, history []models.AssistantResponse, appender *source.ResponseAppender) (string, error) {
+	// The tools explore the database with SQL
+	if db.Dialect() == prompt.MongoDB {
+		return "", fmt.Errorf("agent mode does not support %s databases", prompt.MongoDB)
+	}
+
+	appender.AppendResponse(ctx, o.askID, "step_output", "Exploring the database to generate a SQL query... please wait")
+
+	tools := newSchemaTools(db)
+	sqlStep := o.steps[models.StepSQL]
+	payload := prompt.LLMPayload{
+		Dialect:          o.dialect,
+		Question:         question,
+		StructuredOutput: sqlStep.structuredOutput,
+	}
+
+	systemPrompt := prompt.SystemPrompt(o.dialect, "explores a database with tools and generates SQL queries based on natural language questions")
+	if len(history) > 0 {
+		systemPrompt += " Earlier questions of this conversation are included with the queries that answered them; the new question may refer to them."
+	}
+
+	messages := []llm.Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+	}
+	messages = append(messages, historyMessages(o.dialect, history)...)
+	messages = append(messages, llm.Message{
+		Role:    "user",
+		Content: payload.AgentPrompt(),
+	})
+
+	for step := 1; step <= maxAgentSteps; step++ {
+		request := sqlStep.request(messages, sqlStep.sqlResponseFormat())
+		if step < maxAgentSteps {
+			request.Tools = tools.definitions()
+		} else {
+			request.Messages = append(request.Messages, llm.Message{
+				Role:    "user",
+				Content: "You have run out of tool calls. Answer now with the final query.",
+			})
+		}
+
+		completion, err := sqlStep.complete(ctx, request)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate SQL query: %w", err)
+		}
+
+		if len(completion.ToolCalls) == 0 {
+			result, err := prompt.ParseSQLResult(completion.Content)
+			if err != nil {
+				return "", err
+			}
+			if err := appender.AppendResponse(ctx, o.askID, "step_output", result.SQL); err != nil {
+				return "", err
+			}
+			return result.SQL, nil
+		}
+
+		messages = append(messages, llm.Message{
+			Role:      "assistant",
+			Content:   completion.Content,
+			ToolCalls: completion.ToolCalls,
+		})
+
+		for _, call := range completion.ToolCalls {
+			appender.AppendResponse(ctx, o.askID, "tool_call", fmt.Sprintf("%s(%s)", call.Name, call.Arguments))
+
+			result, err := tools.call(ctx, call)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				// Tool errors go back to the model so it can correct itself
+				result = fmt.Sprintf("error: %v", err)
+			}
+			appender.AppendResponse(ctx, o.askID, "tool_result", truncate(result, maxResultSummaryLength))
+
+			messages = append(messages, llm.Message{
+				Role:       "tool",
+				Content:    result,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+
+	return "", fmt.Errorf("no SQL query generated within %d steps", maxAgentSteps)
+}
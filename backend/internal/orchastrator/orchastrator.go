@@ -4,19 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
 	"github.com/shahariaazam/smart-insights/internal/llm"
-	internalOpenAI "github.com/shahariaazam/smart-insights/internal/llm/openai" // Our internal OpenAI package
+	"github.com/shahariaazam/smart-insights/internal/llm/audit"
+	"github.com/shahariaazam/smart-insights/internal/llm/factory"
+	"github.com/shahariaazam/smart-insights/internal/llm/fallback"
+	"github.com/shahariaazam/smart-insights/internal/llm/ratelimit"
+	"github.com/shahariaazam/smart-insights/internal/llm/retry"
 	"github.com/shahariaazam/smart-insights/internal/prompt"
 	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/sqlguard"
 	"github.com/shahariaazam/smart-insights/internal/storage"
+	"github.com/shahariaazam/smart-insights/internal/usage"
 	"github.com/sirupsen/logrus"
 )
 
 // QueryResult represents the result of a database query
 type QueryResult struct {
+	Query   string      `json:"query,omitempty"`
 	Data    interface{} `json:"data"`
 	Message string      `json:"message,omitempty"`
 	Error   string      `json:"error,omitempty"`
@@ -25,76 +35,382 @@ type QueryResult struct {
 // Orchestrator coordinates the flow between different components
 type Orchestrator struct {
 	storage          storage.Storage
-	provider         llm.Provider
 	sourceDBRegistry *source.Registry
+	broker           *source.Broker
 	askID            string
 	dbConfigName     string
-	logger           *logrus.Logger
+	maxQueryAttempts int
+	mode             string
+	// dialect is the SQL dialect of the source database, known once it is connected
+	dialect string
+	// steps holds the LLM of every pipeline step, keyed by models.StepSQL etc.
+	steps  map[string]*stepLLM
+	logger *logrus.Logger
+}
+
+// StepRoute configures the LLM calls of a pipeline step
+type StepRoute struct {
+	// LLMConfigs are tried in order for every call, falling through to the next one
+	// when a config fails
+	LLMConfigs []*models.LLMConfig
+	// Model overrides the model of the configs when set
+	Model string
+	// Temperature overrides the step's default temperature when set
+	Temperature *float64
+	// MaxTokens limits the completion when positive
+	MaxTokens int
 }
 
+// stepLLM is the provider and request settings of a pipeline step
+type stepLLM struct {
+	name             string
+	provider         llm.Provider
+	structuredOutput bool
+	model            string
+	temperature      float64
+	maxTokens        int
+}
+
+// defaultTemperatures keep SQL deterministic and let the report be more creative
+var defaultTemperatures = map[string]float64{
+	models.StepSQL:    0.3,
+	models.StepRepair: 0.3,
+	models.StepReport: 0.7,
+}
+
+// defaultMaxQueryAttempts is used when no positive attempt limit is configured
+const defaultMaxQueryAttempts = 3
+
+// Limits on the conversation history sent with follow-up questions
+const (
+	maxThreadHistory       = 5
+	resultSummaryRows      = 3
+	maxResultSummaryLength = 1000
+)
+
+// partialResponseInterval is how often streamed report text is appended to the ask
+const partialResponseInterval = 250 * time.Millisecond
+
+// NewOrchestrator creates an orchestrator for an ask. routes must configure every
+// step of models.OrchestrationSteps.
 func NewOrchestrator(
 	ctx context.Context,
 	storage storage.Storage,
 	sourceRegistry *source.Registry,
-	provider string,
-	llmConfig *models.LLMConfig,
+	broker *source.Broker,
+	routes map[string]StepRoute,
 	dbConfigName string,
 	askID string,
+	maxQueryAttempts int,
+	mode string,
 	logger *logrus.Logger,
 ) (*Orchestrator, error) {
-	// Get the LLM provider
-	llmProvider, err := llm.GetProvider(provider)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get LLM provider: %w", err)
+	// Keep the API keys of every LLM config in use out of the audit log
+	var apiKeys []string
+	for _, route := range routes {
+		for _, llmConfig := range route.LLMConfigs {
+			apiKeys = append(apiKeys, llmConfig.APIKey)
+		}
 	}
 
-	// Convert LLMConfig to provider-specific config
-	var providerConfig llm.Config
-	switch provider {
-	case "openai":
-		providerConfig = internalOpenAI.NewConfig(
-			llmConfig.Name,
-			llmConfig.APIKey,
-			llmConfig.Model,
-			getMaxTokens(llmConfig.Options),
-		)
-	// Add cases for other providers here
-	default:
-		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
+	appender := source.NewResponseAppender(storage, broker)
+	llms := &llmSet{
+		redactor:     audit.NewRedactor(apiKeys...),
+		storage:      storage,
+		appender:     appender,
+		askID:        askID,
+		dbConfigName: dbConfigName,
+		logger:       logger,
+		providers:    make(map[string]llm.Provider),
+		references:   make(map[string]models.LLMReference),
+		configs:      make(map[string]*models.LLMConfig),
 	}
 
-	// Initialize the provider
-	if err := llmProvider.Initialize(ctx, providerConfig); err != nil {
-		return nil, fmt.Errorf("failed to initialize LLM provider: %w", err)
+	steps := make(map[string]*stepLLM, len(models.OrchestrationSteps))
+	for _, step := range models.OrchestrationSteps {
+		route, ok := routes[step]
+		if !ok || len(route.LLMConfigs) == 0 {
+			return nil, fmt.Errorf("no LLM configured for the %s step", step)
+		}
+
+		provider, err := llms.provider(ctx, route.LLMConfigs)
+		if err != nil {
+			return nil, err
+		}
+
+		temperature := defaultTemperatures[step]
+		if route.Temperature != nil {
+			temperature = *route.Temperature
+		}
+		steps[step] = &stepLLM{
+			name:             step,
+			provider:         provider,
+			structuredOutput: llm.SupportsStructuredOutput(provider),
+			model:            route.Model,
+			temperature:      temperature,
+			maxTokens:        route.MaxTokens,
+		}
+	}
+
+	if maxQueryAttempts <= 0 {
+		maxQueryAttempts = defaultMaxQueryAttempts
+	}
+
+	switch mode {
+	case "":
+		mode = ModePrompt
+	case ModePrompt, ModeAgent:
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
 	}
 
 	return &Orchestrator{
 		storage:          storage,
-		provider:         llmProvider,
 		sourceDBRegistry: sourceRegistry,
+		broker:           broker,
 		askID:            askID,
 		dbConfigName:     dbConfigName,
+		maxQueryAttempts: maxQueryAttempts,
+		mode:             mode,
+		steps:            steps,
 		logger:           logger,
 	}, nil
 }
 
-// Helper function for extracting max tokens from options
-func getMaxTokens(options map[string]interface{}) int {
-	if options == nil || options["max_tokens"] == nil {
-		return 3000 // Default value
+// llmSet creates the providers of an ask, sharing one provider between the steps
+// that use the same LLM configs
+type llmSet struct {
+	storage      storage.Storage
+	appender     *source.ResponseAppender
+	askID        string
+	dbConfigName string
+	logger       *logrus.Logger
+	redactor     *audit.Redactor
+
+	providers map[string]llm.Provider
+	// references identify the configs of the chain entries by entry name
+	references map[string]models.LLMReference
+	// configs are the LLM configs of the chain entries by entry name
+	configs map[string]*models.LLMConfig
+	// answeredBy is the LLM that answered the latest call of the ask
+	answeredBy models.LLMReference
+}
+
+// provider returns the provider that tries llmConfigs in order
+func (s *llmSet) provider(ctx context.Context, llmConfigs []*models.LLMConfig) (llm.Provider, error) {
+	names := make([]string, len(llmConfigs))
+	for i, llmConfig := range llmConfigs {
+		names[i] = string(llmConfig.Type) + "/" + llmConfig.Name
+	}
+	key := strings.Join(names, ",")
+	if provider, ok := s.providers[key]; ok {
+		return provider, nil
 	}
 
-	maxTokens, ok := options["max_tokens"].(float64)
-	if !ok || maxTokens == 0 {
-		return 3000 // Default value
+	entries := make([]fallback.Entry, 0, len(llmConfigs))
+	for i, llmConfig := range llmConfigs {
+		name := names[i]
+		llmProvider, err := newLLMProvider(ctx, llmConfig, func(ctx context.Context, call audit.Call) {
+			s.recordCall(ctx, name, call)
+		}, func(ctx context.Context, attempt retry.Attempt) {
+			s.appender.AppendResponse(ctx, s.askID, "debug_log", fmt.Sprintf(
+				"LLM call to %s failed (attempt %d of %d): %v. Retrying in %s",
+				name, attempt.Number, attempt.MaxAttempts, attempt.Err, attempt.Delay.Round(time.Millisecond)))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, fallback.Entry{Name: name, Provider: llmProvider})
+		s.references[name] = models.LLMReference{
+			Provider: llmConfig.Type,
+			Config:   llmConfig.Name,
+			Model:    llmConfig.Model,
+		}
+		s.configs[name] = llmConfig
 	}
 
-	return int(maxTokens)
+	provider := fallback.New(entries, fallback.Options{
+		OnFallback: func(ctx context.Context, from, to fallback.Entry, err error) {
+			s.appender.AppendResponse(ctx, s.askID, "debug_log", fmt.Sprintf(
+				"LLM %s failed: %v. Falling back to %s", from.Name, err, to.Name))
+		},
+		OnAnswer: func(ctx context.Context, entry fallback.Entry, response *llm.CompletionResponse) {
+			s.recordAnswer(ctx, entry, response)
+			s.recordUsage(ctx, entry, response)
+		},
+	})
+	s.providers[key] = provider
+	return provider, nil
+}
+
+// reference identifies the LLM config of a chain entry and the model that answered
+func (s *llmSet) reference(name string, response *llm.CompletionResponse) models.LLMReference {
+	reference := s.references[name]
+	if response == nil {
+		return reference
+	}
+	if model, ok := response.Metadata["model"].(string); ok && model != "" {
+		reference.Model = model
+	}
+	return reference
 }
 
-// Run executes the main orchestration flow
-func (o *Orchestrator) Run(ctx context.Context) { // Add context parameter
-	appender := source.NewResponseAppender(o.storage)
+// recordAnswer stores on the ask which LLM answered
+func (s *llmSet) recordAnswer(ctx context.Context, entry fallback.Entry, response *llm.CompletionResponse) {
+	reference := s.reference(entry.Name, response)
+	// Only a change of LLM is worth a write
+	if reference == s.answeredBy {
+		return
+	}
+	if err := s.appender.SetAnsweredBy(ctx, s.askID, reference); err != nil {
+		s.logger.WithError(err).Error("Failed to record the answering LLM")
+		return
+	}
+	s.answeredBy = reference
+}
+
+// recordUsage stores the tokens and cost of an LLM call, adding them to the ask
+func (s *llmSet) recordUsage(ctx context.Context, entry fallback.Entry, response *llm.CompletionResponse) {
+	reference := s.reference(entry.Name, response)
+
+	record := models.LLMUsage{
+		AskID:            s.askID,
+		Provider:         reference.Provider,
+		Config:           reference.Config,
+		Model:            reference.Model,
+		DBConfig:         s.dbConfigName,
+		Step:             stepFromContext(ctx),
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if record.TotalTokens == 0 {
+		record.TotalTokens = record.PromptTokens + record.CompletionTokens
+	}
+	if price, ok := usage.Price(s.configs[entry.Name], reference.Model); ok {
+		record.Cost = usage.Cost(price, record.PromptTokens, record.CompletionTokens)
+	} else {
+		s.logger.Warnf("No price known for model %s, recording its cost as 0", reference.Model)
+	}
+
+	if err := s.storage.SaveLLMUsage(ctx, record); err != nil {
+		s.logger.WithError(err).Error("Failed to record LLM usage")
+	}
+	if err := s.appender.AddUsage(ctx, s.askID, record); err != nil {
+		s.logger.WithError(err).Error("Failed to add LLM usage to the ask")
+	}
+}
+
+// recordCall stores the audit record of an LLM call made through a chain entry,
+// with secrets redacted
+func (s *llmSet) recordCall(ctx context.Context, name string, call audit.Call) {
+	reference := s.reference(name, call.Response)
+	if call.Response == nil && call.Request.Model != "" {
+		reference.Model = call.Request.Model
+	}
+
+	record := models.LLMCall{
+		ID:        uuid.New().String(),
+		AskID:     s.askID,
+		Step:      stepFromContext(ctx),
+		Provider:  reference.Provider,
+		Config:    reference.Config,
+		Model:     reference.Model,
+		LatencyMS: call.Latency.Milliseconds(),
+		CreatedAt: call.Started.UTC(),
+	}
+	for _, message := range call.Request.Messages {
+		record.Messages = append(record.Messages, models.LLMCallMessage{
+			Role:       message.Role,
+			Content:    s.redactor.Redact(message.Content),
+			ToolCalls:  s.redactToolCalls(message.ToolCalls),
+			ToolCallID: message.ToolCallID,
+		})
+	}
+	if call.Response != nil {
+		record.Completion = s.redactor.Redact(call.Response.Content)
+		record.ToolCalls = s.redactToolCalls(call.Response.ToolCalls)
+		record.PromptTokens = call.Response.Usage.PromptTokens
+		record.CompletionTokens = call.Response.Usage.CompletionTokens
+		record.TotalTokens = call.Response.Usage.TotalTokens
+	}
+	if call.Err != nil {
+		record.Error = s.redactor.Redact(call.Err.Error())
+	}
+
+	if err := s.storage.SaveLLMCall(ctx, record); err != nil {
+		s.logger.WithError(err).Error("Failed to record LLM call")
+	}
+}
+
+func (s *llmSet) redactToolCalls(toolCalls []llm.ToolCall) []models.LLMCallToolCall {
+	var redacted []models.LLMCallToolCall
+	for _, toolCall := range toolCalls {
+		redacted = append(redacted, models.LLMCallToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Name,
+			Arguments: s.redactor.Redact(toolCall.Arguments),
+		})
+	}
+	return redacted
+}
+
+// stepContextKey carries the pipeline step making an LLM call
+type stepContextKey struct{}
+
+// stepFromContext returns the pipeline step of an LLM call, if known
+func stepFromContext(ctx context.Context) string {
+	step, _ := ctx.Value(stepContextKey{}).(string)
+	return step
+}
+
+// newLLMProvider initializes the provider of an LLM config, retrying transient
+// failures with a circuit breaker shared by every ask that uses the config. Every
+// attempt is passed to onCall.
+func newLLMProvider(ctx context.Context, llmConfig *models.LLMConfig, onCall func(context.Context, audit.Call), onRetry func(context.Context, retry.Attempt)) (llm.Provider, error) {
+	provider := string(llmConfig.Type)
+
+	// Create the LLM provider; it is initialized with the config of the ask alone
+	llmProvider, err := factory.CreateProvider(provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM provider: %w", err)
+	}
+
+	// Convert LLMConfig to provider-specific config
+	providerConfig, err := factory.CreateConfig(
+		provider,
+		llmConfig.Name,
+		llmConfig.APIKey,
+		llmConfig.Model,
+		llmConfig.Options,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported LLM provider: %w", err)
+	}
+
+	// Initialize the provider
+	if err := llmProvider.Initialize(ctx, providerConfig); err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM provider: %w", err)
+	}
+
+	// Every attempt waits its turn on the rate limit of the config; only calls that
+	// reach the provider are audited
+	key := provider + "/" + llmConfig.Name
+	limiter := ratelimit.LimiterFor(key, ratelimit.LimitsFromOptions(llmConfig.Options))
+	limited := ratelimit.Wrap(audit.Wrap(llmProvider, onCall), limiter)
+	return retry.Wrap(limited, retry.Options{
+		Policy:  retry.DefaultPolicy,
+		Breaker: retry.BreakerFor(key),
+		OnRetry: onRetry,
+	}), nil
+}
+
+// Run executes the main orchestration flow. Cancelling ctx aborts any in-flight LLM
+// call or database query and marks the ask as cancelled.
+func (o *Orchestrator) Run(ctx context.Context) {
+	appender := source.NewResponseAppender(o.storage, o.broker)
 
 	// Initialize logging
 	o.logger.Printf("Starting orchestration for askID: %s", o.askID)
@@ -103,7 +419,7 @@ func (o *Orchestrator) Run(ctx context.Context) { // Add context parameter
 	defer func() {
 		if r := recover(); r != nil {
 			o.logger.Printf("Recovered from panic in orchestration: %v", r)
-			appender.UpdateStatus(ctx, o.askID, "failed", false)
+			appender.UpdateStatus(context.Background(), o.askID, "failed", false)
 		}
 		o.logger.Printf("Orchestration completed in %v", time.Since(startTime))
 	}()
@@ -122,29 +438,45 @@ func (o *Orchestrator) Run(ctx context.Context) { // Add context parameter
 		o.handleError(ctx, appender, "Failed to connect to database", err)
 		return
 	}
-	defer db.Close()
+	defer db.Close(context.Background())
+	o.dialect = db.Dialect()
 
-	// Step 3: Fetch database schema
+	// Step 3: Fetch database schema, which query repair needs in every mode
 	schema, err := o.fetchDatabaseSchema(ctx, db, appender)
 	if err != nil {
 		o.handleError(ctx, appender, "Failed to fetch database schema", err)
 		return
 	}
 
-	// Step 4: Generate SQL query using LLM
-	query, err := o.generateSQLQuery(ctx, schema, assistantResponse.Question, appender)
+	// Step 4: Generate SQL query using LLM, with the earlier asks of the thread as context
+	history, err := o.loadThreadHistory(ctx, assistantResponse)
+	if err != nil {
+		o.handleError(ctx, appender, "Failed to load conversation history", err)
+		return
+	}
+
+	var query string
+	if o.mode == ModeAgent {
+		query, err = o.generateSQLQueryWithTools(ctx, db, assistantResponse.Question, history, appender)
+	} else {
+		query, err = o.generateSQLQuery(ctx, schema, assistantResponse.Question, history, appender)
+	}
 	if err != nil {
 		o.handleError(ctx, appender, "Failed to generate SQL query", err)
 		return
 	}
 
-	// Step 5: Execute query
-	queryResult, err := o.executeQuery(ctx, db, query, appender)
+	// Step 5: Execute query, asking the LLM to repair it if execution fails
+	queryResult, err := o.executeQueryWithRepair(ctx, db, schema, assistantResponse.Question, query, appender)
 	if err != nil {
 		o.handleError(ctx, appender, "Failed to execute query", err)
 		return
 	}
 
+	if err := appender.SetQueryResult(ctx, o.askID, queryResult.Query, summarizeQueryResult(queryResult)); err != nil {
+		o.logger.Printf("Failed to record query result: %v", err)
+	}
+
 	// Step 6: Generate final response
 	if err := o.generateFinalResponse(ctx, appender, assistantResponse.Question, queryResult, appender); err != nil {
 		o.handleError(ctx, appender, "Failed to generate final response", err)
@@ -155,36 +487,42 @@ func (o *Orchestrator) Run(ctx context.Context) { // Add context parameter
 	appender.UpdateStatus(ctx, o.askID, "completed", true)
 }
 
-func (o *Orchestrator) generateSQLQuery(ctx context.Context, schema string, question string, appender *source.ResponseAppender) (string, error) {
+func (o *Orchestrator) generateSQLQuery(ctx context.Context, schema string, question string, history []models.AssistantResponse, appender *source.ResponseAppender) (string, error) {
+	step := o.steps[models.StepSQL]
 	appender.AppendResponse(ctx, o.askID, "step_output", "Generating SQL query... please wait")
 
 	payload := prompt.LLMPayload{
-		DBSchema: schema,
-		Question: question,
+		Dialect:          o.dialect,
+		DBSchema:         schema,
+		Question:         question,
+		StructuredOutput: step.structuredOutput,
+	}
+
+	systemPrompt := prompt.SystemPrompt(o.dialect, "generates SQL queries based on natural language questions")
+	if len(history) > 0 {
+		systemPrompt += " Earlier questions of this conversation are included with the queries that answered them; the new question may refer to them."
 	}
 
 	messages := []llm.Message{
 		{
 			Role:    "system",
-			Content: "You are a PostgreSQL expert who generates SQL queries based on natural language questions.",
-		},
-		{
-			Role:    "user",
-			Content: payload.InitialPrompt(),
+			Content: systemPrompt,
 		},
 	}
-
-	completion, err := o.provider.Complete(ctx, llm.CompletionRequest{
-		Messages:    messages,
-		Temperature: 0.3, // Lower temperature for more deterministic SQL generation
+	messages = append(messages, historyMessages(o.dialect, history)...)
+	messages = append(messages, llm.Message{
+		Role:    "user",
+		Content: payload.InitialPrompt(),
 	})
+
+	completion, err := step.complete(ctx, step.request(messages, step.queryResponseFormat(o.dialect)))
 	if err != nil {
 		return "", fmt.Errorf("failed to generate SQL query: %w", err)
 	}
 
-	query := prompt.ExtractResponse("sql", completion.Content)
-	if query == "" {
-		return "", fmt.Errorf("no SQL query found in LLM response")
+	query, err := prompt.ParseQuery(o.dialect, completion.Content)
+	if err != nil {
+		return "", err
 	}
 
 	err = appender.AppendResponse(ctx, o.askID, "step_output", query)
@@ -195,15 +533,209 @@ func (o *Orchestrator) generateSQLQuery(ctx context.Context, schema string, ques
 	return query, nil
 }
 
+// complete sends a completion request on behalf of the step
+func (s *stepLLM) complete(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return s.provider.Complete(context.WithValue(ctx, stepContextKey{}, s.name), request)
+}
+
+// completeStream streams a completion on behalf of the step
+func (s *stepLLM) completeStream(ctx context.Context, request llm.CompletionRequest, handler llm.StreamHandler) (*llm.CompletionResponse, error) {
+	return llm.CompleteStream(context.WithValue(ctx, stepContextKey{}, s.name), s.provider, request, handler)
+}
+
+// request builds a completion request with the settings of the step
+func (s *stepLLM) request(messages []llm.Message, responseFormat *llm.ResponseFormat) llm.CompletionRequest {
+	return llm.CompletionRequest{
+		Messages:       messages,
+		Model:          s.model,
+		Temperature:    s.temperature,
+		MaxTokens:      s.maxTokens,
+		ResponseFormat: responseFormat,
+	}
+}
+
+// responseFormat asks providers with structured output for a JSON object matching
+// schema. Other providers answer in tags, so no format is requested from them.
+func (s *stepLLM) responseFormat(name, description string, schema map[string]interface{}) *llm.ResponseFormat {
+	if !s.structuredOutput {
+		return nil
+	}
+	return &llm.ResponseFormat{
+		Name:        name,
+		Description: description,
+		Schema:      schema,
+	}
+}
+
+func (s *stepLLM) sqlResponseFormat() *llm.ResponseFormat {
+	return s.responseFormat("sql_query", "SQL query answering the question", prompt.SQLResultSchema)
+}
+
+// queryResponseFormat is the response format of the query steps for a database of
+// the given dialect
+func (s *stepLLM) queryResponseFormat(dialect string) *llm.ResponseFormat {
+	if dialect == prompt.MongoDB {
+		return s.responseFormat("aggregation_pipeline", "MongoDB aggregation pipeline answering the question", prompt.PipelineResultSchema)
+	}
+	return s.sqlResponseFormat()
+}
+
+// historyMessages turns the earlier asks of a thread into question and answer turns,
+// answered in the same form the dialect's prompt asks for
+func historyMessages(dialect string, history []models.AssistantResponse) []llm.Message {
+	tag := "sql"
+	if dialect == prompt.MongoDB {
+		tag = "pipeline"
+	}
+
+	var messages []llm.Message
+	for _, previous := range history {
+		messages = append(messages,
+			llm.Message{
+				Role:    "user",
+				Content: previous.Question,
+			},
+			llm.Message{
+				Role:    "assistant",
+				Content: fmt.Sprintf("<%s>\n%s\n</%s>\n\nResult: %s", tag, previous.SQLQuery, tag, previous.ResultSummary),
+			},
+		)
+	}
+	return messages
+}
+
+// loadThreadHistory returns the earlier asks of the thread that produced a query,
+// keeping only the most recent ones
+func (o *Orchestrator) loadThreadHistory(ctx context.Context, current *models.AssistantResponse) ([]models.AssistantResponse, error) {
+	if current.ThreadID == "" {
+		return nil, nil
+	}
+
+	thread, err := o.storage.GetThreadResponses(ctx, current.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []models.AssistantResponse
+	for _, response := range thread {
+		if response.Sequence < current.Sequence && response.SQLQuery != "" {
+			history = append(history, response)
+		}
+	}
+	if len(history) > maxThreadHistory {
+		history = history[len(history)-maxThreadHistory:]
+	}
+	return history, nil
+}
+
+// summarizeQueryResult describes a query result briefly enough to be sent with every
+// follow-up question: the row count and the first few rows
+func summarizeQueryResult(result *QueryResult) string {
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		return ""
+	}
+
+	var rows []json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return truncate(string(data), maxResultSummaryLength)
+	}
+
+	sample := rows
+	if len(sample) > resultSummaryRows {
+		sample = sample[:resultSummaryRows]
+	}
+	sampleJSON, _ := json.Marshal(sample)
+
+	return truncate(fmt.Sprintf("%d row(s), first rows: %s", len(rows), sampleJSON), maxResultSummaryLength)
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
+
+// executeQueryWithRepair runs the query and, when the database rejects it, feeds the
+// error, the failed SQL and the schema back to the LLM for a corrected query. Every
+// attempt and every failure is recorded as its own update on the ask.
+func (o *Orchestrator) executeQueryWithRepair(ctx context.Context, db dbinterface.Provider, schema string, question string, query string, appender *source.ResponseAppender) (*QueryResult, error) {
+	var lastErr error
+	for attempt := 1; attempt <= o.maxQueryAttempts; attempt++ {
+		appender.AppendResponse(ctx, o.askID, "query_attempt", fmt.Sprintf("Attempt %d of %d:\n%s", attempt, o.maxQueryAttempts, query))
+
+		result, err := o.executeQuery(ctx, db, query, appender)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		appender.AppendResponse(ctx, o.askID, "query_error", fmt.Sprintf("Attempt %d failed: %v", attempt, err))
+
+		// Don't ask for a repair that will never be executed, or when the ask is gone
+		if attempt == o.maxQueryAttempts || ctx.Err() != nil {
+			break
+		}
+
+		query, err = o.repairSQLQuery(ctx, schema, question, query, err, appender)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("query failed after %d attempt(s): %w", o.maxQueryAttempts, lastErr)
+}
+
+// repairSQLQuery asks the LLM to correct a query that failed to execute
+func (o *Orchestrator) repairSQLQuery(ctx context.Context, schema string, question string, failedQuery string, queryErr error, appender *source.ResponseAppender) (string, error) {
+	appender.AppendResponse(ctx, o.askID, "step_output", "Query failed, asking the LLM to correct it...")
+
+	step := o.steps[models.StepRepair]
+	payload := prompt.LLMPayload{
+		Dialect:          o.dialect,
+		DBSchema:         schema,
+		Question:         question,
+		InitialQuery:     failedQuery,
+		QueryError:       queryErr.Error(),
+		StructuredOutput: step.structuredOutput,
+	}
+
+	messages := []llm.Message{
+		{
+			Role:    "system",
+			Content: prompt.SystemPrompt(o.dialect, "fixes SQL queries that failed to execute"),
+		},
+		{
+			Role:    "user",
+			Content: payload.RepairQueryPrompt(),
+		},
+	}
+
+	completion, err := step.complete(ctx, step.request(messages, step.queryResponseFormat(o.dialect)))
+	if err != nil {
+		return "", fmt.Errorf("failed to repair SQL query: %w", err)
+	}
+
+	query, err := prompt.ParseQuery(o.dialect, completion.Content)
+	if err != nil {
+		return "", fmt.Errorf("invalid LLM repair response: %w", err)
+	}
+
+	return query, nil
+}
+
 func (o *Orchestrator) generateFinalResponse(ctx context.Context, appender *source.ResponseAppender, question string, queryResult *QueryResult, responseAppender *source.ResponseAppender) error {
 	resultJSON, err := json.Marshal(queryResult.Data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal query result: %w", err)
 	}
 
+	step := o.steps[models.StepReport]
 	payload := prompt.LLMPayload{
-		Question:        question,
-		QueryResultJSON: string(resultJSON),
+		Question:         question,
+		QueryResultJSON:  string(resultJSON),
+		StructuredOutput: step.structuredOutput,
 	}
 
 	messages := []llm.Message{
@@ -219,20 +751,45 @@ func (o *Orchestrator) generateFinalResponse(ctx context.Context, appender *sour
 
 	appender.AppendResponse(ctx, o.askID, "step_output", "Generating report...")
 
-	completion, err := o.provider.Complete(ctx, llm.CompletionRequest{
-		Messages:    messages,
-		Temperature: 0.7, // Higher temperature for more creative explanations
+	// Stream the report into the ask as it is written, batching deltas so storage is
+	// not rewritten for every token
+	var extractor prompt.StreamExtractor = prompt.NewTagStream("markdown")
+	if step.structuredOutput {
+		extractor = prompt.NewJSONFieldStream("markdown")
+	}
+	var partial strings.Builder
+	lastFlush := time.Now()
+	flush := func() error {
+		if partial.Len() == 0 {
+			return nil
+		}
+		text := partial.String()
+		partial.Reset()
+		lastFlush = time.Now()
+		return appender.AppendResponse(ctx, o.askID, "partial_response", text)
+	}
+
+	request := step.request(messages, step.responseFormat("report", "Markdown report explaining the query results", prompt.ReportResultSchema))
+	completion, err := step.completeStream(ctx, request, func(delta string) error {
+		partial.WriteString(extractor.Write(delta))
+		if time.Since(lastFlush) < partialResponseInterval {
+			return nil
+		}
+		return flush()
 	})
 	if err != nil {
 		return fmt.Errorf("failed to generate report: %w", err)
 	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to append partial response: %w", err)
+	}
 
-	markdown := prompt.ExtractResponse("markdown", completion.Content)
-	if markdown == "" {
-		return fmt.Errorf("no markdown content found in LLM response")
+	report, err := prompt.ParseReportResult(completion.Content)
+	if err != nil {
+		return err
 	}
 
-	if err := appender.AppendResponse(ctx, o.askID, "final_response", markdown); err != nil {
+	if err := appender.AppendResponse(ctx, o.askID, "final_response", report.Markdown); err != nil {
 		return fmt.Errorf("failed to append final response: %w", err)
 	}
 
@@ -240,11 +797,25 @@ func (o *Orchestrator) generateFinalResponse(ctx context.Context, appender *sour
 }
 
 func (o *Orchestrator) handleError(ctx context.Context, appender *source.ResponseAppender, message string, err error) {
+	if ctx.Err() == context.Canceled {
+		o.handleCancellation(appender)
+		return
+	}
+
 	o.logger.Printf("Error: %s: %v", message, err)
 	appender.AppendResponse(ctx, o.askID, "error", fmt.Sprintf("%s: %v", message, err))
 	appender.UpdateStatus(ctx, o.askID, "failed", false)
 }
 
+// handleCancellation records that the ask was cancelled. The ask's own context is
+// already done at this point, so the final writes use a fresh one.
+func (o *Orchestrator) handleCancellation(appender *source.ResponseAppender) {
+	o.logger.Printf("Orchestration cancelled for askID: %s", o.askID)
+	ctx := context.Background()
+	appender.AppendResponse(ctx, o.askID, "step_output", "Request cancelled")
+	appender.UpdateStatus(ctx, o.askID, "cancelled", false)
+}
+
 func (o *Orchestrator) loadAssistantResponse(ctx context.Context) (*models.AssistantResponse, error) {
 	response, err := o.storage.LoadAssistantResponse(ctx, o.askID)
 	if err != nil {
@@ -253,23 +824,54 @@ func (o *Orchestrator) loadAssistantResponse(ctx context.Context) (*models.Assis
 	return response, nil
 }
 
-func (o *Orchestrator) connectToDatabase(ctx context.Context, appender *source.ResponseAppender) (source.DatabaseConnector, error) {
-	connectDB, err := o.sourceDBRegistry.LoadSource(o.dbConfigName, appender)
+func (o *Orchestrator) connectToDatabase(ctx context.Context, appender *source.ResponseAppender) (dbinterface.Provider, error) {
+	connectDB, err := o.sourceDBRegistry.LoadSource(ctx, o.dbConfigName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to source database: %w", err)
 	}
 	return connectDB, nil
 }
 
-func (o *Orchestrator) fetchDatabaseSchema(ctx context.Context, db source.DatabaseConnector, appender *source.ResponseAppender) (string, error) {
-	schemaStr, err := db.GetSchema(ctx, o.askID)
+// fetchDatabaseSchema reads the schema of the source database and describes it for
+// the prompts
+func (o *Orchestrator) fetchDatabaseSchema(ctx context.Context, db dbinterface.Provider, appender *source.ResponseAppender) (string, error) {
+	appender.AppendResponse(ctx, o.askID, "step_output", "Fetching database schema...")
+
+	schema, err := db.GetSchema(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get schema: %w", err)
 	}
-	return schemaStr, nil
+
+	appender.AppendResponse(ctx, o.askID, "debug_log", fmt.Sprintf("Processed tables: %v", strings.Join(schemaNames(schema), ", ")))
+
+	appender.AppendResponse(ctx, o.askID, "step_output", "Schema retrieval completed")
+	return prompt.FormatSchema(db.Dialect(), schema), nil
+}
+
+// schemaNames lists the tables and views of schema
+func schemaNames(schema *dbinterface.SchemaInfo) []string {
+	names := make([]string, 0, len(schema.Tables)+len(schema.Views))
+	for _, table := range schema.Tables {
+		names = append(names, table.Name)
+	}
+	for _, view := range schema.Views {
+		names = append(names, view.Name)
+	}
+	return names
 }
 
-func (o *Orchestrator) executeQuery(ctx context.Context, db source.DatabaseConnector, query string, appender *source.ResponseAppender) (*QueryResult, error) {
+func (o *Orchestrator) executeQuery(ctx context.Context, db dbinterface.Provider, query string, appender *source.ResponseAppender) (*QueryResult, error) {
+	// Never send anything but a single read-only query to the source database
+	var err error
+	if db.Dialect() == prompt.MongoDB {
+		err = sqlguard.ValidatePipeline(query)
+	} else {
+		err = sqlguard.Validate(db.Dialect(), query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query failed validation: %w", err)
+	}
+
 	result, err := db.ExecuteQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
@@ -278,6 +880,7 @@ func (o *Orchestrator) executeQuery(ctx context.Context, db source.DatabaseConne
 	appender.AppendResponse(ctx, o.askID, "step_output", "Query executed successfully")
 
 	return &QueryResult{
-		Data: result,
+		Query: query,
+		Data:  result.Rows,
 	}, nil
 }
@@ -0,0 +1,575 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/llm"
+	"github.com/shahariaazam/smart-insights/internal/llm/factory"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/shahariaazam/smart-insights/internal/source"
+	"github.com/shahariaazam/smart-insights/internal/sqlguard"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeAll routes every pipeline step to the given LLM configs
+func routeAll(llmConfigs ...*models.LLMConfig) map[string]StepRoute {
+	routes := make(map[string]StepRoute)
+	for _, step := range models.OrchestrationSteps {
+		routes[step] = StepRoute{LLMConfigs: llmConfigs}
+	}
+	return routes
+}
+
+// TestFakeProviderSteps runs the SQL and report steps against the fake provider,
+// configured through storage like any other LLM
+func TestFakeProviderSteps(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	llmConfig := models.LLMConfig{
+		Name:  "scripted",
+		Type:  factory.FakeProviderType,
+		Model: "fake-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				// The report prompt repeats the question, so its rule comes first
+				map[string]interface{}{
+					"pattern":  `Query Results`,
+					"response": "<markdown>There are **42** users.</markdown>",
+				},
+				map[string]interface{}{
+					"pattern":  `User Question: How many (\w+)`,
+					"response": "<sql>SELECT count(*) FROM $1</sql>",
+				},
+			},
+		},
+	}
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, llmConfig))
+
+	askID := "3f1c6a2e-8b9d-4e7f-a1b2-c3d4e5f60718"
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+		UUID:     askID,
+		Question: "How many users are there?",
+		Status:   "in_progress",
+	}))
+
+	broker := source.NewBroker()
+	o, err := NewOrchestrator(ctx, store, nil, broker, routeAll(&llmConfig), "db", askID, 0, "", logrus.New())
+	require.NoError(t, err)
+
+	appender := source.NewResponseAppender(store, broker)
+	query, err := o.generateSQLQuery(ctx, "users(id integer)", "How many users are there?", nil, appender)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT count(*) FROM users", query)
+
+	err = o.generateFinalResponse(ctx, appender, "How many users are there?", &QueryResult{
+		Query: query,
+		Data:  []map[string]interface{}{{"count": 42}},
+	}, appender)
+	require.NoError(t, err)
+
+	response, err := store.LoadAssistantResponse(ctx, askID)
+	require.NoError(t, err)
+
+	var final string
+	for _, update := range response.Response {
+		if update.Type == "final_response" {
+			final = update.Text
+		}
+	}
+	assert.Equal(t, "There are **42** users.", final)
+}
+
+// TestDialect asks for queries in the dialect of the connected database
+func TestDialect(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	llmConfig := models.LLMConfig{
+		Name:  "scripted",
+		Type:  factory.FakeProviderType,
+		Model: "fake-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"pattern":  `MySQL syntax`,
+					"response": "<sql>SELECT count(*) FROM `users`</sql>",
+				},
+			},
+		},
+	}
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, llmConfig))
+
+	askID := "5d2e7f3a-1b4c-4d6e-8f9a-0b1c2d3e4f50"
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+		UUID:     askID,
+		Question: "How many users are there?",
+		Status:   "in_progress",
+	}))
+
+	broker := source.NewBroker()
+	o, err := NewOrchestrator(ctx, store, nil, broker, routeAll(&llmConfig), "db", askID, 0, "", logrus.New())
+	require.NoError(t, err)
+	o.dialect = prompt.MySQL
+
+	query, err := o.generateSQLQuery(ctx, "users(id int)", "How many users are there?", nil, source.NewResponseAppender(store, broker))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT count(*) FROM `users`", query)
+
+	calls, err := store.GetLLMCalls(ctx, askID)
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "You are a MySQL expert who generates SQL queries based on natural language questions.", calls[0].Messages[0].Content)
+}
+
+// TestPipelineDialect asks a MongoDB source for an aggregation pipeline and
+// validates it as one
+func TestPipelineDialect(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	llmConfig := models.LLMConfig{
+		Name:  "scripted",
+		Type:  factory.FakeProviderType,
+		Model: "fake-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"pattern": `aggregation pipeline`,
+					// $$ escapes the dollar from the fake provider's group expansion
+					"response": `<pipeline>{"collection": "users", "pipeline": [{"$$count": "count"}]}</pipeline>`,
+				},
+			},
+		},
+	}
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, llmConfig))
+
+	askID := "7b1c9e2d-3f4a-4b5c-8d6e-1a2b3c4d5e60"
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+		UUID:     askID,
+		Question: "How many users are there?",
+		Status:   "in_progress",
+	}))
+
+	broker := source.NewBroker()
+	o, err := NewOrchestrator(ctx, store, nil, broker, routeAll(&llmConfig), "db", askID, 0, "", logrus.New())
+	require.NoError(t, err)
+	o.dialect = prompt.MongoDB
+
+	query, err := o.generateSQLQuery(ctx, "Collection: users", "How many users are there?", nil, source.NewResponseAppender(store, broker))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"collection": "users", "pipeline": [{"$count": "count"}]}`, query)
+	assert.NoError(t, sqlguard.ValidatePipeline(query))
+}
+
+// TestFallbackChain falls through to the second LLM when the first cannot answer
+// and records which one did
+func TestFallbackChain(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	primary := models.LLMConfig{
+		Name:  "primary",
+		Type:  factory.FakeProviderType,
+		Model: "primary-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"pattern": `^never$`, "response": "unused"},
+			},
+		},
+	}
+	secondary := models.LLMConfig{
+		Name:   "secondary",
+		Type:   factory.FakeProviderType,
+		APIKey: "secondary-api-key",
+		Model:  "secondary-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"pattern": `How many (\w+)`, "response": "<sql>SELECT count(*) FROM $1</sql>"},
+			},
+		},
+	}
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, primary))
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, secondary))
+
+	askID := "5a2e7c1d-9f3b-4d6a-8e2c-1b0f9a8d7c6e"
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+		UUID:     askID,
+		Question: "How many users are there?",
+		Status:   "in_progress",
+	}))
+
+	broker := source.NewBroker()
+	o, err := NewOrchestrator(ctx, store, nil, broker, routeAll(&primary, &secondary), "db", askID, 0, "", logrus.New())
+	require.NoError(t, err)
+
+	// A question leaking a key must not leak it into the audit log
+	question := "How many users are there? The key is secondary-api-key"
+	query, err := o.generateSQLQuery(ctx, "users(id integer)", question, nil, source.NewResponseAppender(store, broker))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT count(*) FROM users", query)
+
+	calls, err := store.GetLLMCalls(ctx, askID)
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "primary", calls[0].Config)
+	assert.Equal(t, models.StepSQL, calls[0].Step)
+	assert.NotEmpty(t, calls[0].Error)
+	assert.Empty(t, calls[0].Completion)
+	assert.Equal(t, "secondary", calls[1].Config)
+	assert.Empty(t, calls[1].Error)
+	assert.Equal(t, "<sql>SELECT count(*) FROM users</sql>", calls[1].Completion)
+	assert.Positive(t, calls[1].TotalTokens)
+	require.NotEmpty(t, calls[1].Messages)
+	assert.Equal(t, "system", calls[1].Messages[0].Role)
+	for _, message := range calls[1].Messages {
+		assert.NotContains(t, message.Content, "secondary-api-key")
+	}
+	assert.Contains(t, calls[1].Messages[len(calls[1].Messages)-1].Content, "The key is [REDACTED]")
+
+	response, err := store.LoadAssistantResponse(ctx, askID)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LLMReference{
+		Provider: factory.FakeProviderType,
+		Config:   "secondary",
+		Model:    "secondary-model",
+	}, response.AnsweredBy)
+
+	var fellBack bool
+	for _, update := range response.Response {
+		if update.Type == "debug_log" && strings.Contains(update.Text, "Falling back to fake/secondary") {
+			fellBack = true
+		}
+	}
+	assert.True(t, fellBack)
+}
+
+// TestStepRouting sends the SQL and report steps to different LLMs and models
+func TestStepRouting(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	sqlConfig := models.LLMConfig{
+		Name:    "strong",
+		Type:    factory.FakeProviderType,
+		Model:   "strong-model",
+		Pricing: &models.LLMPricing{InputPerMillion: 1e6, OutputPerMillion: 2e6},
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"pattern": `User Question: How many (\w+)`, "response": "<sql>SELECT count(*) FROM $1</sql>"},
+			},
+		},
+	}
+	reportConfig := models.LLMConfig{
+		Name:  "cheap",
+		Type:  factory.FakeProviderType,
+		Model: "cheap-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"pattern": `Query Results`, "response": "<markdown>There are **42** users.</markdown>"},
+			},
+		},
+	}
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, sqlConfig))
+	require.NoError(t, store.SaveLLMConfig(ctx, factory.FakeProviderType, reportConfig))
+
+	askID := "8c4b2a1f-3e5d-4f6a-9b7c-0d1e2f3a4b5c"
+	require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+		UUID:     askID,
+		Question: "How many users are there?",
+		Status:   "in_progress",
+	}))
+
+	temperature := 0.0
+	routes := map[string]StepRoute{
+		models.StepSQL:    {LLMConfigs: []*models.LLMConfig{&sqlConfig}, Model: "strong-model-latest", Temperature: &temperature},
+		models.StepRepair: {LLMConfigs: []*models.LLMConfig{&sqlConfig}},
+		models.StepReport: {LLMConfigs: []*models.LLMConfig{&reportConfig}, MaxTokens: 500},
+	}
+
+	broker := source.NewBroker()
+	o, err := NewOrchestrator(ctx, store, nil, broker, routes, "db", askID, 0, "", logrus.New())
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, o.steps[models.StepSQL].temperature)
+	assert.Equal(t, 0.3, o.steps[models.StepRepair].temperature)
+	assert.Equal(t, 0.7, o.steps[models.StepReport].temperature)
+	assert.Same(t, o.steps[models.StepSQL].provider, o.steps[models.StepRepair].provider)
+
+	answeredBy := func() *models.LLMReference {
+		response, err := store.LoadAssistantResponse(ctx, askID)
+		require.NoError(t, err)
+		return response.AnsweredBy
+	}
+
+	appender := source.NewResponseAppender(store, broker)
+	query, err := o.generateSQLQuery(ctx, "users(id integer)", "How many users are there?", nil, appender)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LLMReference{Provider: factory.FakeProviderType, Config: "strong", Model: "strong-model-latest"}, answeredBy())
+
+	err = o.generateFinalResponse(ctx, appender, "How many users are there?", &QueryResult{
+		Query: query,
+		Data:  []map[string]interface{}{{"count": 42}},
+	}, appender)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LLMReference{Provider: factory.FakeProviderType, Config: "cheap", Model: "cheap-model"}, answeredBy())
+
+	// Every call is recorded with its step and priced by its config
+	records, err := store.GetLLMUsage(ctx, models.UsageFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, models.StepSQL, records[0].Step)
+	assert.Equal(t, "strong-model-latest", records[0].Model)
+	assert.Equal(t, "db", records[0].DBConfig)
+	assert.Greater(t, records[0].PromptTokens, 0)
+	assert.Equal(t, float64(records[0].PromptTokens+2*records[0].CompletionTokens), records[0].Cost)
+	assert.Equal(t, models.StepReport, records[1].Step)
+	assert.Equal(t, "cheap", records[1].Config)
+	assert.Zero(t, records[1].Cost)
+
+	response, err := store.LoadAssistantResponse(ctx, askID)
+	require.NoError(t, err)
+	require.NotNil(t, response.Usage)
+	assert.Equal(t, 2, response.Usage.Calls)
+	assert.Equal(t, records[0].TotalTokens+records[1].TotalTokens, response.Usage.TotalTokens)
+	assert.Equal(t, records[0].Cost, response.Usage.Cost)
+
+	_, err = NewOrchestrator(ctx, store, nil, broker, map[string]StepRoute{
+		models.StepSQL: {LLMConfigs: []*models.LLMConfig{&sqlConfig}},
+	}, "db", askID, 0, "", logrus.New())
+	assert.ErrorContains(t, err, "no LLM configured for the repair step")
+}
+
+// TestProviderPerConfig initializes the provider of an ask from its own config, so
+// a broken config of the same type does not affect it
+func TestProviderPerConfig(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	llm.Initialize(store)
+
+	require.NoError(t, store.SaveLLMConfig(ctx, string(models.OpenAICompatible), models.LLMConfig{
+		Name:  "broken",
+		Type:  models.OpenAICompatible,
+		Model: "llama3",
+	}))
+	gateway := models.LLMConfig{
+		Name:    "gateway",
+		Type:    models.OpenAICompatible,
+		Model:   "llama3",
+		Options: map[string]interface{}{"base_url": "http://localhost:11434/v1"},
+	}
+
+	_, err := NewOrchestrator(ctx, store, nil, source.NewBroker(), routeAll(&gateway), "db", "", 0, "", logrus.New())
+	assert.NoError(t, err)
+}
+
+// TestRun answers a follow-up question from a SQLite file end to end: the SQL step
+// records its completion through a flaky gateway into a cassette, the guard rejects
+// the query, the repair falls back to a second LLM and the report is streamed. The
+// same ask is then answered again from the cassette alone.
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shop.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT NOT NULL, total REAL);
+INSERT INTO orders VALUES (1, 'Ada', 250.5), (2, 'Ada', 20), (3, 'Grace', 120);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The gateway is overloaded on the first call, then answers with a query the guard
+	// must reject
+	var gatewayCalls atomic.Int32
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if gatewayCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "overloaded"}`))
+			return
+		}
+		w.Write([]byte(`{"text": "<sql>SELECT sum(total) AS revenue FROM orders; DELETE FROM orders</sql>"}`))
+	}))
+	defer gateway.Close()
+
+	cassette := filepath.Join(t.TempDir(), "sql.json")
+	recorder := models.LLMConfig{
+		Name:  "recorder",
+		Type:  factory.FakeProviderType,
+		Model: "house-model",
+		Options: map[string]interface{}{
+			"mode":     "cassette",
+			"cassette": cassette,
+			"upstream": map[string]interface{}{
+				"type":  "http",
+				"model": "house-model",
+				"options": map[string]interface{}{
+					"url":              gateway.URL,
+					"request_template": `{"prompt": {{json .Prompt}}}`,
+					"content_path":     "$.text",
+					"error_path":       "$.error",
+				},
+			},
+		},
+	}
+	offline := models.LLMConfig{
+		Name:  "offline",
+		Type:  factory.FakeProviderType,
+		Model: "offline-model",
+		Options: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"pattern": `^never$`, "response": "unused"},
+			},
+		},
+	}
+	scripted := models.LLMConfig{
+		Name:  "scripted",
+		Type:  factory.FakeProviderType,
+		Model: "fake-model",
+		Options: map[string]interface{}{
+			"requests_per_minute": 600,
+			"rules": []interface{}{
+				map[string]interface{}{
+					"pattern":  `multiple_statements`,
+					"response": "<sql>SELECT count(*) AS orders, sum(total) AS revenue FROM orders</sql>",
+				},
+				map[string]interface{}{
+					"pattern":  `Query Results`,
+					"response": "<markdown>The **3** orders brought in **$$390.5**.</markdown>",
+				},
+			},
+		},
+	}
+
+	run := func(sqlConfig *models.LLMConfig) (*models.AssistantResponse, []models.LLMCall) {
+		store := memory.NewMemoryStorage()
+		llm.Initialize(store)
+		require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "shop", Type: models.SQLite, Path: path}))
+		registry := source.NewRegistry(store)
+		defer registry.Close()
+
+		threadID := "0b7e4c2a-6d1f-4a8e-9c3b-5f2d1e0a9b8c"
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:          "2e9d4b1a-7c3f-4e5d-8a6b-0c1d2e3f4a5b",
+			Question:      "How many orders are there?",
+			Status:        "completed",
+			ThreadID:      threadID,
+			Sequence:      1,
+			SQLQuery:      "SELECT count(*) AS orders FROM orders",
+			ResultSummary: `1 row(s), first rows: [{"orders":3}]`,
+		}))
+		askID := "9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a"
+		require.NoError(t, store.SaveAssistantResponse(ctx, models.AssistantResponse{
+			UUID:     askID,
+			Question: "And how much revenue did they bring in?",
+			Status:   "in_progress",
+			ThreadID: threadID,
+			Sequence: 2,
+		}))
+
+		routes := routeAll(&offline, &scripted)
+		routes[models.StepSQL] = StepRoute{LLMConfigs: []*models.LLMConfig{sqlConfig}}
+		routes[models.StepReport] = StepRoute{LLMConfigs: []*models.LLMConfig{&scripted}}
+		o, err := NewOrchestrator(ctx, store, registry, source.NewBroker(), routes, "shop", askID, 0, "", logrus.New())
+		require.NoError(t, err)
+		o.Run(ctx)
+
+		response, err := store.LoadAssistantResponse(ctx, askID)
+		require.NoError(t, err)
+		calls, err := store.GetLLMCalls(ctx, askID)
+		require.NoError(t, err)
+		return response, calls
+	}
+
+	updates := func(response *models.AssistantResponse, updateType string) []string {
+		var texts []string
+		for _, update := range response.Response {
+			if update.Type == updateType {
+				texts = append(texts, update.Text)
+			}
+		}
+		return texts
+	}
+
+	repaired := "SELECT count(*) AS orders, sum(total) AS revenue FROM orders"
+	assertAnswered := func(response *models.AssistantResponse) {
+		assert.Equal(t, "completed", response.Status)
+		assert.True(t, response.Success)
+		assert.Empty(t, updates(response, "error"))
+
+		// The guard rejects the generated query before it reaches the database, and
+		// the repaired query answers the question
+		assert.Equal(t, []string{
+			"Attempt 1 of 3:\nSELECT sum(total) AS revenue FROM orders; DELETE FROM orders",
+			"Attempt 2 of 3:\n" + repaired,
+		}, updates(response, "query_attempt"))
+		queryErrors := updates(response, "query_error")
+		require.Len(t, queryErrors, 1)
+		assert.Contains(t, queryErrors[0], "multiple_statements")
+		assert.Equal(t, repaired, response.SQLQuery)
+		assert.Equal(t, `1 row(s), first rows: [{"orders":3,"revenue":390.5}]`, response.ResultSummary)
+
+		// The report is streamed before it is final
+		final := updates(response, "final_response")
+		assert.Equal(t, []string{"The **3** orders brought in **$390.5**."}, final)
+		assert.Equal(t, final[0], strings.Join(updates(response, "partial_response"), ""))
+		assert.Equal(t, &models.LLMReference{Provider: factory.FakeProviderType, Config: "scripted", Model: "fake-model"}, response.AnsweredBy)
+	}
+
+	response, calls := run(&recorder)
+	assertAnswered(response)
+	assert.Equal(t, int32(2), gatewayCalls.Load())
+
+	// The overloaded gateway is retried, the offline LLM falls back and every
+	// attempt is audited
+	debugLog := strings.Join(updates(response, "debug_log"), "\n")
+	assert.Contains(t, debugLog, "LLM call to fake/recorder failed (attempt 1 of 4)")
+	assert.Contains(t, debugLog, "Falling back to fake/scripted")
+	require.Len(t, calls, 5)
+	steps := make([]string, len(calls))
+	for i, call := range calls {
+		steps[i] = call.Step + " " + call.Config
+	}
+	assert.Equal(t, []string{
+		"sql recorder", "sql recorder", "repair offline", "repair scripted", "report scripted",
+	}, steps)
+	assert.NotEmpty(t, calls[0].Error)
+	assert.Empty(t, calls[1].Error)
+
+	// The earlier question of the thread and its query are sent with the new one
+	var history []string
+	for _, message := range calls[1].Messages {
+		history = append(history, message.Role+": "+message.Content)
+	}
+	assert.Contains(t, history, "user: How many orders are there?")
+	assert.Contains(t, strings.Join(history, "\n"), "SELECT count(*) AS orders FROM orders")
+
+	// Replaying the cassette answers the same ask without the gateway
+	gateway.Close()
+	replay := models.LLMConfig{
+		Name:    "recorder",
+		Type:    factory.FakeProviderType,
+		Model:   "house-model",
+		Options: map[string]interface{}{"mode": "cassette", "cassette": cassette},
+	}
+	response, calls = run(&replay)
+	assertAnswered(response)
+	assert.Equal(t, int32(2), gatewayCalls.Load())
+	require.Len(t, calls, 4)
+	assert.Empty(t, calls[0].Error)
+}
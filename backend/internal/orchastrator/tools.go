@@ -0,0 +1,294 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/llm"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/shahariaazam/smart-insights/internal/sqlguard"
+)
+
+// Limits on what the schema tools return to the model
+const (
+	defaultSampleRows   = 5
+	maxSampleRows       = 20
+	maxToolQueryRows    = 50
+	maxToolResultLength = 8000
+)
+
+// schemaTools lets the model explore the source database through tool calls
+// instead of receiving the whole schema up front
+type schemaTools struct {
+	db     dbinterface.Provider
+	schema *dbinterface.SchemaInfo
+}
+
+func newSchemaTools(db dbinterface.Provider) *schemaTools {
+	return &schemaTools{db: db}
+}
+
+// definitions returns the tools offered to the model
+func (t *schemaTools) definitions() []llm.Tool {
+	tableParameter := map[string]interface{}{
+		"type":        "string",
+		"description": "Name of the table or view",
+	}
+
+	return []llm.Tool{
+		{
+			Name:        "list_tables",
+			Description: "List the tables and views of the database.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "describe_table",
+			Description: "Describe the columns, primary key and foreign keys of a table or view.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"table": tableParameter,
+				},
+				"required": []string{"table"},
+			},
+		},
+		{
+			Name:        "sample_rows",
+			Description: fmt.Sprintf("Return up to %d rows of a table or view to show what its data looks like.", maxSampleRows),
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"table": tableParameter,
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Number of rows to return, %d by default", defaultSampleRows),
+					},
+				},
+				"required": []string{"table"},
+			},
+		},
+		{
+			Name:        "run_readonly_query",
+			Description: fmt.Sprintf("Run a single read-only SELECT query and return at most %d rows. Use it to check values or try out parts of the final query.", maxToolQueryRows),
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The SELECT query to run",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+}
+
+// call runs the tool requested by the model and returns its result as JSON
+func (t *schemaTools) call(ctx context.Context, call llm.ToolCall) (string, error) {
+	var args struct {
+		Table string `json:"table"`
+		Limit int    `json:"limit"`
+		Query string `json:"query"`
+	}
+	if strings.TrimSpace(call.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments for %s: %w", call.Name, err)
+		}
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch call.Name {
+	case "list_tables":
+		result, err = t.listTables(ctx)
+	case "describe_table":
+		result, err = t.describeTable(ctx, args.Table)
+	case "sample_rows":
+		result, err = t.sampleRows(ctx, args.Table, args.Limit)
+	case "run_readonly_query":
+		result, err = t.runReadonlyQuery(ctx, args.Query)
+	default:
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s result: %w", call.Name, err)
+	}
+	return truncate(string(data), maxToolResultLength), nil
+}
+
+func (t *schemaTools) loadSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	if t.schema != nil {
+		return t.schema, nil
+	}
+
+	schema, err := t.db.GetSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	t.schema = schema
+	return schema, nil
+}
+
+func (t *schemaTools) listTables(ctx context.Context) (interface{}, error) {
+	schema, err := t.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, len(schema.Tables))
+	for i, table := range schema.Tables {
+		tables[i] = table.Name
+	}
+	views := make([]string, len(schema.Views))
+	for i, view := range schema.Views {
+		views[i] = view.Name
+	}
+
+	return map[string][]string{
+		"tables": tables,
+		"views":  views,
+	}, nil
+}
+
+type columnDescription struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Nullable    bool   `json:"nullable"`
+	Description string `json:"description,omitempty"`
+}
+
+type foreignKeyDescription struct {
+	Columns    []string `json:"columns"`
+	References string   `json:"references"`
+}
+
+type tableDescription struct {
+	Name        string                  `json:"name"`
+	Columns     []columnDescription     `json:"columns"`
+	PrimaryKey  []string                `json:"primary_key,omitempty"`
+	ForeignKeys []foreignKeyDescription `json:"foreign_keys,omitempty"`
+}
+
+func (t *schemaTools) describeTable(ctx context.Context, name string) (interface{}, error) {
+	schema, err := t.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	describeColumns := func(columns []dbinterface.ColumnInfo) []columnDescription {
+		described := make([]columnDescription, len(columns))
+		for i, column := range columns {
+			described[i] = columnDescription{
+				Name:        column.Name,
+				Type:        column.DataType,
+				Nullable:    column.IsNullable,
+				Description: column.Description,
+			}
+		}
+		return described
+	}
+
+	for _, table := range schema.Tables {
+		if table.Name != name {
+			continue
+		}
+		description := tableDescription{
+			Name:       table.Name,
+			Columns:    describeColumns(table.Columns),
+			PrimaryKey: table.PrimaryKey,
+		}
+		for _, fk := range table.ForeignKeys {
+			description.ForeignKeys = append(description.ForeignKeys, foreignKeyDescription{
+				Columns:    fk.ColumnNames,
+				References: fmt.Sprintf("%s(%s)", fk.RefTableName, strings.Join(fk.RefColumnNames, ", ")),
+			})
+		}
+		return description, nil
+	}
+
+	for _, view := range schema.Views {
+		if view.Name == name {
+			return tableDescription{
+				Name:    view.Name,
+				Columns: describeColumns(view.Columns),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("table %q does not exist", name)
+}
+
+func (t *schemaTools) sampleRows(ctx context.Context, name string, limit int) (interface{}, error) {
+	schema, err := t.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only names taken from the schema end up in the query
+	exists := false
+	for _, table := range schema.Tables {
+		exists = exists || table.Name == name
+	}
+	for _, view := range schema.Views {
+		exists = exists || view.Name == name
+	}
+	if !exists {
+		return nil, fmt.Errorf("table %q does not exist", name)
+	}
+
+	if limit <= 0 {
+		limit = defaultSampleRows
+	}
+	if limit > maxSampleRows {
+		limit = maxSampleRows
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT %d", quoteIdentifier(t.db.Dialect(), name), limit)
+	result, err := t.db.ExecuteQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows, nil
+}
+
+func (t *schemaTools) runReadonlyQuery(ctx context.Context, query string) (interface{}, error) {
+	if err := sqlguard.Validate(t.db.Dialect(), query); err != nil {
+		return nil, err
+	}
+
+	// Bound the result so a broad query cannot flood the conversation. Comments are
+	// dropped first, as a trailing -- comment would swallow the closing parenthesis.
+	query, err := sqlguard.StripComments(t.db.Dialect(), query)
+	if err != nil {
+		return nil, err
+	}
+	limited := fmt.Sprintf("SELECT * FROM (%s) AS tool_query LIMIT %d", query, maxToolQueryRows)
+
+	result, err := t.db.ExecuteQuery(ctx, limited)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows, nil
+}
+
+// quoteIdentifier quotes name as an identifier of dialect
+func quoteIdentifier(dialect, name string) string {
+	if dialect == prompt.MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
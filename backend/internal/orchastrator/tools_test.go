@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/shahariaazam/smart-insights/internal/llm"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB records the queries it runs and returns a single row for each
+type fakeDB struct {
+	schema  *dbinterface.SchemaInfo
+	dialect string
+	queries []string
+}
+
+func (f *fakeDB) NewCredentials(config *models.DatabaseConfig) (dbinterface.Credentials, error) {
+	return nil, nil
+}
+func (f *fakeDB) Connect(ctx context.Context, creds dbinterface.Credentials) error { return nil }
+func (f *fakeDB) Close(ctx context.Context) error                                  { return nil }
+func (f *fakeDB) Ping(ctx context.Context) error                                   { return nil }
+func (f *fakeDB) Dialect() string                                                  { return f.dialect }
+func (f *fakeDB) Clone() dbinterface.Provider                                      { return &fakeDB{} }
+
+func (f *fakeDB) GetSchema(ctx context.Context) (*dbinterface.SchemaInfo, error) {
+	return f.schema, nil
+}
+
+func (f *fakeDB) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*dbinterface.QueryResult, error) {
+	f.queries = append(f.queries, query)
+	return &dbinterface.QueryResult{
+		Columns: []string{"id"},
+		Rows:    []map[string]interface{}{{"id": 1}},
+	}, nil
+}
+
+func TestSchemaTools(t *testing.T) {
+	ctx := context.Background()
+
+	newTools := func() (*schemaTools, *fakeDB) {
+		db := &fakeDB{schema: &dbinterface.SchemaInfo{
+			Tables: []dbinterface.TableInfo{{
+				Name:       "orders",
+				PrimaryKey: []string{"id"},
+				Columns: []dbinterface.ColumnInfo{
+					{Name: "id", DataType: "integer"},
+					{Name: "customer_id", DataType: "integer", IsNullable: true},
+				},
+				ForeignKeys: []dbinterface.ForeignKeyInfo{{
+					ColumnNames:    []string{"customer_id"},
+					RefTableName:   "customers",
+					RefColumnNames: []string{"id"},
+				}},
+			}},
+			Views: []dbinterface.ViewInfo{{Name: "monthly_revenue"}},
+		}, dialect: prompt.PostgreSQL}
+		return newSchemaTools(db), db
+	}
+
+	t.Run("list tables", func(t *testing.T) {
+		tools, _ := newTools()
+		result, err := tools.call(ctx, llm.ToolCall{Name: "list_tables"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"tables":["orders"],"views":["monthly_revenue"]}`, result)
+	})
+
+	t.Run("describe table", func(t *testing.T) {
+		tools, _ := newTools()
+		result, err := tools.call(ctx, llm.ToolCall{Name: "describe_table", Arguments: `{"table":"orders"}`})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "orders",
+			"columns": [
+				{"name": "id", "type": "integer", "nullable": false},
+				{"name": "customer_id", "type": "integer", "nullable": true}
+			],
+			"primary_key": ["id"],
+			"foreign_keys": [{"columns": ["customer_id"], "references": "customers(id)"}]
+		}`, result)
+
+		_, err = tools.call(ctx, llm.ToolCall{Name: "describe_table", Arguments: `{"table":"missing"}`})
+		assert.Error(t, err)
+	})
+
+	t.Run("sample rows only reads known tables", func(t *testing.T) {
+		tools, db := newTools()
+		_, err := tools.call(ctx, llm.ToolCall{Name: "sample_rows", Arguments: `{"table":"orders","limit":500}`})
+		require.NoError(t, err)
+		assert.Equal(t, []string{`SELECT * FROM "orders" LIMIT 20`}, db.queries)
+
+		_, err = tools.call(ctx, llm.ToolCall{Name: "sample_rows", Arguments: `{"table":"orders; drop table orders"}`})
+		assert.Error(t, err)
+		assert.Len(t, db.queries, 1)
+
+		// Names are quoted the way the dialect of the database does
+		db.dialect = prompt.MySQL
+		_, err = tools.call(ctx, llm.ToolCall{Name: "sample_rows", Arguments: `{"table":"orders"}`})
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM `orders` LIMIT 5", db.queries[1])
+	})
+
+	t.Run("readonly query is validated and limited", func(t *testing.T) {
+		tools, db := newTools()
+		result, err := tools.call(ctx, llm.ToolCall{Name: "run_readonly_query", Arguments: `{"query":"select id from orders;"}`})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"id":1}]`, result)
+		assert.Equal(t, []string{"SELECT * FROM (select id from orders) AS tool_query LIMIT 50"}, db.queries)
+
+		_, err = tools.call(ctx, llm.ToolCall{Name: "run_readonly_query", Arguments: `{"query":"delete from orders"}`})
+		assert.Error(t, err)
+		assert.Len(t, db.queries, 1)
+
+		// Comments cannot swallow the closing parenthesis of the wrapping query
+		_, err = tools.call(ctx, llm.ToolCall{Name: "run_readonly_query", Arguments: `{"query":"select id from orders -- every order"}`})
+		require.NoError(t, err)
+		_, err = tools.call(ctx, llm.ToolCall{Name: "run_readonly_query", Arguments: `{"query":"select id from orders /* paid; or not */ ;"}`})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"SELECT * FROM (select id from orders) AS tool_query LIMIT 50",
+			"SELECT * FROM (select id from orders) AS tool_query LIMIT 50",
+		}, db.queries[1:])
+	})
+
+	t.Run("unknown tool and bad arguments", func(t *testing.T) {
+		tools, _ := newTools()
+		_, err := tools.call(ctx, llm.ToolCall{Name: "drop_database"})
+		assert.Error(t, err)
+		_, err = tools.call(ctx, llm.ToolCall{Name: "describe_table", Arguments: `{"table":`})
+		assert.Error(t, err)
+	})
+}
@@ -5,17 +5,53 @@ import (
 	"strings"
 )
 
+// Dialects of the SQL that prompts ask for
+const (
+	PostgreSQL = "PostgreSQL"
+	MySQL      = "MySQL"
+	SQLite     = "SQLite"
+	DuckDB     = "DuckDB"
+	// MongoDB sources are queried with aggregation pipelines instead of SQL
+	MongoDB = "MongoDB"
+)
+
+// dialectNotes are additional rules for dialects that differ from what models
+// write by default
+var dialectNotes = map[string]string{
+	MySQL: `- Quote identifiers with backticks, never with double quotes
+- Escape single quotes in string literals by doubling them
+- Use MySQL functions such as DATE_FORMAT, DATE_SUB and IFNULL; there is no ILIKE, :: cast or FULL OUTER JOIN
+`,
+	SQLite: `- Dates are stored as TEXT, REAL or INTEGER; use date(), strftime() and julianday() to work with them
+- There is no ILIKE, :: cast, RIGHT JOIN or FULL OUTER JOIN; LIKE is case-insensitive for ASCII
+- Use CAST(x AS REAL) before dividing integers to avoid integer division
+`,
+	DuckDB: `- Quote identifiers with double quotes, as table names taken from file names may contain spaces or dashes
+- DuckDB supports PostgreSQL syntax such as ILIKE and :: casts, plus functions like date_trunc, strftime and list aggregates
+- Do not read files with read_csv, read_parquet or similar functions; query the tables in the schema
+`,
+}
+
 // LLMPayload contains the data needed for generating prompts
 type LLMPayload struct {
+	// Dialect is the SQL dialect of the database, PostgreSQL when empty
+	Dialect         string
 	DBSchema        string
 	Question        string
 	InitialQuery    string
+	QueryError      string
 	QueryResultJSON string
+	// StructuredOutput asks for a JSON object instead of tagged text, for providers
+	// that enforce a response schema
+	StructuredOutput bool
 }
 
 // InitialPrompt generates the prompt for SQL query generation
 func (l *LLMPayload) InitialPrompt() string {
-	return fmt.Sprintf(`You are a PostgreSQL expert who helps convert natural language questions into SQL queries.
+	if l.dialect() == MongoDB {
+		return l.initialPipelinePrompt()
+	}
+	return fmt.Sprintf(`You are a %s expert who helps convert natural language questions into SQL queries.
 Your task is to analyze the provided database schema and generate the most appropriate SQL query to answer the user's question.
 
 Database Schema:
@@ -36,20 +72,168 @@ Instructions:
 8. Consider query performance and optimization
 
 Important Notes:
-- Ensure the query follows PostgreSQL syntax
-- Use lowercase for SQL keywords for consistency
+- Ensure the query follows %s syntax
+%s- Use lowercase for SQL keywords for consistency
 - Include proper table aliases when joining multiple tables
 - Add appropriate comments for complex logic
 - Handle NULL values appropriately
 - Only include tables and columns that exist in the schema
 - No DML operations (INSERT, UPDATE, DELETE) allowed
 
-Response Format (no markdown):
-<sql>
-Your SQL query here
-</sql>
+%s
+
+Generate the SQL query now.`, l.dialect(), l.DBSchema, l.Question, l.dialect(), dialectNotes[l.dialect()], l.sqlResponseFormat("Response Format", "Your SQL query here"))
+}
+
+// RepairQueryPrompt generates the prompt for correcting a query that failed to execute
+func (l *LLMPayload) RepairQueryPrompt() string {
+	if l.dialect() == MongoDB {
+		return l.repairPipelinePrompt()
+	}
+	return fmt.Sprintf(`The following %s query was generated to answer the user's question, but it failed to execute.
+Your task is to correct the query so that it runs successfully and still answers the question.
+
+Database Schema:
+"""
+%s
+"""
+
+User Question: %s
+
+Failed Query:
+"""
+%s
+"""
+
+Database Error:
+"""
+%s
+"""
+
+Instructions:
+1. Read the database error carefully and identify its cause (e.g. unknown column, type mismatch, ambiguous reference)
+2. Only use tables and columns that exist in the schema
+3. Keep the intent of the original query unless it was the cause of the error
+4. Generate a single corrected SQL query without inline comments
+5. No DML operations (INSERT, UPDATE, DELETE) allowed
+%s
+%s
 
-Generate the SQL query now.`, l.DBSchema, l.Question)
+Generate the corrected SQL query now.`, l.dialect(), l.DBSchema, l.Question, l.InitialQuery, l.QueryError, dialectNotes[l.dialect()], l.sqlResponseFormat("Response Format", "Your corrected SQL query here"))
+}
+
+// AgentPrompt generates the prompt for SQL query generation when the model explores
+// the database through tools instead of receiving the whole schema
+func (l *LLMPayload) AgentPrompt() string {
+	return fmt.Sprintf(`You are a %s expert who helps convert natural language questions into SQL queries.
+You do not know the database schema yet. Use the tools to find the tables and columns needed to answer the user's question.
+
+User Question: %s
+
+Instructions:
+1. Start with list_tables, then use describe_table on the tables that look relevant
+2. Use sample_rows or run_readonly_query when you need to see actual values, e.g. how statuses or dates are stored
+3. Only use tables and columns that you have seen through the tools
+4. Keep tool calls to what is needed; you have a limited number of steps
+5. When you are ready, answer with a single, efficient SELECT query without inline comments
+6. No DML operations (INSERT, UPDATE, DELETE) allowed
+%s
+%s`, l.dialect(), l.Question, dialectNotes[l.dialect()], l.sqlResponseFormat("Final Response Format", "Your SQL query here"))
+}
+
+// pipelineRules are the instructions shared by the MongoDB prompts
+const pipelineRules = `- Aggregate a single collection; use $lookup to join other collections
+- Only use fields that appear in the collection schemas, with dotted paths for nested fields
+- Write the pipeline as JSON, using Extended JSON such as {"$date": "2024-01-01T00:00:00Z"} for dates and {"$oid": "..."} for ObjectIds
+- Never use $out or $merge, nor $where, $function or $accumulator`
+
+// initialPipelinePrompt generates the prompt for aggregation pipeline generation
+func (l *LLMPayload) initialPipelinePrompt() string {
+	return fmt.Sprintf(`You are a MongoDB expert who helps convert natural language questions into aggregation pipelines.
+Your task is to analyze the provided collection schemas and build the aggregation pipeline that best answers the user's question.
+
+Collection Schemas:
+"""
+%s
+"""
+
+The schemas were inferred from sampled documents; the note after a field gives the share of sampled documents that contain it.
+
+User Question: %s
+
+Instructions:
+1. Pick the collection that holds the data the question is about
+2. Use $match to filter, $group to aggregate, $sort to order and $project to name the output fields
+3. Use $unwind to work with the elements of array fields
+4. Add a $limit stage when returning large result sets
+5. Keep the pipeline as short as the question allows
+
+Important Notes:
+%s
+
+%s
+
+Generate the aggregation pipeline now.`, l.DBSchema, l.Question, pipelineRules, l.pipelineResponseFormat("Response Format"))
+}
+
+// repairPipelinePrompt generates the prompt for correcting a pipeline that failed
+func (l *LLMPayload) repairPipelinePrompt() string {
+	return fmt.Sprintf(`The following MongoDB aggregation pipeline was generated to answer the user's question, but it failed to execute.
+Your task is to correct the pipeline so that it runs successfully and still answers the question.
+
+Collection Schemas:
+"""
+%s
+"""
+
+User Question: %s
+
+Failed Pipeline:
+"""
+%s
+"""
+
+Database Error:
+"""
+%s
+"""
+
+Instructions:
+1. Read the database error carefully and identify its cause (e.g. invalid JSON, unknown stage, wrong field path)
+2. Keep the intent of the original pipeline unless it was the cause of the error
+%s
+
+%s
+
+Generate the corrected aggregation pipeline now.`, l.DBSchema, l.Question, l.InitialQuery, l.QueryError, pipelineRules, l.pipelineResponseFormat("Response Format"))
+}
+
+func (l *LLMPayload) pipelineResponseFormat(title string) string {
+	if l.StructuredOutput {
+		return title + `:
+Respond with a JSON object with the collection name in the "collection" field and the JSON array of stages, as a string, in the "pipeline" field.`
+	}
+	return title + ` (no markdown):
+<pipeline>
+{"collection": "collection name", "pipeline": [{"$match": {...}}, ...]}
+</pipeline>`
+}
+
+// dialect returns the SQL dialect to ask for
+func (l *LLMPayload) dialect() string {
+	if l.Dialect == "" {
+		return PostgreSQL
+	}
+	return l.Dialect
+}
+
+// SystemPrompt returns the system message of the SQL steps, which describes the
+// model as an expert in the dialect doing task
+func SystemPrompt(dialect, task string) string {
+	if dialect == "" {
+		dialect = PostgreSQL
+	}
+	return fmt.Sprintf("You are a %s expert who %s.", dialect, task)
 }
 
 // GenerateReportPrompt creates the prompt for formatting query results
@@ -73,47 +257,165 @@ Instructions:
 3. Include relevant statistics or insights
 4. Format numbers appropriately (e.g., currencies, percentages)
 5. Keep the report concise but informative
-6. Use proper markdown syntax and formatting insie the <markdown> tags. Don't use markdown response outside of <markdown> tags.'
+%s
+
+Generate the report now.`, l.Question, l.QueryResultJSON, l.reportResponseFormat())
+}
+
+func (l *LLMPayload) sqlResponseFormat(title, placeholder string) string {
+	if l.StructuredOutput {
+		return title + `:
+Respond with a JSON object with the query in the "sql" field.`
+	}
+	return fmt.Sprintf(`%s (no markdown):
+<sql>
+%s
+</sql>`, title, placeholder)
+}
+
+func (l *LLMPayload) reportResponseFormat() string {
+	if l.StructuredOutput {
+		return `6. Use proper markdown syntax and formatting in the report
+
+Response Format:
+Respond with a JSON object with the report in the "markdown" field.`
+	}
+	return `6. Use proper markdown syntax and formatting insie the <markdown> tags. Don't use markdown response outside of <markdown> tags.'
 
 Response Format (no markdown):
 <markdown>
 Your markdown-formatted report here
-</markdown>
-
-Generate the report now.`, l.Question, l.QueryResultJSON)
+</markdown>`
 }
 
-// ExtractResponse extracts content between specified XML-style tags
+// ExtractResponse extracts content between specified XML-style tags. When the tag
+// appears more than once the last complete block wins, nested blocks of the same tag
+// are unwrapped, and a code fence around the content is removed. Without any tags,
+// a code fence labelled with the tag name (e.g. ```sql) is used instead.
 func ExtractResponse(tag, response string) string {
 	startTag := fmt.Sprintf("<%s>", tag)
 	endTag := fmt.Sprintf("</%s>", tag)
 
-	startIndex := strings.Index(response, startTag)
-	endIndex := strings.Index(response, endTag)
+	var content string
+	found := false
+	depth, start := 0, 0
+	for i := 0; i < len(response); {
+		switch {
+		case strings.HasPrefix(response[i:], startTag):
+			if depth == 0 {
+				start = i + len(startTag)
+			}
+			depth++
+			i += len(startTag)
+		case depth > 0 && strings.HasPrefix(response[i:], endTag):
+			depth--
+			if depth == 0 {
+				content = response[start:i]
+				found = true
+			}
+			i += len(endTag)
+		default:
+			i++
+		}
+	}
+
+	if !found {
+		return extractCodeFence(tag, response)
+	}
+
+	if strings.Contains(content, startTag) {
+		if inner := ExtractResponse(tag, content); inner != "" {
+			return inner
+		}
+	}
+	return stripCodeFence(strings.TrimSpace(content))
+}
+
+// stripCodeFence removes a markdown code fence around s
+func stripCodeFence(s string) string {
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if newline := strings.Index(s, "\n"); newline != -1 {
+		s = s[newline+1:]
+	} else {
+		s = strings.TrimPrefix(s, "```")
+	}
+	return strings.TrimSpace(s)
+}
 
-	if startIndex == -1 || endIndex == -1 {
+// extractCodeFence returns the content of the last code fence labelled with lang
+func extractCodeFence(lang, response string) string {
+	opening := "```" + lang + "\n"
+	startIndex := strings.LastIndex(response, opening)
+	if startIndex == -1 {
+		return ""
+	}
+	content := response[startIndex+len(opening):]
+	endIndex := strings.Index(content, "```")
+	if endIndex == -1 {
 		return ""
 	}
+	return strings.TrimSpace(content[:endIndex])
+}
 
-	return strings.TrimSpace(response[startIndex+len(startTag) : endIndex])
+// TagStream extracts the content between XML-style tags from a response that
+// arrives in pieces, such as a streamed completion
+type TagStream struct {
+	startTag string
+	endTag   string
+	pending  string
+	inside   bool
+	done     bool
 }
 
-// ValidateQuery performs basic validation of generated SQL query
-func ValidateQuery(query string) error {
-	query = strings.ToLower(strings.TrimSpace(query))
+func NewTagStream(tag string) *TagStream {
+	return &TagStream{
+		startTag: fmt.Sprintf("<%s>", tag),
+		endTag:   fmt.Sprintf("</%s>", tag),
+	}
+}
 
-	// Check for dangerous operations
-	dangerousKeywords := []string{"drop", "truncate", "delete", "update", "insert", "alter", "create"}
-	for _, keyword := range dangerousKeywords {
-		if strings.Contains(query, keyword) {
-			return fmt.Errorf("query contains forbidden keyword: %s", keyword)
+// Write consumes the next piece of the response and returns the tag content it
+// completes. Text that may be the beginning of a tag is held back until the next
+// piece shows whether it is one.
+func (t *TagStream) Write(piece string) string {
+	if t.done {
+		return ""
+	}
+	t.pending += piece
+
+	if !t.inside {
+		startIndex := strings.Index(t.pending, t.startTag)
+		if startIndex == -1 {
+			t.pending = t.pending[len(t.pending)-partialTagLen(t.pending, t.startTag):]
+			return ""
 		}
+		t.pending = t.pending[startIndex+len(t.startTag):]
+		t.inside = true
 	}
 
-	// Ensure it starts with SELECT
-	if !strings.HasPrefix(query, "select") {
-		return fmt.Errorf("query must start with SELECT")
+	if endIndex := strings.Index(t.pending, t.endTag); endIndex != -1 {
+		content := t.pending[:endIndex]
+		t.pending = ""
+		t.done = true
+		return content
 	}
 
-	return nil
+	keep := partialTagLen(t.pending, t.endTag)
+	content := t.pending[:len(t.pending)-keep]
+	t.pending = t.pending[len(t.pending)-keep:]
+	return content
+}
+
+// partialTagLen returns the length of the longest suffix of s that is a proper
+// prefix of tag
+func partialTagLen(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
 }
@@ -0,0 +1,156 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagStream(t *testing.T) {
+	response := "Sure!\n<markdown>\n# Revenue\nTotal is <b>42</b>.\n</markdown>\ntrailing <markdown>ignored</markdown>"
+
+	for _, size := range []int{1, 2, 3, 7, len(response)} {
+		stream := NewTagStream("markdown")
+
+		var content strings.Builder
+		for i := 0; i < len(response); i += size {
+			end := i + size
+			if end > len(response) {
+				end = len(response)
+			}
+			content.WriteString(stream.Write(response[i:end]))
+		}
+
+		assert.Equal(t, "\n# Revenue\nTotal is <b>42</b>.\n", content.String(), "piece size %d", size)
+	}
+}
+
+func TestExtractResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"plain", "<sql>select 1</sql>", "select 1"},
+		{"repeated tag keeps the last block", "For example <sql>select 0</sql>. Final:\n<sql>\nselect 1\n</sql>", "select 1"},
+		{"nested tag", "<sql><sql>select 1</sql></sql>", "select 1"},
+		{"code fence inside tags", "<sql>\n```sql\nselect 1\n```\n</sql>", "select 1"},
+		{"tags inside code fence", "```xml\n<sql>select 1</sql>\n```", "select 1"},
+		{"code fence without tags", "Here you go:\n```sql\nselect 1\n```", "select 1"},
+		{"unterminated tag", "<sql>select 1", ""},
+		{"missing", "no query here", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtractResponse("sql", tt.response))
+		})
+	}
+}
+
+func TestParseSQLResult(t *testing.T) {
+	for _, content := range []string{
+		`{"sql": "select 1"}`,
+		"```json\n{\"sql\": \"select 1\"}\n```",
+		"<sql>select 1</sql>",
+	} {
+		result, err := ParseSQLResult(content)
+		if assert.NoError(t, err, content) {
+			assert.Equal(t, "select 1", result.SQL)
+		}
+	}
+
+	_, err := ParseSQLResult(`{"sql": ""}`)
+	assert.Error(t, err)
+}
+
+func TestJSONFieldStream(t *testing.T) {
+	response := `{"markdown": "# Revenue\n\"Total\" is 42 €\\n", "other": "ignored"}`
+
+	for _, size := range []int{1, 2, 5, len(response)} {
+		stream := NewJSONFieldStream("markdown")
+
+		var content strings.Builder
+		for i := 0; i < len(response); i += size {
+			end := i + size
+			if end > len(response) {
+				end = len(response)
+			}
+			content.WriteString(stream.Write(response[i:end]))
+		}
+
+		assert.Equal(t, "# Revenue\n\"Total\" is 42 €\\n", content.String(), "piece size %d", size)
+	}
+}
+
+func TestDialectPrompts(t *testing.T) {
+	postgres := LLMPayload{DBSchema: "users(id integer)", Question: "How many users are there?"}
+	assert.Contains(t, postgres.InitialPrompt(), "You are a PostgreSQL expert")
+	assert.Contains(t, postgres.InitialPrompt(), "Ensure the query follows PostgreSQL syntax")
+	assert.NotContains(t, postgres.InitialPrompt(), "backticks")
+
+	mysql := LLMPayload{Dialect: MySQL, DBSchema: "users(id int)", Question: "How many users are there?", InitialQuery: "select 1", QueryError: "error"}
+	for _, p := range []string{mysql.InitialPrompt(), mysql.RepairQueryPrompt(), mysql.AgentPrompt()} {
+		assert.Contains(t, p, "MySQL")
+		assert.NotContains(t, p, "PostgreSQL")
+		assert.Contains(t, p, "Quote identifiers with backticks")
+	}
+
+	assert.Equal(t, "You are a MySQL expert who fixes SQL queries.", SystemPrompt(MySQL, "fixes SQL queries"))
+	assert.Equal(t, "You are a PostgreSQL expert who fixes SQL queries.", SystemPrompt("", "fixes SQL queries"))
+}
+
+func TestPipelinePrompts(t *testing.T) {
+	payload := LLMPayload{Dialect: MongoDB, DBSchema: "Collection: orders", Question: "How many orders are there?", InitialQuery: `{"collection": "orders"}`, QueryError: "pipeline is required"}
+	for _, p := range []string{payload.InitialPrompt(), payload.RepairQueryPrompt()} {
+		assert.Contains(t, p, "MongoDB")
+		assert.Contains(t, p, "Never use $out or $merge")
+		assert.Contains(t, p, "<pipeline>")
+		assert.NotContains(t, p, "SQL")
+	}
+
+	payload.StructuredOutput = true
+	assert.Contains(t, payload.InitialPrompt(), `"pipeline" field`)
+}
+
+func TestParsePipelineResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "structured output",
+			content: `{"collection": "orders", "pipeline": "[{\"$count\": \"total\"}]"}`,
+			want:    `{"collection":"orders","pipeline":[{"$count": "total"}]}`,
+		},
+		{
+			name:    "query object in a code fence",
+			content: "```json\n{\"collection\": \"orders\", \"pipeline\": [{\"$count\": \"total\"}]}\n```",
+			want:    `{"collection":"orders","pipeline":[{"$count": "total"}]}`,
+		},
+		{
+			name:    "tags",
+			content: "Here it is:\n<pipeline>\n{\"collection\": \"orders\", \"pipeline\": []}\n</pipeline>",
+			want:    `{"collection": "orders", "pipeline": []}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParsePipelineResult(tt.content)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, query)
+		})
+	}
+
+	_, err := ParsePipelineResult(`{"collection": "orders", "pipeline": "[{"}`)
+	assert.Error(t, err)
+	_, err = ParsePipelineResult("SELECT 1")
+	assert.Error(t, err)
+
+	query, err := ParseQuery(PostgreSQL, "<sql>SELECT 1</sql>")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1", query)
+}
@@ -0,0 +1,65 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+)
+
+// FormatSchema describes schema for the query generation prompts of dialect. The
+// tables of a MongoDB schema are its collections and their columns the fields found
+// in sampled documents.
+func FormatSchema(dialect string, schema *dbinterface.SchemaInfo) string {
+	tableLabel, columnsLabel := "Table", "Columns"
+	if dialect == MongoDB {
+		tableLabel, columnsLabel = "Collection", "Fields"
+	}
+
+	var b strings.Builder
+	b.WriteString("Database Schema:\n\n")
+
+	writeColumns := func(columns []dbinterface.ColumnInfo) {
+		b.WriteString(columnsLabel + ":\n")
+		for _, column := range columns {
+			b.WriteString(fmt.Sprintf("  - %s %s", column.Name, column.DataType))
+			if column.CharMaxLength != nil {
+				b.WriteString(fmt.Sprintf("(%d)", *column.CharMaxLength))
+			}
+			if column.Description != "" {
+				b.WriteString(" -- " + column.Description)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for _, table := range schema.Tables {
+		b.WriteString(fmt.Sprintf("%s: %s\n", tableLabel, table.Name))
+		if table.Description != "" {
+			b.WriteString(fmt.Sprintf("Description: %s\n", table.Description))
+		}
+		writeColumns(table.Columns)
+		if len(table.PrimaryKey) > 0 {
+			b.WriteString(fmt.Sprintf("Primary Key: %s\n", strings.Join(table.PrimaryKey, ", ")))
+		}
+		if len(table.ForeignKeys) > 0 {
+			b.WriteString("Foreign Keys:\n")
+			for _, fk := range table.ForeignKeys {
+				b.WriteString(fmt.Sprintf("  - (%s) references %s(%s)\n",
+					strings.Join(fk.ColumnNames, ", "), fk.RefTableName, strings.Join(fk.RefColumnNames, ", ")))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	for _, view := range schema.Views {
+		b.WriteString(fmt.Sprintf("View: %s\n", view.Name))
+		if view.Description != "" {
+			b.WriteString(fmt.Sprintf("Description: %s\n", view.Description))
+		}
+		writeColumns(view.Columns)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
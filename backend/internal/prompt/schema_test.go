@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchema(t *testing.T) {
+	length := 120
+	schema := &dbinterface.SchemaInfo{
+		Tables: []dbinterface.TableInfo{
+			{
+				Name:        "order_items",
+				Description: "Lines of an order",
+				Columns: []dbinterface.ColumnInfo{
+					{Name: "order_id", DataType: "bigint unsigned"},
+					{Name: "line", DataType: "int"},
+					{Name: "product_id", DataType: "bigint unsigned", Description: "Product sold"},
+					{Name: "note", DataType: "character varying", CharMaxLength: &length},
+				},
+				PrimaryKey: []string{"order_id", "line"},
+				ForeignKeys: []dbinterface.ForeignKeyInfo{
+					{Name: "fk_order", ColumnNames: []string{"order_id"}, RefTableName: "orders", RefColumnNames: []string{"id"}},
+				},
+			},
+		},
+		Views: []dbinterface.ViewInfo{
+			{Name: "daily_sales", Columns: []dbinterface.ColumnInfo{{Name: "day", DataType: "date"}}},
+		},
+	}
+
+	assert.Equal(t, `Database Schema:
+
+Table: order_items
+Description: Lines of an order
+Columns:
+  - order_id bigint unsigned
+  - line int
+  - product_id bigint unsigned -- Product sold
+  - note character varying(120)
+Primary Key: order_id, line
+Foreign Keys:
+  - (order_id) references orders(id)
+
+View: daily_sales
+Columns:
+  - day date
+
+`, FormatSchema(MySQL, schema))
+
+	collections := &dbinterface.SchemaInfo{
+		Tables: []dbinterface.TableInfo{{
+			Name:        "orders",
+			Description: "2 sampled documents",
+			Columns: []dbinterface.ColumnInfo{
+				{Name: "_id", DataType: "objectId", Description: "in 100% of sampled documents"},
+				{Name: "total", DataType: "double|int", IsNullable: true, Description: "in 50% of sampled documents"},
+			},
+		}},
+	}
+
+	assert.Equal(t, `Database Schema:
+
+Collection: orders
+Description: 2 sampled documents
+Fields:
+  - _id objectId -- in 100% of sampled documents
+  - total double|int -- in 50% of sampled documents
+
+`, FormatSchema(MongoDB, collections))
+}
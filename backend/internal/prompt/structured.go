@@ -0,0 +1,246 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQLResult is the structured answer of the SQL generation and repair steps
+type SQLResult struct {
+	SQL string `json:"sql"`
+}
+
+// ReportResult is the structured answer of the report step
+type ReportResult struct {
+	Markdown string `json:"markdown"`
+}
+
+// SQLResultSchema is the JSON schema of SQLResult
+var SQLResultSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"sql": map[string]interface{}{
+			"type":        "string",
+			"description": "A single SQL query without inline comments",
+		},
+	},
+	"required":             []string{"sql"},
+	"additionalProperties": false,
+}
+
+// PipelineResult is the structured answer of the query steps for MongoDB sources
+type PipelineResult struct {
+	Collection string `json:"collection"`
+	// Pipeline is the JSON array of aggregation stages
+	Pipeline string `json:"pipeline"`
+}
+
+// PipelineResultSchema is the JSON schema of PipelineResult. The stages are a JSON
+// string because strict schemas cannot describe arbitrary stage documents.
+var PipelineResultSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"collection": map[string]interface{}{
+			"type":        "string",
+			"description": "The collection to aggregate",
+		},
+		"pipeline": map[string]interface{}{
+			"type":        "string",
+			"description": "JSON array of the aggregation stages",
+		},
+	},
+	"required":             []string{"collection", "pipeline"},
+	"additionalProperties": false,
+}
+
+// ReportResultSchema is the JSON schema of ReportResult
+var ReportResultSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"markdown": map[string]interface{}{
+			"type":        "string",
+			"description": "The markdown-formatted report",
+		},
+	},
+	"required":             []string{"markdown"},
+	"additionalProperties": false,
+}
+
+// ParseSQLResult reads the query from a completion, either as a SQLResult JSON
+// object or, for providers without structured output, from its <sql> tags
+func ParseSQLResult(content string) (*SQLResult, error) {
+	var result SQLResult
+	if parseJSONObject(content, &result) && strings.TrimSpace(result.SQL) != "" {
+		result.SQL = strings.TrimSpace(result.SQL)
+		return &result, nil
+	}
+
+	if query := ExtractResponse("sql", content); query != "" {
+		return &SQLResult{SQL: query}, nil
+	}
+	return nil, fmt.Errorf("no SQL query found in LLM response")
+}
+
+// ParsePipelineResult reads an aggregation pipeline from a completion and returns
+// it as a {"collection": ..., "pipeline": [...]} query. It accepts a PipelineResult
+// JSON object, the query object itself, or for providers without structured output
+// the content of its <pipeline> tags.
+func ParsePipelineResult(content string) (string, error) {
+	var fields map[string]json.RawMessage
+	if parseJSONObject(content, &fields) && fields["collection"] != nil && fields["pipeline"] != nil {
+		var collection string
+		if err := json.Unmarshal(fields["collection"], &collection); err != nil {
+			return "", fmt.Errorf("invalid collection in LLM response: %w", err)
+		}
+
+		stages := fields["pipeline"]
+		var encoded string
+		if json.Unmarshal(stages, &encoded) == nil {
+			stages = json.RawMessage(encoded)
+		}
+		if !json.Valid(stages) {
+			return "", fmt.Errorf("invalid pipeline in LLM response")
+		}
+
+		query, err := json.Marshal(struct {
+			Collection string          `json:"collection"`
+			Pipeline   json.RawMessage `json:"pipeline"`
+		}{collection, stages})
+		if err != nil {
+			return "", err
+		}
+		return string(query), nil
+	}
+
+	if query := ExtractResponse("pipeline", content); query != "" {
+		return query, nil
+	}
+	return "", fmt.Errorf("no aggregation pipeline found in LLM response")
+}
+
+// ParseQuery reads the query of the SQL and repair steps from a completion: a SQL
+// query, or for MongoDB an aggregation pipeline
+func ParseQuery(dialect, content string) (string, error) {
+	if dialect == MongoDB {
+		return ParsePipelineResult(content)
+	}
+	result, err := ParseSQLResult(content)
+	if err != nil {
+		return "", err
+	}
+	return result.SQL, nil
+}
+
+// ParseReportResult reads the report from a completion, either as a ReportResult
+// JSON object or, for providers without structured output, from its <markdown> tags
+func ParseReportResult(content string) (*ReportResult, error) {
+	var result ReportResult
+	if parseJSONObject(content, &result) && strings.TrimSpace(result.Markdown) != "" {
+		result.Markdown = strings.TrimSpace(result.Markdown)
+		return &result, nil
+	}
+
+	if markdown := ExtractResponse("markdown", content); markdown != "" {
+		return &ReportResult{Markdown: markdown}, nil
+	}
+	return nil, fmt.Errorf("no markdown content found in LLM response")
+}
+
+// parseJSONObject decodes content into v if it is a JSON object, possibly wrapped in
+// a code fence
+func parseJSONObject(content string, v interface{}) bool {
+	content = stripCodeFence(strings.TrimSpace(content))
+	if !strings.HasPrefix(content, "{") {
+		return false
+	}
+	return json.Unmarshal([]byte(content), v) == nil
+}
+
+// StreamExtractor picks the answer out of a completion that arrives in pieces
+type StreamExtractor interface {
+	// Write consumes the next piece of the completion and returns the part of the
+	// answer it completes
+	Write(piece string) string
+}
+
+// JSONFieldStream extracts the value of a top-level string field from a JSON object
+// that arrives in pieces, such as a streamed structured completion
+type JSONFieldStream struct {
+	key     string
+	pending string
+	inside  bool
+	done    bool
+}
+
+func NewJSONFieldStream(field string) *JSONFieldStream {
+	return &JSONFieldStream{key: strconv.Quote(field)}
+}
+
+// Write consumes the next piece of the object and returns the decoded field value
+// it completes. An escape sequence split across pieces is held back until it is
+// complete.
+func (s *JSONFieldStream) Write(piece string) string {
+	if s.done {
+		return ""
+	}
+	s.pending += piece
+
+	if !s.inside {
+		keyIndex := strings.Index(s.pending, s.key)
+		if keyIndex == -1 {
+			s.pending = s.pending[len(s.pending)-partialTagLen(s.pending, s.key):]
+			return ""
+		}
+		// Skip over the colon to the opening quote of the value
+		rest := strings.TrimLeft(s.pending[keyIndex+len(s.key):], " \t\r\n")
+		if rest == "" || (rest[0] == ':' && strings.TrimLeft(rest[1:], " \t\r\n") == "") {
+			return ""
+		}
+		if rest[0] != ':' {
+			s.done = true
+			return ""
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\r\n")
+		if rest[0] != '"' {
+			s.done = true
+			return ""
+		}
+		s.pending = rest[1:]
+		s.inside = true
+	}
+
+	var out strings.Builder
+	i := 0
+	for i < len(s.pending) {
+		c := s.pending[i]
+		if c == '"' {
+			s.done = true
+			s.pending = ""
+			return out.String()
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			i++
+			continue
+		}
+
+		// Decode the escape sequence once it is complete
+		length := 2
+		if i+1 < len(s.pending) && s.pending[i+1] == 'u' {
+			length = 6
+		}
+		if i+length > len(s.pending) {
+			break
+		}
+		decoded, err := strconv.Unquote(`"` + s.pending[i:i+length] + `"`)
+		if err != nil {
+			decoded = s.pending[i : i+length]
+		}
+		out.WriteString(decoded)
+		i += length
+	}
+	s.pending = s.pending[i:]
+	return out.String()
+}
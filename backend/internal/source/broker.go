@@ -0,0 +1,79 @@
+package source
+
+import (
+	"sync"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+)
+
+// subscriberBuffer is the number of events a slow subscriber may fall behind by
+// before events are dropped for it
+const subscriberBuffer = 64
+
+// Event is published whenever an assistant response changes
+type Event struct {
+	// ID is the 1-based position of the update in the response, or the number of
+	// updates in the response for status events
+	ID int
+	// Update is set when an update was appended
+	Update *models.Update
+	// Status is set when the status of the response changed
+	Status  string
+	Success bool
+}
+
+// Broker fans out response events to the subscribers of each response UUID
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of a response, and a function
+// that must be called to release it
+func (b *Broker) Subscribe(uuid string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.subscribers[uuid] == nil {
+		b.subscribers[uuid] = make(map[chan Event]struct{})
+	}
+	b.subscribers[uuid][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if subs, ok := b.subscribers[uuid]; ok {
+			delete(subs, ch)
+			if len(subs) == 0 {
+				delete(b.subscribers, uuid)
+			}
+		}
+	}
+}
+
+// Publish delivers an event to every subscriber of a response without blocking.
+// Subscribers that are too far behind miss the event and must detect the gap
+// from the event IDs.
+func (b *Broker) Publish(uuid string, event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[uuid] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -13,12 +13,16 @@ import (
 // ResponseAppender handles appending responses with thread safety
 type ResponseAppender struct {
 	storage storage.Storage
+	broker  *Broker
 	mu      sync.Mutex
 }
 
-func NewResponseAppender(storage storage.Storage) *ResponseAppender {
+// NewResponseAppender creates an appender that publishes every change to broker,
+// which may be nil
+func NewResponseAppender(storage storage.Storage, broker *Broker) *ResponseAppender {
 	return &ResponseAppender{
 		storage: storage,
+		broker:  broker,
 	}
 }
 
@@ -48,6 +52,8 @@ func (ra *ResponseAppender) AppendResponse(ctx context.Context, uuid string, upd
 		return fmt.Errorf("failed to save updated response: %w", err)
 	}
 
+	ra.broker.Publish(uuid, Event{ID: len(response.Response), Update: &update})
+
 	return nil
 }
 
@@ -71,5 +77,78 @@ func (ra *ResponseAppender) UpdateStatus(ctx context.Context, uuid string, statu
 		return fmt.Errorf("failed to save updated status: %w", err)
 	}
 
+	ra.broker.Publish(uuid, Event{ID: len(response.Response), Status: status, Success: success})
+
+	return nil
+}
+
+// SetQueryResult records the query that answered an ask and a summary of its result,
+// which later asks of the same thread use as conversation history
+func (ra *ResponseAppender) SetQueryResult(ctx context.Context, uuid string, query string, summary string) error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	// Load existing response
+	response, err := ra.storage.LoadAssistantResponse(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to load response: %w", err)
+	}
+
+	response.SQLQuery = query
+	response.ResultSummary = summary
+
+	// Save the updated response
+	if err := ra.storage.SaveAssistantResponse(ctx, *response); err != nil {
+		return fmt.Errorf("failed to save query result: %w", err)
+	}
+
+	return nil
+}
+
+// SetAnsweredBy records the LLM that answered the latest LLM call of an ask
+func (ra *ResponseAppender) SetAnsweredBy(ctx context.Context, uuid string, answeredBy models.LLMReference) error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	// Load existing response
+	response, err := ra.storage.LoadAssistantResponse(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to load response: %w", err)
+	}
+
+	response.AnsweredBy = &answeredBy
+
+	// Save the updated response
+	if err := ra.storage.SaveAssistantResponse(ctx, *response); err != nil {
+		return fmt.Errorf("failed to save answered by: %w", err)
+	}
+
+	return nil
+}
+
+// AddUsage adds the tokens and cost of an LLM call to the usage of an ask
+func (ra *ResponseAppender) AddUsage(ctx context.Context, uuid string, usage models.LLMUsage) error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	// Load existing response
+	response, err := ra.storage.LoadAssistantResponse(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to load response: %w", err)
+	}
+
+	// Copy the summary, which the stored response may share
+	var summary models.UsageSummary
+	if response.Usage != nil {
+		summary = *response.Usage
+	}
+	summary.Add(usage)
+	response.Usage = &summary
+
+	// Save the updated response
+	if err := ra.storage.SaveAssistantResponse(ctx, *response); err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+
 	return nil
 }
@@ -2,91 +2,97 @@ package source
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
-	"time"
 
-	_ "github.com/lib/pq"
-	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/database"
+	"github.com/shahariaazam/smart-insights/internal/dbinterface"
 	"github.com/shahariaazam/smart-insights/internal/storage"
 )
 
-type DatabaseConnector interface {
-	GetSchema(ctx context.Context, responseUUID string) (string, error)
-	ExecuteQuery(ctx context.Context, query string) (interface{}, error)
-	Close() error
-}
-
+// Registry keeps a connected provider per database configuration, so that the asks
+// about a database reuse its connection pool
 type Registry struct {
 	storage storage.Storage
 	mu      sync.RWMutex
-	pools   map[string]*sql.DB
+	pools   map[string]dbinterface.Provider
+}
+
+// pooled is a provider of the registry handed out by LoadSource. Closing it leaves
+// the pool open for the next caller.
+type pooled struct {
+	dbinterface.Provider
+}
+
+func (p pooled) Close(ctx context.Context) error {
+	return nil // Connection is managed by the registry
 }
 
 func NewRegistry(storage storage.Storage) *Registry {
 	return &Registry{
 		storage: storage,
-		pools:   make(map[string]*sql.DB),
+		pools:   make(map[string]dbinterface.Provider),
 	}
 }
 
-func (r *Registry) LoadSource(dbConfigName string, appender *ResponseAppender) (DatabaseConnector, error) {
+// LoadSource returns the provider of a database configuration, connecting the
+// provider registered for its type on first use
+func (r *Registry) LoadSource(ctx context.Context, dbConfigName string) (dbinterface.Provider, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Check if we already have a valid connection pool
-	if pool, exists := r.pools[dbConfigName]; exists {
-		if err := pool.Ping(); err == nil {
-			return NewPostgresConnector(pool, appender), nil
+	if existing, exists := r.pools[dbConfigName]; exists {
+		if err := existing.Ping(ctx); err == nil {
+			return pooled{existing}, nil
 		}
 		// If ping fails, remove the pool
+		existing.Close(context.Background())
 		delete(r.pools, dbConfigName)
 	}
 
 	// Load database configuration from storage
-	config, err := r.storage.LoadDatabaseConfig(context.Background(), dbConfigName)
+	config, err := r.storage.LoadDatabaseConfig(ctx, dbConfigName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load database config: %w", err)
 	}
 
-	// Create new connection pool
-	pool, err := createConnectionPool(config)
+	provider, err := database.GetProvider(string(config.Type))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
+	}
+	creds, err := provider.NewCredentials(config)
 	if err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+	if err := creds.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+
+	// Create new connection pool
+	if err := provider.Connect(ctx, creds); err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
 	// Store the pool for reuse
-	r.pools[dbConfigName] = pool
+	r.pools[dbConfigName] = provider
 
-	return NewPostgresConnector(pool, appender), nil
+	return pooled{provider}, nil
 }
 
-func createConnectionPool(config *models.DatabaseConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.Host, config.Port, config.Username, config.Password, config.DBName,
-	)
-
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
-
-	// Configure pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Hour)
+// Evict closes the connection pool of a database config if one is open, so the
+// next LoadSource reads the config again
+func (r *Registry) Evict(dbConfigName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Verify connection
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	existing, exists := r.pools[dbConfigName]
+	if !exists {
+		return nil
 	}
-
-	return db, nil
+	delete(r.pools, dbConfigName)
+	return existing.Close(context.Background())
 }
 
 // Close closes all connection pools
@@ -96,7 +102,7 @@ func (r *Registry) Close() error {
 
 	var errors []string
 	for name, pool := range r.pools {
-		if err := pool.Close(); err != nil {
+		if err := pool.Close(context.Background()); err != nil {
 			errors = append(errors, fmt.Sprintf("failed to close pool %s: %v", name, err))
 		}
 	}
@@ -106,163 +112,3 @@ func (r *Registry) Close() error {
 	}
 	return nil
 }
-
-// PostgresConnector implements DatabaseConnector for PostgreSQL
-type PostgresConnector struct {
-	db       *sql.DB
-	mu       sync.RWMutex
-	appender *ResponseAppender
-}
-
-func NewPostgresConnector(db *sql.DB, appender *ResponseAppender) *PostgresConnector {
-	return &PostgresConnector{
-		db:       db,
-		appender: appender,
-	}
-}
-
-// GetSchema retrieves and formats the database schema
-func (p *PostgresConnector) GetSchema(ctx context.Context, responseUUID string) (string, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	p.appender.AppendResponse(ctx, responseUUID, "step_output", "Fetching database schema...")
-
-	rows, err := p.db.QueryContext(ctx, getSchemaQuery)
-	if err != nil {
-		return "", fmt.Errorf("failed to query schema: %w", err)
-	}
-	defer rows.Close()
-
-	return p.processSchemaRows(ctx, responseUUID, rows)
-}
-
-func (p *PostgresConnector) processSchemaRows(ctx context.Context, responseUUID string, rows *sql.Rows) (string, error) {
-	var schema strings.Builder
-	schema.WriteString("Database Schema:\n\n")
-
-	processedTable := []string{}
-
-	for rows.Next() {
-		var (
-			tableName    string
-			columnsJSON  []byte
-			tableComment sql.NullString
-			columns      []string
-		)
-
-		if err := rows.Scan(&tableName, &columnsJSON, &tableComment); err != nil {
-			return "", fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		if err := json.Unmarshal(columnsJSON, &columns); err != nil {
-			return "", fmt.Errorf("failed to parse columns: %w", err)
-		}
-
-		p.formatSchemaEntry(&schema, tableName, tableComment, columns)
-		processedTable = append(processedTable, tableName)
-	}
-
-	p.appender.AppendResponse(ctx, responseUUID, "debug_log", fmt.Sprintf("Processed tables: %v", strings.Join(processedTable, ", ")))
-
-	if err := rows.Err(); err != nil {
-		return "", fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	p.appender.AppendResponse(ctx, responseUUID, "step_output", "Schema retrieval completed")
-	return schema.String(), nil
-}
-
-func (p *PostgresConnector) formatSchemaEntry(schema *strings.Builder, tableName string, tableComment sql.NullString, columns []string) {
-	schema.WriteString(fmt.Sprintf("Table: %s\n", tableName))
-	if tableComment.Valid {
-		schema.WriteString(fmt.Sprintf("Description: %s\n", tableComment.String))
-	}
-	schema.WriteString("Columns:\n")
-	for _, column := range columns {
-		schema.WriteString(fmt.Sprintf("  - %s\n", column))
-	}
-	schema.WriteString("\n")
-}
-
-// ExecuteQuery executes a SQL query and returns the results
-func (p *PostgresConnector) ExecuteQuery(ctx context.Context, query string) (interface{}, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	rows, err := p.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
-	defer rows.Close()
-
-	return p.processQueryResults(rows)
-}
-
-func (p *PostgresConnector) processQueryResults(rows *sql.Rows) (interface{}, error) {
-	columns, err := rows.Columns()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
-	}
-
-	var result []map[string]interface{}
-	for rows.Next() {
-		// Create value holders for this row
-		values := make([]interface{}, len(columns))
-		valuePointers := make([]interface{}, len(columns))
-		for i := range values {
-			valuePointers[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePointers...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		// Convert row to map
-		row := make(map[string]interface{})
-		for i, column := range columns {
-			value := values[i]
-			if b, ok := value.([]byte); ok {
-				row[column] = string(b)
-			} else {
-				row[column] = value
-			}
-		}
-		result = append(result, row)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return result, nil
-}
-
-func (p *PostgresConnector) Close() error {
-	return nil // Connection is managed by the registry
-}
-
-// Query to get database schema
-const getSchemaQuery = `
-SELECT 
-    t.table_name,
-    json_agg(
-        c.column_name || ' ' || 
-        c.data_type || 
-        CASE 
-            WHEN c.character_maximum_length IS NOT NULL 
-            THEN '(' || c.character_maximum_length || ')'
-            ELSE ''
-        END
-    ) as columns,
-    obj_description(quote_ident(t.table_name)::regclass::oid) as table_comment
-FROM 
-    information_schema.tables t
-    JOIN information_schema.columns c ON t.table_name = c.table_name
-WHERE 
-    t.table_schema = 'public'
-GROUP BY 
-    t.table_name
-ORDER BY 
-    t.table_name;
-`
@@ -0,0 +1,67 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shop.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store := memory.NewMemoryStorage()
+	require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "shop", Type: models.SQLite, Path: path}))
+	require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "legacy", Type: "oracle", Host: "db", Port: "1521"}))
+	require.NoError(t, store.SaveDatabaseConfig(ctx, models.DatabaseConfig{Name: "nameless", Type: models.PostgreSQL, Host: "db", Port: "5432"}))
+
+	registry := NewRegistry(store)
+	defer registry.Close()
+
+	// The provider registered for the type of the config is connected and pooled
+	provider, err := registry.LoadSource(ctx, "shop")
+	require.NoError(t, err)
+	assert.Equal(t, prompt.SQLite, provider.Dialect())
+	schema, err := provider.GetSchema(ctx)
+	require.NoError(t, err)
+	require.Len(t, schema.Tables, 1)
+	assert.Equal(t, "customers", schema.Tables[0].Name)
+
+	// Closing a loaded provider keeps the pool open for the next ask
+	require.NoError(t, provider.Close(ctx))
+	again, err := registry.LoadSource(ctx, "shop")
+	require.NoError(t, err)
+	assert.Equal(t, provider, again)
+	_, err = again.ExecuteQuery(ctx, "SELECT count(*) AS customers FROM customers")
+	require.NoError(t, err)
+
+	// Evicting closes the pool, so the next load connects anew
+	require.NoError(t, registry.Evict("shop"))
+	_, err = provider.ExecuteQuery(ctx, "SELECT 1")
+	assert.Error(t, err)
+	reloaded, err := registry.LoadSource(ctx, "shop")
+	require.NoError(t, err)
+	_, err = reloaded.ExecuteQuery(ctx, "SELECT 1")
+	assert.NoError(t, err)
+
+	_, err = registry.LoadSource(ctx, "legacy")
+	assert.EqualError(t, err, "unsupported database type: oracle")
+
+	_, err = registry.LoadSource(ctx, "nameless")
+	assert.ErrorContains(t, err, "invalid database config: user is required")
+
+	_, err = registry.LoadSource(ctx, "missing")
+	assert.ErrorContains(t, err, "failed to load database config")
+}
@@ -0,0 +1,334 @@
+package sqlguard
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokParam
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+	tokSemicolon
+	tokDot
+	tokOther
+)
+
+// token is a single lexical element of a SQL statement
+type token struct {
+	kind  tokenKind
+	value string // lower-cased for unquoted identifiers, raw text otherwise
+	pos   int    // byte offset in the original query
+	end   int    // byte offset just past the token in the original query
+}
+
+// SyntaxError is returned when a query cannot be tokenized or parsed
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Message)
+}
+
+// tokenize splits a query of dialect into tokens, dropping whitespace and comments.
+// MySQL differs from the other dialects: # starts a comment, -- only does when
+// followed by whitespace, strings in single or double quotes take backslash escapes
+// and $ is an identifier character. Its /*! ... */ comments are executed by the
+// server, so they are rejected rather than dropped.
+func tokenize(dialect, query string) ([]token, error) {
+	mysql := dialect == prompt.MySQL
+	var tokens []token
+	i := 0
+	n := len(query)
+
+	for i < n {
+		c := query[i]
+
+		switch {
+		case isSpace(c):
+			i++
+
+		case isLineComment(mysql, query, i):
+			for i < n && query[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			if mysql && (strings.HasPrefix(query[i:], "/*!") || strings.HasPrefix(query[i:], "/*M!")) {
+				return nil, &SyntaxError{Pos: i, Message: "executable comments are not allowed"}
+			}
+			end, err := skipBlockComment(query, i, !mysql)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+
+		case c == '\'' || (mysql && c == '"'):
+			end, err := scanString(query, i, mysql)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, value: query[i:end], pos: i, end: end})
+			i = end
+
+		case isStringPrefix(query, i):
+			// E'...', B'...', X'...' and N'...' literals
+			end, err := scanString(query, i+1, mysql || query[i] == 'e' || query[i] == 'E')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, value: query[i:end], pos: i, end: end})
+			i = end
+
+		case c == '"' || c == '`':
+			// MySQL quotes identifiers with backticks
+			end, value, err := scanQuotedIdent(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokQuotedIdent, value: value, pos: i, end: end})
+			i = end
+
+		case c == '$' && !mysql:
+			if i+1 < n && isDigit(query[i+1]) {
+				end := i + 1
+				for end < n && isDigit(query[end]) {
+					end++
+				}
+				tokens = append(tokens, token{kind: tokParam, value: query[i:end], pos: i, end: end})
+				i = end
+				continue
+			}
+			end, err := scanDollarString(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, value: query[i:end], pos: i, end: end})
+			i = end
+
+		case isIdentStart(c) || (mysql && c == '$'):
+			end := i + 1
+			for end < n && isIdentPart(query[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, value: strings.ToLower(query[i:end]), pos: i, end: end})
+			i = end
+
+		case isDigit(c) || (c == '.' && i+1 < n && isDigit(query[i+1])):
+			end := i + 1
+			for end < n && (isDigit(query[end]) || query[end] == '.' || query[end] == '_' ||
+				query[end] == 'e' || query[end] == 'E' ||
+				((query[end] == '+' || query[end] == '-') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, value: query[i:end], pos: i, end: end})
+			i = end
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, value: "(", pos: i, end: i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, value: ")", pos: i, end: i + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, value: ",", pos: i, end: i + 1})
+			i++
+		case c == ';':
+			tokens = append(tokens, token{kind: tokSemicolon, value: ";", pos: i, end: i + 1})
+			i++
+		case c == '.':
+			tokens = append(tokens, token{kind: tokDot, value: ".", pos: i, end: i + 1})
+			i++
+
+		case isOperatorChar(c):
+			end := i + 1
+			for end < n && isOperatorChar(query[end]) {
+				// Stop before a comment start embedded in an operator run
+				if isLineComment(mysql, query, end) || (query[end] == '/' && end+1 < n && query[end+1] == '*') {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, token{kind: tokOperator, value: query[i:end], pos: i, end: end})
+			i = end
+
+		default:
+			tokens = append(tokens, token{kind: tokOther, value: string(c), pos: i, end: i + 1})
+			i++
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: n, end: n})
+	return tokens, nil
+}
+
+// StripComments returns query of dialect without its comments and trailing semicolons, so that
+// it can be embedded in a larger query. A comment, like the whitespace around it,
+// becomes a single space.
+func StripComments(dialect, query string) (string, error) {
+	tokens, err := tokenize(dialect, query)
+	if err != nil {
+		return "", err
+	}
+
+	// Drop the EOF token and any trailing semicolons
+	last := len(tokens) - 1
+	for last > 0 && tokens[last-1].kind == tokSemicolon {
+		last--
+	}
+
+	var b strings.Builder
+	for i, tok := range tokens[:last] {
+		if i > 0 && tok.pos > tokens[i-1].end {
+			b.WriteByte(' ')
+		}
+		b.WriteString(query[tok.pos:tok.end])
+	}
+	return b.String(), nil
+}
+
+// isLineComment reports whether a comment running to the end of the line starts at
+// offset i
+func isLineComment(mysql bool, query string, i int) bool {
+	if mysql && query[i] == '#' {
+		return true
+	}
+	if !strings.HasPrefix(query[i:], "--") {
+		return false
+	}
+	// MySQL needs whitespace or a control character after --, so 1--1 is 1 - -1
+	return !mysql || i+2 == len(query) || query[i+2] <= ' '
+}
+
+// skipBlockComment returns the offset just past the comment starting at start.
+// PostgreSQL comments nest, MySQL's end at the first */.
+func skipBlockComment(query string, start int, nested bool) (int, error) {
+	depth := 0
+	i := start
+	for i < len(query) {
+		switch {
+		case strings.HasPrefix(query[i:], "/*") && (nested || depth == 0):
+			depth++
+			i += 2
+		case strings.HasPrefix(query[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, &SyntaxError{Pos: start, Message: "unterminated block comment"}
+}
+
+// scanString returns the offset just past the literal quoted with the character at
+// start, where a doubled quote stands for the quote itself
+func scanString(query string, start int, backslashEscapes bool) (int, error) {
+	quote := query[start]
+	i := start + 1
+	for i < len(query) {
+		switch {
+		case backslashEscapes && query[i] == '\\':
+			i += 2
+		case query[i] == quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1, nil
+		default:
+			i++
+		}
+	}
+	return 0, &SyntaxError{Pos: start, Message: "unterminated string literal"}
+}
+
+// scanQuotedIdent reads an identifier quoted with the character at start, where a
+// doubled quote stands for the quote itself
+func scanQuotedIdent(query string, start int) (int, string, error) {
+	var b strings.Builder
+	quote := query[start]
+	i := start + 1
+	for i < len(query) {
+		if query[i] == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				b.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return i + 1, b.String(), nil
+		}
+		b.WriteByte(query[i])
+		i++
+	}
+	return 0, "", &SyntaxError{Pos: start, Message: "unterminated quoted identifier"}
+}
+
+// scanDollarString handles $$...$$ and $tag$...$tag$ literals
+func scanDollarString(query string, start int) (int, error) {
+	end := start + 1
+	for end < len(query) && query[end] != '$' {
+		if !isIdentPart(query[end]) {
+			return 0, &SyntaxError{Pos: start, Message: "unexpected '$'"}
+		}
+		end++
+	}
+	if end >= len(query) {
+		return 0, &SyntaxError{Pos: start, Message: "unexpected '$'"}
+	}
+
+	tag := query[start : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing == -1 {
+		return 0, &SyntaxError{Pos: start, Message: "unterminated dollar-quoted string"}
+	}
+	return end + 1 + closing + len(tag), nil
+}
+
+func isStringPrefix(query string, i int) bool {
+	if i+1 >= len(query) || query[i+1] != '\'' {
+		return false
+	}
+	switch query[i] {
+	case 'e', 'E', 'b', 'B', 'x', 'X', 'n', 'N':
+		// Only a prefix when it doesn't end a longer identifier
+		return i == 0 || !isIdentPart(query[i-1])
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|?:[]", c) >= 0
+}
@@ -0,0 +1,36 @@
+package sqlguard
+
+import (
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		dialect  string
+		query    string
+		expected string
+	}{
+		{prompt.PostgreSQL, "select id from orders;", "select id from orders"},
+		{prompt.PostgreSQL, "select id from orders -- every order", "select id from orders"},
+		{prompt.PostgreSQL, "select id from orders /* paid; or not */ ;", "select id from orders"},
+		{prompt.PostgreSQL, "select id, /* total */ total\nfrom orders;;", "select id, total from orders"},
+		{prompt.PostgreSQL, "select o.id, sum(i.price)::numeric(10,2) from orders o", "select o.id, sum(i.price)::numeric(10,2) from orders o"},
+		{prompt.PostgreSQL, "select '-- not a comment' as s, `id` from t", "select '-- not a comment' as s, `id` from t"},
+		{prompt.MySQL, "select 'it\\'s' # every order", "select 'it\\'s'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stripped, err := StripComments(tt.dialect, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stripped)
+		})
+	}
+
+	_, err := StripComments(prompt.PostgreSQL, "select 'abc")
+	assert.Error(t, err)
+}
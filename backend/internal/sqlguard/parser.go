@@ -0,0 +1,363 @@
+package sqlguard
+
+import (
+	"strings"
+)
+
+// Statement is a top-level SQL statement terminated by ';' or the end of input
+type Statement struct {
+	Pos   int
+	Query *Query
+}
+
+// Query is a (sub)query together with the constructs relevant to read-only validation
+type Query struct {
+	// Kind is the leading keyword of the query body in upper case (SELECT, INSERT, ...)
+	Kind string
+	Pos  int
+	// CTEs holds the common table expressions of a WITH clause
+	CTEs []*CTE
+	// IntoPos is the position of a SELECT ... INTO clause, or -1
+	IntoPos int
+	// Locking holds row-locking clauses such as FOR UPDATE
+	Locking []LockingClause
+	// Functions holds every function call made directly by this query
+	Functions []FunctionCall
+	// Subqueries holds nested queries, e.g. in FROM, WHERE or set operations
+	Subqueries []*Query
+}
+
+// CTE is a single WITH-clause entry
+type CTE struct {
+	Name  string
+	Pos   int
+	Query *Query
+}
+
+// LockingClause is a FOR UPDATE / FOR SHARE style clause, or MySQL's LOCK IN SHARE MODE
+type LockingClause struct {
+	Strength string
+	// Clause is the clause in upper case, e.g. FOR NO KEY UPDATE
+	Clause string
+	Pos    int
+}
+
+// FunctionCall is a call to a possibly schema-qualified function
+type FunctionCall struct {
+	Schema string
+	Name   string
+	Pos    int
+}
+
+// queryStartKeywords are the keywords that open a (sub)query
+var queryStartKeywords = map[string]bool{
+	"select": true, "with": true, "values": true, "table": true,
+	"insert": true, "update": true, "delete": true, "merge": true,
+}
+
+// nonFunctionKeywords are keywords that may be directly followed by '(' without being a call
+var nonFunctionKeywords = map[string]bool{
+	"all": true, "and": true, "any": true, "array": true, "as": true, "by": true, "distinct": true,
+	"else": true, "except": true, "exists": true, "filter": true, "from": true, "group": true,
+	"having": true, "in": true, "intersect": true, "join": true, "lateral": true, "not": true,
+	"on": true, "or": true, "over": true, "recursive": true, "row": true, "select": true,
+	"some": true, "then": true, "union": true, "using": true, "values": true, "when": true,
+	"where": true, "with": true, "within": true,
+}
+
+// Parse splits a query of dialect into statements and builds their syntax trees
+func Parse(dialect, query string) ([]*Statement, error) {
+	tokens, err := tokenize(dialect, query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var statements []*Statement
+
+	for p.peek().kind != tokEOF {
+		if p.peek().kind == tokSemicolon {
+			p.next()
+			continue
+		}
+
+		stmt := &Statement{Pos: p.peek().pos}
+		stmt.Query, err = p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := p.peek(); tok.kind {
+		case tokSemicolon, tokEOF:
+		case tokRParen:
+			return nil, &SyntaxError{Pos: tok.pos, Message: "unbalanced parentheses"}
+		default:
+			return nil, &SyntaxError{Pos: tok.pos, Message: "unexpected " + tok.value}
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	return statements, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	tok := p.peek()
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(offset int, keyword string) bool {
+	tok := p.peekAt(offset)
+	return tok.kind == tokIdent && tok.value == keyword
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.peek()
+	if tok.kind != kind {
+		return tok, &SyntaxError{Pos: tok.pos, Message: "expected " + what}
+	}
+	return p.next(), nil
+}
+
+// startsQuery reports whether the token at offset opens a query
+func (p *parser) startsQuery(offset int) bool {
+	tok := p.peekAt(offset)
+	if tok.kind == tokIdent {
+		return queryStartKeywords[tok.value]
+	}
+	// A parenthesised query such as ((select 1) union (select 2))
+	return tok.kind == tokLParen && p.startsQuery(offset+1)
+}
+
+// parseQuery parses a query up to the ')' , ';' or end of input that terminates it
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{Pos: p.peek().pos, IntoPos: -1}
+
+	if p.isKeyword(0, "with") {
+		p.next()
+		if err := p.parseCTEs(q); err != nil {
+			return nil, err
+		}
+	}
+
+	switch tok := p.peek(); tok.kind {
+	case tokIdent:
+		q.Kind = strings.ToUpper(tok.value)
+	case tokLParen:
+		// The kind of a parenthesised query is that of its first operand
+		if p.startsQuery(1) {
+			p.next()
+			sub, err := p.parseQuery()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokRParen, "')'"); err != nil {
+				return nil, err
+			}
+			q.Kind = sub.Kind
+			q.Subqueries = append(q.Subqueries, sub)
+		}
+	}
+
+	if err := p.walk(q, false); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseCTEs parses the body of a WITH clause
+func (p *parser) parseCTEs(q *Query) error {
+	if p.isKeyword(0, "recursive") {
+		p.next()
+	}
+
+	for {
+		nameTok := p.next()
+		if nameTok.kind != tokIdent && nameTok.kind != tokQuotedIdent {
+			return &SyntaxError{Pos: nameTok.pos, Message: "expected common table expression name"}
+		}
+		cte := &CTE{Name: nameTok.value, Pos: nameTok.pos}
+
+		// Optional column list
+		if p.peek().kind == tokLParen {
+			p.next()
+			if err := p.walk(&Query{IntoPos: -1}, true); err != nil {
+				return err
+			}
+			if _, err := p.expect(tokRParen, "')'"); err != nil {
+				return err
+			}
+		}
+
+		if !p.isKeyword(0, "as") {
+			return &SyntaxError{Pos: p.peek().pos, Message: "expected AS"}
+		}
+		p.next()
+
+		if p.isKeyword(0, "not") {
+			p.next()
+		}
+		if p.isKeyword(0, "materialized") {
+			p.next()
+		}
+
+		if _, err := p.expect(tokLParen, "'('"); err != nil {
+			return err
+		}
+		sub, err := p.parseQuery()
+		if err != nil {
+			return err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return err
+		}
+		cte.Query = sub
+		q.CTEs = append(q.CTEs, cte)
+
+		if p.peek().kind != tokComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// walk consumes the tokens of a query, or of a parenthesised expression when nested is
+// true, recording function calls, subqueries and clauses on q. It stops in front of the
+// terminating ')' , ';' or end of input.
+func (p *parser) walk(q *Query, nested bool) error {
+	for {
+		tok := p.peek()
+
+		switch tok.kind {
+		case tokEOF, tokRParen:
+			return nil
+
+		case tokSemicolon:
+			if nested {
+				return &SyntaxError{Pos: tok.pos, Message: "unbalanced parentheses"}
+			}
+			return nil
+
+		case tokLParen:
+			p.next()
+			if p.startsQuery(0) {
+				sub, err := p.parseQuery()
+				if err != nil {
+					return err
+				}
+				q.Subqueries = append(q.Subqueries, sub)
+			} else if err := p.walk(q, true); err != nil {
+				return err
+			}
+			if _, err := p.expect(tokRParen, "')'"); err != nil {
+				return &SyntaxError{Pos: tok.pos, Message: "unbalanced parentheses"}
+			}
+
+		case tokIdent, tokQuotedIdent:
+			if call, ok := p.functionCall(); ok {
+				q.Functions = append(q.Functions, call)
+				continue
+			}
+
+			p.next()
+			if tok.kind != tokIdent {
+				continue
+			}
+
+			switch tok.value {
+			case "into":
+				if !nested && q.IntoPos == -1 {
+					q.IntoPos = tok.pos
+				}
+			case "for":
+				if strength, ok := p.lockingStrength(); ok {
+					q.Locking = append(q.Locking, LockingClause{Strength: strength, Clause: "FOR " + strength, Pos: tok.pos})
+				}
+			case "lock":
+				if p.isKeyword(0, "in") && p.isKeyword(1, "share") && p.isKeyword(2, "mode") {
+					p.next()
+					p.next()
+					p.next()
+					q.Locking = append(q.Locking, LockingClause{Strength: "SHARE", Clause: "LOCK IN SHARE MODE", Pos: tok.pos})
+				}
+			}
+
+		default:
+			p.next()
+		}
+	}
+}
+
+// functionCall consumes a (possibly schema-qualified) identifier followed by '('. The
+// parenthesis itself is left for walk so that the arguments are inspected as well.
+func (p *parser) functionCall() (FunctionCall, bool) {
+	first := p.peek()
+	if first.kind == tokIdent && nonFunctionKeywords[first.value] {
+		return FunctionCall{}, false
+	}
+
+	// Only the name directly in front of '(' is the function; schema.func( is qualified
+	if p.peekAt(1).kind == tokDot {
+		second := p.peekAt(2)
+		if (second.kind == tokIdent || second.kind == tokQuotedIdent) && p.peekAt(3).kind == tokLParen {
+			p.next()
+			p.next()
+			p.next()
+			return FunctionCall{Schema: first.value, Name: second.value, Pos: first.pos}, true
+		}
+		return FunctionCall{}, false
+	}
+
+	if p.peekAt(1).kind != tokLParen {
+		return FunctionCall{}, false
+	}
+	// A query keyword followed by '(' is a parenthesised operand, not a call
+	if first.kind == tokIdent && queryStartKeywords[first.value] {
+		return FunctionCall{}, false
+	}
+
+	p.next()
+	return FunctionCall{Name: first.value, Pos: first.pos}, true
+}
+
+// lockingStrength recognises the remainder of FOR UPDATE, FOR NO KEY UPDATE, FOR SHARE
+// and FOR KEY SHARE after the FOR keyword has been consumed
+func (p *parser) lockingStrength() (string, bool) {
+	switch {
+	case p.isKeyword(0, "update"):
+		p.next()
+		return "UPDATE", true
+	case p.isKeyword(0, "share"):
+		p.next()
+		return "SHARE", true
+	case p.isKeyword(0, "no") && p.isKeyword(1, "key") && p.isKeyword(2, "update"):
+		p.next()
+		p.next()
+		p.next()
+		return "NO KEY UPDATE", true
+	case p.isKeyword(0, "key") && p.isKeyword(1, "share"):
+		p.next()
+		p.next()
+		return "KEY SHARE", true
+	}
+	return "", false
+}
@@ -0,0 +1,137 @@
+package sqlguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Violation rules reported by ValidatePipeline
+const (
+	RuleForbiddenStage    = "forbidden_stage"
+	RuleForbiddenOperator = "forbidden_operator"
+)
+
+// forbiddenStages write the results of an aggregation to a collection
+var forbiddenStages = map[string]bool{
+	"$out":   true,
+	"$merge": true,
+}
+
+// forbiddenOperators run JavaScript on the server
+var forbiddenOperators = map[string]bool{
+	"$where":       true,
+	"$function":    true,
+	"$accumulator": true,
+}
+
+// Pipeline is a MongoDB aggregation over one collection, which is what MongoDB
+// sources run instead of SQL. Stages are kept as raw JSON so that Extended JSON
+// values such as {"$date": ...} reach the driver untouched.
+type Pipeline struct {
+	Collection string            `json:"collection"`
+	Stages     []json.RawMessage `json:"pipeline"`
+}
+
+// ParsePipeline decodes a query of the form
+// {"collection": "orders", "pipeline": [{"$match": {...}}, ...]}
+func ParsePipeline(query string) (*Pipeline, error) {
+	decoder := json.NewDecoder(strings.NewReader(query))
+	decoder.DisallowUnknownFields()
+
+	var pipeline Pipeline
+	if err := decoder.Decode(&pipeline); err != nil {
+		return nil, fmt.Errorf("invalid pipeline JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the pipeline object")
+	}
+	if strings.TrimSpace(pipeline.Collection) == "" {
+		return nil, fmt.Errorf("collection is required")
+	}
+
+	for i, stage := range pipeline.Stages {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(stage, &fields); err != nil || len(fields) != 1 {
+			return nil, fmt.Errorf("stage %d must be an object with a single stage operator", i+1)
+		}
+		for name := range fields {
+			if !strings.HasPrefix(name, "$") {
+				return nil, fmt.Errorf("stage %d: %q is not a stage operator", i+1, name)
+			}
+		}
+	}
+	return &pipeline, nil
+}
+
+// ValidatePipeline parses a MongoDB aggregation query and checks that it only reads:
+// no $out or $merge stage, including in $facet, $lookup and $unionWith
+// sub-pipelines, and no server-side JavaScript. It returns a *ValidationError
+// listing every violation found.
+func ValidatePipeline(query string) error {
+	violations := CheckPipeline(query)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// CheckPipeline returns the violations found in a MongoDB aggregation query, or nil
+// if it is allowed. Positions are the 1-based index of the offending stage.
+func CheckPipeline(query string) []Violation {
+	pipeline, err := ParsePipeline(query)
+	if err != nil {
+		return []Violation{{Rule: RuleSyntax, Message: err.Error()}}
+	}
+
+	var violations []Violation
+	for i, stage := range pipeline.Stages {
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(stage))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return []Violation{{Rule: RuleSyntax, Message: err.Error(), Position: i + 1}}
+		}
+		violations = append(violations, checkPipelineValue(value, i+1)...)
+	}
+	return violations
+}
+
+// checkPipelineValue looks for forbidden operators anywhere within a stage
+func checkPipelineValue(value interface{}, stage int) []Violation {
+	var violations []Violation
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// Sort the keys so violations are reported in a stable order
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			switch {
+			case forbiddenStages[key]:
+				violations = append(violations, Violation{
+					Rule:     RuleForbiddenStage,
+					Message:  fmt.Sprintf("stage %s writes to a collection", key),
+					Position: stage,
+				})
+			case forbiddenOperators[key]:
+				violations = append(violations, Violation{
+					Rule:     RuleForbiddenOperator,
+					Message:  fmt.Sprintf("operator %s runs JavaScript on the server", key),
+					Position: stage,
+				})
+			}
+			violations = append(violations, checkPipelineValue(v[key], stage)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			violations = append(violations, checkPipelineValue(item, stage)...)
+		}
+	}
+	return violations
+}
@@ -0,0 +1,66 @@
+package sqlguard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePipelineAllowsReadOnlyPipelines(t *testing.T) {
+	queries := []string{
+		`{"collection": "orders", "pipeline": []}`,
+		`{"collection": "orders", "pipeline": [{"$match": {"status": "paid", "created_at": {"$gte": {"$date": "2024-01-01T00:00:00Z"}}}}, {"$group": {"_id": "$customer.country", "total": {"$sum": "$amount"}}}, {"$sort": {"total": -1}}, {"$limit": 10}]}`,
+		`{"collection": "orders", "pipeline": [{"$lookup": {"from": "customers", "localField": "customer_id", "foreignField": "_id", "as": "customer"}}, {"$unwind": "$customer"}]}`,
+		// Field values that merely look like stage names are fine
+		`{"collection": "events", "pipeline": [{"$match": {"name": "$out"}}]}`,
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			assert.NoError(t, ValidatePipeline(query))
+		})
+	}
+}
+
+func TestValidatePipelineRejectsUnsafePipelines(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		rule  string
+	}{
+		{"out", `{"collection": "orders", "pipeline": [{"$match": {}}, {"$out": "copy"}]}`, RuleForbiddenStage},
+		{"merge", `{"collection": "orders", "pipeline": [{"$merge": {"into": "copy"}}]}`, RuleForbiddenStage},
+		{"out in facet", `{"collection": "orders", "pipeline": [{"$facet": {"a": [{"$out": "copy"}]}}]}`, RuleForbiddenStage},
+		{"merge in lookup", `{"collection": "orders", "pipeline": [{"$lookup": {"from": "x", "as": "y", "pipeline": [{"$merge": "z"}]}}]}`, RuleForbiddenStage},
+		{"where", `{"collection": "orders", "pipeline": [{"$match": {"$where": "sleep(1000)"}}]}`, RuleForbiddenOperator},
+		{"function", `{"collection": "orders", "pipeline": [{"$project": {"x": {"$function": {"body": "f", "args": [], "lang": "js"}}}}]}`, RuleForbiddenOperator},
+		{"invalid json", `{"collection": "orders", "pipeline": [`, RuleSyntax},
+		{"missing collection", `{"pipeline": []}`, RuleSyntax},
+		{"unknown field", `{"collection": "orders", "pipeline": [], "drop": true}`, RuleSyntax},
+		{"stage with two operators", `{"collection": "orders", "pipeline": [{"$match": {}, "$limit": 1}]}`, RuleSyntax},
+		{"stage without operator", `{"collection": "orders", "pipeline": [{"status": "paid"}]}`, RuleSyntax},
+		{"trailing data", `{"collection": "orders", "pipeline": []} {"collection": "x", "pipeline": []}`, RuleSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePipeline(tt.query)
+			require.Error(t, err)
+
+			var rules []string
+			for _, v := range err.(*ValidationError).Violations {
+				rules = append(rules, v.Rule)
+			}
+			assert.Contains(t, rules, tt.rule)
+		})
+	}
+}
+
+func TestParsePipeline(t *testing.T) {
+	pipeline, err := ParsePipeline(`{"collection": "orders", "pipeline": [{"$match": {"n": 1}}, {"$limit": 5}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", pipeline.Collection)
+	require.Len(t, pipeline.Stages, 2)
+	assert.JSONEq(t, `{"$limit": 5}`, string(pipeline.Stages[1]))
+}
@@ -0,0 +1,245 @@
+// Package sqlguard parses LLM-generated SQL and MongoDB aggregation pipelines and
+// rejects anything that is not a single read-only query.
+package sqlguard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+)
+
+// Violation rules reported by Validate
+const (
+	RuleSyntax             = "syntax"
+	RuleEmpty              = "empty"
+	RuleMultipleStatements = "multiple_statements"
+	RuleStatementType      = "statement_type"
+	RuleDataModifyingCTE   = "data_modifying_cte"
+	RuleDataModifyingQuery = "data_modifying_subquery"
+	RuleSelectInto         = "select_into"
+	RuleLockingClause      = "locking_clause"
+	RuleForbiddenFunction  = "forbidden_function"
+)
+
+// Violation describes a single reason for rejecting a query
+type Violation struct {
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+}
+
+// ValidationError is returned when a query violates one or more rules
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("query rejected: %s", strings.Join(messages, "; "))
+}
+
+// readOnlyKinds are the query kinds that may appear in CTEs and subqueries
+var readOnlyKinds = map[string]bool{
+	"SELECT": true,
+	"VALUES": true,
+	"TABLE":  true,
+}
+
+// forbiddenFunctions are keyed by dialect and list the functions that can sleep,
+// reach other servers, touch the file system or change server state even from
+// within a SELECT
+var forbiddenFunctions = map[string]map[string]bool{
+	prompt.PostgreSQL: {
+		"pg_sleep":                   true,
+		"pg_sleep_for":               true,
+		"pg_sleep_until":             true,
+		"dblink":                     true,
+		"dblink_exec":                true,
+		"dblink_connect":             true,
+		"dblink_connect_u":           true,
+		"dblink_send_query":          true,
+		"dblink_open":                true,
+		"lo_import":                  true,
+		"lo_export":                  true,
+		"lo_unlink":                  true,
+		"lo_from_bytea":              true,
+		"lo_put":                     true,
+		"pg_read_file":               true,
+		"pg_read_binary_file":        true,
+		"pg_ls_dir":                  true,
+		"pg_stat_file":               true,
+		"pg_file_write":              true,
+		"pg_terminate_backend":       true,
+		"pg_cancel_backend":          true,
+		"pg_reload_conf":             true,
+		"pg_rotate_logfile":          true,
+		"pg_promote":                 true,
+		"pg_switch_wal":              true,
+		"pg_create_restore_point":    true,
+		"pg_advisory_lock":           true,
+		"pg_advisory_xact_lock":      true,
+		"pg_notify":                  true,
+		"set_config":                 true,
+		"nextval":                    true,
+		"setval":                     true,
+		"query_to_xml":               true,
+		"query_to_xml_and_xmlschema": true,
+		"query_to_xmlschema":         true,
+	},
+	prompt.MySQL: {
+		"sleep":                      true,
+		"benchmark":                  true,
+		"load_file":                  true,
+		"get_lock":                   true,
+		"release_lock":               true,
+		"release_all_locks":          true,
+		"source_pos_wait":            true,
+		"master_pos_wait":            true,
+		"wait_for_executed_gtid_set": true,
+	},
+	prompt.SQLite: {
+		"load_extension": true,
+		"readfile":       true,
+		"writefile":      true,
+		"edit":           true,
+	},
+	prompt.DuckDB: {
+		"read_csv":       true,
+		"read_csv_auto":  true,
+		"read_parquet":   true,
+		"parquet_scan":   true,
+		"read_json":      true,
+		"read_json_auto": true,
+		"read_text":      true,
+		"read_blob":      true,
+		"glob":           true,
+		"sniff_csv":      true,
+	},
+}
+
+// isForbidden reports whether the function name is forbidden in dialect. The
+// functions of every dialect are forbidden in a dialect without its own list.
+func isForbidden(dialect, name string) bool {
+	if functions, ok := forbiddenFunctions[dialect]; ok {
+		return functions[name]
+	}
+	for _, functions := range forbiddenFunctions {
+		if functions[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate parses query and checks that it is a single read-only SELECT (optionally
+// with a WITH clause) that only calls functions allowed in dialect, one of the
+// prompt dialects. It returns a *ValidationError listing every violation found.
+func Validate(dialect, query string) error {
+	violations := Check(dialect, query)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// Check returns the violations found in query of dialect, or nil if it is allowed
+func Check(dialect, query string) []Violation {
+	statements, err := Parse(dialect, query)
+	if err != nil {
+		if syntaxErr, ok := err.(*SyntaxError); ok {
+			return []Violation{{Rule: RuleSyntax, Message: syntaxErr.Message, Position: syntaxErr.Pos}}
+		}
+		return []Violation{{Rule: RuleSyntax, Message: err.Error()}}
+	}
+
+	if len(statements) == 0 {
+		return []Violation{{Rule: RuleEmpty, Message: "query is empty"}}
+	}
+
+	var violations []Violation
+	if len(statements) > 1 {
+		violations = append(violations, Violation{
+			Rule:     RuleMultipleStatements,
+			Message:  fmt.Sprintf("expected a single statement, found %d", len(statements)),
+			Position: statements[1].Pos,
+		})
+	}
+
+	for _, stmt := range statements {
+		if stmt.Query.Kind != "SELECT" {
+			violations = append(violations, Violation{
+				Rule:     RuleStatementType,
+				Message:  fmt.Sprintf("only SELECT statements are allowed, found %s", describeKind(stmt.Query.Kind)),
+				Position: stmt.Pos,
+			})
+		}
+		violations = append(violations, checkQuery(dialect, stmt.Query)...)
+	}
+
+	return violations
+}
+
+func checkQuery(dialect string, q *Query) []Violation {
+	var violations []Violation
+
+	for _, cte := range q.CTEs {
+		if !readOnlyKinds[cte.Query.Kind] {
+			violations = append(violations, Violation{
+				Rule:     RuleDataModifyingCTE,
+				Message:  fmt.Sprintf("common table expression %q contains %s", cte.Name, describeKind(cte.Query.Kind)),
+				Position: cte.Pos,
+			})
+		}
+		violations = append(violations, checkQuery(dialect, cte.Query)...)
+	}
+
+	for _, sub := range q.Subqueries {
+		if !readOnlyKinds[sub.Kind] {
+			violations = append(violations, Violation{
+				Rule:     RuleDataModifyingQuery,
+				Message:  fmt.Sprintf("subquery contains %s", describeKind(sub.Kind)),
+				Position: sub.Pos,
+			})
+		}
+		violations = append(violations, checkQuery(dialect, sub)...)
+	}
+
+	if q.Kind == "SELECT" && q.IntoPos >= 0 {
+		violations = append(violations, Violation{
+			Rule:     RuleSelectInto,
+			Message:  "SELECT ... INTO creates a table",
+			Position: q.IntoPos,
+		})
+	}
+
+	for _, lock := range q.Locking {
+		violations = append(violations, Violation{
+			Rule:     RuleLockingClause,
+			Message:  fmt.Sprintf("%s locks rows", lock.Clause),
+			Position: lock.Pos,
+		})
+	}
+
+	for _, call := range q.Functions {
+		if isForbidden(dialect, call.Name) {
+			violations = append(violations, Violation{
+				Rule:     RuleForbiddenFunction,
+				Message:  fmt.Sprintf("function %s is not allowed", call.Name),
+				Position: call.Pos,
+			})
+		}
+	}
+
+	return violations
+}
+
+func describeKind(kind string) string {
+	if kind == "" {
+		return "an unrecognized statement"
+	}
+	return kind
+}
@@ -0,0 +1,108 @@
+package sqlguard
+
+import (
+	"testing"
+
+	"github.com/shahariaazam/smart-insights/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAllowsReadOnlyQueries(t *testing.T) {
+	queries := []string{
+		"select id, created_at, updated_at from orders",
+		"SELECT * FROM users WHERE deleted = false;",
+		"select o.id, sum(i.price)::numeric(10,2) from orders o join items i on i.order_id = o.id group by o.id order by 2 desc limit 10",
+		"with monthly as (select date_trunc('month', created_at) m, count(*) c from orders group by 1) select * from monthly",
+		"with recursive t(n) as (values (1) union all select n + 1 from t where n < 5) select n from t",
+		"select * from (select 1 as x) s where x in (select 1)",
+		"(select 1) union (select 2)",
+		"select substring(name for 3), extract(year from created_at) from users",
+		"select 'drop table users; delete from x' as text",
+		"select $$ select pg_sleep(10) $$ as body",
+		"select \"update\" from \"insert\" -- delete everything\n",
+		"select count(*) filter (where status = 'paid') over (partition by region) from sales",
+		"select e'it\\'s' as s",
+		"select `id`, `update`, `into` from `orders` where `name` = 'x' limit 10",
+		"select date_format(created_at, '%Y-%m') as month, count(*) from orders group by month",
+	}
+
+	for _, dialect := range []string{prompt.PostgreSQL, prompt.MySQL, prompt.SQLite, prompt.DuckDB} {
+		for _, query := range queries {
+			t.Run(dialect+"/"+query, func(t *testing.T) {
+				assert.NoError(t, Validate(dialect, query))
+			})
+		}
+	}
+
+	// MySQL strings take backslash escapes and # starts a comment
+	assert.NoError(t, Validate(prompt.MySQL, "select 'it\\'s', \"say \\\"hi\\\"\" from t # ; delete from t"))
+	assert.NoError(t, Validate(prompt.MySQL, "select 1--1"))
+
+	// A function is only forbidden in the dialect it is dangerous in
+	assert.NoError(t, Validate(prompt.SQLite, "select name from files where glob('*.csv', name)"))
+	assert.NoError(t, Validate(prompt.PostgreSQL, "select sleep from shifts"))
+	assert.NoError(t, Validate(prompt.DuckDB, "select sleep(1)"))
+}
+
+func TestValidateRejectsUnsafeQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		query   string
+		rule    string
+	}{
+		{"insert", prompt.PostgreSQL, "insert into users (name) values ('x')", RuleStatementType},
+		{"update", prompt.PostgreSQL, "update users set name = 'x'", RuleStatementType},
+		{"delete", prompt.PostgreSQL, "DELETE FROM users", RuleStatementType},
+		{"drop", prompt.PostgreSQL, "drop table users", RuleStatementType},
+		{"multiple statements", prompt.PostgreSQL, "select 1; drop table users", RuleMultipleStatements},
+		{"data modifying cte", prompt.PostgreSQL, "with d as (delete from users returning *) select * from d", RuleDataModifyingCTE},
+		{"nested data modifying cte", prompt.PostgreSQL, "with a as (with b as (insert into t values (1) returning *) select * from b) select * from a", RuleDataModifyingCTE},
+		{"with followed by update", prompt.PostgreSQL, "with x as (select 1) update users set id = 1", RuleStatementType},
+		{"select into", prompt.PostgreSQL, "select * into backup_users from users", RuleSelectInto},
+		{"for update", prompt.PostgreSQL, "select * from users for update", RuleLockingClause},
+		{"for no key update", prompt.PostgreSQL, "select * from users for no key update skip locked", RuleLockingClause},
+		{"mysql lock in share mode", prompt.MySQL, "select * from `users` where id = 1 lock in share mode", RuleLockingClause},
+		{"for share in subquery", prompt.PostgreSQL, "select * from (select * from users for share) u", RuleLockingClause},
+		{"pg_sleep", prompt.PostgreSQL, "select pg_sleep(10)", RuleForbiddenFunction},
+		{"qualified pg_sleep", prompt.PostgreSQL, "select * from users where pg_catalog.pg_sleep(1) is not null", RuleForbiddenFunction},
+		{"dblink", prompt.PostgreSQL, "select * from dblink('host=evil', 'select 1') as t(x int)", RuleForbiddenFunction},
+		{"mysql sleep", prompt.MySQL, "select sleep(10)", RuleForbiddenFunction},
+		{"mysql benchmark", prompt.MySQL, "select benchmark(1000000000, md5('x'))", RuleForbiddenFunction},
+		{"mysql load_file", prompt.MySQL, "select load_file('/etc/passwd')", RuleForbiddenFunction},
+		{"sqlite load_extension", prompt.SQLite, "select load_extension('/tmp/evil.so')", RuleForbiddenFunction},
+		{"duckdb read_csv", prompt.DuckDB, "select * from read_csv('/etc/passwd')", RuleForbiddenFunction},
+		{"duckdb glob", prompt.DuckDB, "select * from glob('/etc/*')", RuleForbiddenFunction},
+		{"unknown dialect", "", "select load_extension('/tmp/evil.so')", RuleForbiddenFunction},
+		{"duckdb read_parquet in join", prompt.DuckDB, "select * from orders o join read_parquet('s3://bucket/*.parquet') p on o.id = p.id", RuleForbiddenFunction},
+		{"mysql executable comment", prompt.MySQL, "SELECT 1 /*! INTO OUTFILE '/tmp/x' */", RuleSyntax},
+		{"mysql backslash escaped quote", prompt.MySQL, "SELECT 'a\\'' , sleep(10) -- '", RuleForbiddenFunction},
+		{"mysql hash comment", prompt.MySQL, "SELECT 1 #'\n, sleep(5) -- '", RuleForbiddenFunction},
+		{"mysql double quoted string", prompt.MySQL, `SELECT "a\"" , sleep(10) -- "`, RuleForbiddenFunction},
+		{"mysql dash dash without space", prompt.MySQL, "SELECT 1 --sleep(10)", RuleForbiddenFunction},
+		{"mysql unnested comment", prompt.MySQL, "SELECT 1 /* /* */ , sleep(5) /* */", RuleForbiddenFunction},
+		{"mysql dollar identifier", prompt.MySQL, "SELECT $$ , sleep(5) , $$", RuleForbiddenFunction},
+		{"mysql into outfile", prompt.MySQL, "select * from `users` into outfile '/tmp/users.csv'", RuleSelectInto},
+		{"unterminated backtick", prompt.MySQL, "select `id from users", RuleSyntax},
+		{"empty", prompt.PostgreSQL, "  -- nothing here\n", RuleEmpty},
+		{"unbalanced", prompt.PostgreSQL, "select (1", RuleSyntax},
+		{"unterminated string", prompt.PostgreSQL, "select 'abc", RuleSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.dialect, tt.query)
+			require.Error(t, err)
+
+			validationErr, ok := err.(*ValidationError)
+			require.True(t, ok, "expected *ValidationError, got %T", err)
+
+			rules := make([]string, len(validationErr.Violations))
+			for i, v := range validationErr.Violations {
+				rules[i] = v.Rule
+			}
+			assert.Contains(t, rules, tt.rule)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/shahariaazam/smart-insights/internal/api/models"
 	"github.com/shahariaazam/smart-insights/internal/storage"
@@ -13,7 +15,15 @@ import (
 type MemoryStorage struct {
 	configs            map[string]models.DatabaseConfig
 	llmConfigs         map[string]map[string]interface{}
+	llmChains          map[string]models.LLMChain
+	profiles           map[string]models.OrchestrationProfile
 	assistantResponses map[string]models.AssistantResponse
+	llmUsage           []models.LLMUsage
+	usageMutex         sync.RWMutex
+	llmCalls           []models.LLMCall
+	callsMutex         sync.RWMutex
+	datasets           map[string]models.Dataset
+	datasetsMutex      sync.RWMutex
 	assistantMutex     sync.RWMutex
 	mutex              sync.RWMutex
 	llmMutex           sync.RWMutex
@@ -24,6 +34,9 @@ func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		configs:            make(map[string]models.DatabaseConfig),
 		llmConfigs:         make(map[string]map[string]interface{}),
+		llmChains:          make(map[string]models.LLMChain),
+		profiles:           make(map[string]models.OrchestrationProfile),
+		datasets:           make(map[string]models.Dataset),
 		assistantResponses: make(map[string]models.AssistantResponse),
 	}
 }
@@ -152,6 +165,248 @@ func (m *MemoryStorage) DeleteLLMConfig(ctx context.Context, provider, configNam
 	return nil
 }
 
+func (m *MemoryStorage) SaveLLMChain(ctx context.Context, chain models.LLMChain) error {
+	m.llmMutex.Lock()
+	defer m.llmMutex.Unlock()
+
+	if _, exists := m.llmChains[chain.Name]; exists {
+		return storage.ErrConfigExists
+	}
+
+	m.llmChains[chain.Name] = copyChain(chain)
+	return nil
+}
+
+func (m *MemoryStorage) GetLLMChains(ctx context.Context) ([]models.LLMChain, error) {
+	m.llmMutex.RLock()
+	defer m.llmMutex.RUnlock()
+
+	chains := make([]models.LLMChain, 0, len(m.llmChains))
+	for _, chain := range m.llmChains {
+		chains = append(chains, copyChain(chain))
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Name < chains[j].Name
+	})
+	return chains, nil
+}
+
+func (m *MemoryStorage) LoadLLMChain(ctx context.Context, name string) (*models.LLMChain, error) {
+	m.llmMutex.RLock()
+	defer m.llmMutex.RUnlock()
+
+	chain, exists := m.llmChains[name]
+	if !exists {
+		return nil, storage.ErrConfigNotFound
+	}
+	chain = copyChain(chain)
+	return &chain, nil
+}
+
+func (m *MemoryStorage) DeleteLLMChain(ctx context.Context, name string) error {
+	m.llmMutex.Lock()
+	defer m.llmMutex.Unlock()
+
+	if _, exists := m.llmChains[name]; !exists {
+		return storage.ErrConfigNotFound
+	}
+
+	delete(m.llmChains, name)
+	return nil
+}
+
+// copyChain keeps callers from modifying the stored entries
+func copyChain(chain models.LLMChain) models.LLMChain {
+	chain.Entries = append([]models.LLMChainEntry(nil), chain.Entries...)
+	return chain
+}
+
+func (m *MemoryStorage) SaveOrchestrationProfile(ctx context.Context, profile models.OrchestrationProfile) error {
+	m.llmMutex.Lock()
+	defer m.llmMutex.Unlock()
+
+	if _, exists := m.profiles[profile.Name]; exists {
+		return storage.ErrConfigExists
+	}
+
+	m.profiles[profile.Name] = copyProfile(profile)
+	return nil
+}
+
+func (m *MemoryStorage) GetOrchestrationProfiles(ctx context.Context) ([]models.OrchestrationProfile, error) {
+	m.llmMutex.RLock()
+	defer m.llmMutex.RUnlock()
+
+	profiles := make([]models.OrchestrationProfile, 0, len(m.profiles))
+	for _, profile := range m.profiles {
+		profiles = append(profiles, copyProfile(profile))
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+func (m *MemoryStorage) LoadOrchestrationProfile(ctx context.Context, name string) (*models.OrchestrationProfile, error) {
+	m.llmMutex.RLock()
+	defer m.llmMutex.RUnlock()
+
+	profile, exists := m.profiles[name]
+	if !exists {
+		return nil, storage.ErrConfigNotFound
+	}
+	profile = copyProfile(profile)
+	return &profile, nil
+}
+
+func (m *MemoryStorage) DeleteOrchestrationProfile(ctx context.Context, name string) error {
+	m.llmMutex.Lock()
+	defer m.llmMutex.Unlock()
+
+	if _, exists := m.profiles[name]; !exists {
+		return storage.ErrConfigNotFound
+	}
+
+	delete(m.profiles, name)
+	return nil
+}
+
+// copyProfile keeps callers from modifying the stored steps
+func copyProfile(profile models.OrchestrationProfile) models.OrchestrationProfile {
+	steps := make(map[string]models.StepSettings, len(profile.Steps))
+	for step, settings := range profile.Steps {
+		steps[step] = settings
+	}
+	profile.Steps = steps
+	return profile
+}
+
+func (m *MemoryStorage) SaveLLMUsage(ctx context.Context, usage models.LLMUsage) error {
+	m.usageMutex.Lock()
+	defer m.usageMutex.Unlock()
+
+	m.llmUsage = append(m.llmUsage, usage)
+	return nil
+}
+
+func (m *MemoryStorage) GetLLMUsage(ctx context.Context, filter models.UsageFilter) ([]models.LLMUsage, error) {
+	m.usageMutex.RLock()
+	defer m.usageMutex.RUnlock()
+
+	var records []models.LLMUsage
+	for _, usage := range m.llmUsage {
+		if filter.Matches(usage) {
+			records = append(records, usage)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records, nil
+}
+
+func (m *MemoryStorage) SaveLLMCall(ctx context.Context, call models.LLMCall) error {
+	m.callsMutex.Lock()
+	defer m.callsMutex.Unlock()
+
+	m.llmCalls = append(m.llmCalls, copyLLMCall(call))
+	return nil
+}
+
+func (m *MemoryStorage) GetLLMCalls(ctx context.Context, askID string) ([]models.LLMCall, error) {
+	m.callsMutex.RLock()
+	defer m.callsMutex.RUnlock()
+
+	var calls []models.LLMCall
+	for _, call := range m.llmCalls {
+		if call.AskID == askID {
+			calls = append(calls, copyLLMCall(call))
+		}
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].CreatedAt.Before(calls[j].CreatedAt)
+	})
+	return calls, nil
+}
+
+func (m *MemoryStorage) DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.callsMutex.Lock()
+	defer m.callsMutex.Unlock()
+
+	kept := m.llmCalls[:0]
+	for _, call := range m.llmCalls {
+		if !call.CreatedAt.Before(before) {
+			kept = append(kept, call)
+		}
+	}
+	deleted := int64(len(m.llmCalls) - len(kept))
+	m.llmCalls = kept
+	return deleted, nil
+}
+
+// copyLLMCall copies the messages of a call, so callers cannot change the stored one
+func copyLLMCall(call models.LLMCall) models.LLMCall {
+	call.Messages = append([]models.LLMCallMessage(nil), call.Messages...)
+	call.ToolCalls = append([]models.LLMCallToolCall(nil), call.ToolCalls...)
+	return call
+}
+
+func (m *MemoryStorage) SaveDataset(ctx context.Context, dataset models.Dataset) error {
+	m.datasetsMutex.Lock()
+	defer m.datasetsMutex.Unlock()
+
+	if _, exists := m.datasets[dataset.Name]; exists {
+		return storage.ErrConfigExists
+	}
+
+	m.datasets[dataset.Name] = copyDataset(dataset)
+	return nil
+}
+
+func (m *MemoryStorage) GetDatasets(ctx context.Context) ([]models.Dataset, error) {
+	m.datasetsMutex.RLock()
+	defer m.datasetsMutex.RUnlock()
+
+	datasets := make([]models.Dataset, 0, len(m.datasets))
+	for _, dataset := range m.datasets {
+		datasets = append(datasets, copyDataset(dataset))
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		return datasets[i].Name < datasets[j].Name
+	})
+	return datasets, nil
+}
+
+func (m *MemoryStorage) LoadDataset(ctx context.Context, name string) (*models.Dataset, error) {
+	m.datasetsMutex.RLock()
+	defer m.datasetsMutex.RUnlock()
+
+	dataset, exists := m.datasets[name]
+	if !exists {
+		return nil, storage.ErrConfigNotFound
+	}
+	dataset = copyDataset(dataset)
+	return &dataset, nil
+}
+
+func (m *MemoryStorage) DeleteDataset(ctx context.Context, name string) error {
+	m.datasetsMutex.Lock()
+	defer m.datasetsMutex.Unlock()
+
+	if _, exists := m.datasets[name]; !exists {
+		return storage.ErrConfigNotFound
+	}
+
+	delete(m.datasets, name)
+	return nil
+}
+
+// copyDataset keeps callers from modifying the stored columns
+func copyDataset(dataset models.Dataset) models.Dataset {
+	dataset.Columns = append([]models.DatasetColumn(nil), dataset.Columns...)
+	return dataset
+}
+
 func (m *MemoryStorage) SaveAssistantResponse(ctx context.Context, response models.AssistantResponse) error {
 	m.assistantMutex.Lock()
 	defer m.assistantMutex.Unlock()
@@ -181,6 +436,22 @@ func (m *MemoryStorage) GetAssistantHistories(ctx context.Context) ([]models.Ass
 	return histories, nil
 }
 
+func (m *MemoryStorage) GetThreadResponses(ctx context.Context, threadID string) ([]models.AssistantResponse, error) {
+	m.assistantMutex.RLock()
+	defer m.assistantMutex.RUnlock()
+
+	var thread []models.AssistantResponse
+	for _, response := range m.assistantResponses {
+		if response.ThreadID == threadID {
+			thread = append(thread, response)
+		}
+	}
+	sort.Slice(thread, func(i, j int) bool {
+		return thread[i].Sequence < thread[j].Sequence
+	})
+	return thread, nil
+}
+
 func (m *MemoryStorage) Close() error {
 	return nil // No-op for memory storage
 }
@@ -52,6 +52,130 @@ func TestMemoryLLMStorage(t *testing.T) {
 	})
 }
 
+func TestMemoryLLMChainStorage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	chain := models.LLMChain{
+		Name: "resilient",
+		Entries: []models.LLMChainEntry{
+			{Provider: models.OpenAI, Config: "gpt-4o"},
+			{Provider: models.Anthropic, Config: "claude"},
+		},
+	}
+
+	require.NoError(t, store.SaveLLMChain(ctx, chain))
+	assert.Equal(t, storage.ErrConfigExists, store.SaveLLMChain(ctx, chain))
+
+	loaded, err := store.LoadLLMChain(ctx, "resilient")
+	require.NoError(t, err)
+	assert.Equal(t, chain, *loaded)
+
+	// The stored entries are not shared with callers
+	loaded.Entries[0].Config = "changed"
+	chains, err := store.GetLLMChains(ctx)
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, "gpt-4o", chains[0].Entries[0].Config)
+
+	require.NoError(t, store.DeleteLLMChain(ctx, "resilient"))
+	_, err = store.LoadLLMChain(ctx, "resilient")
+	assert.Equal(t, storage.ErrConfigNotFound, err)
+	assert.Equal(t, storage.ErrConfigNotFound, store.DeleteLLMChain(ctx, "resilient"))
+}
+
+func TestMemoryLLMUsageStorage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	later := models.LLMUsage{AskID: "b", Provider: models.Anthropic, Config: "claude", DBConfig: "hr", CreatedAt: start.Add(48 * time.Hour)}
+	earlier := models.LLMUsage{AskID: "a", Provider: models.OpenAI, Config: "gpt-4o", DBConfig: "sales", CreatedAt: start}
+	require.NoError(t, store.SaveLLMUsage(ctx, later))
+	require.NoError(t, store.SaveLLMUsage(ctx, earlier))
+
+	records, err := store.GetLLMUsage(ctx, models.UsageFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.LLMUsage{earlier, later}, records)
+
+	records, err = store.GetLLMUsage(ctx, models.UsageFilter{Provider: models.OpenAI, Config: "gpt-4o", DBConfig: "sales"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.LLMUsage{earlier}, records)
+
+	// From is inclusive, To exclusive
+	records, err = store.GetLLMUsage(ctx, models.UsageFilter{From: start, To: later.CreatedAt})
+	require.NoError(t, err)
+	assert.Equal(t, []models.LLMUsage{earlier}, records)
+}
+
+func TestMemoryLLMCallStorage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	first := models.LLMCall{ID: "1", AskID: "a", Messages: []models.LLMCallMessage{{Role: "user", Content: "q"}}, CreatedAt: start}
+	second := models.LLMCall{ID: "2", AskID: "a", CreatedAt: start.Add(time.Hour)}
+	other := models.LLMCall{ID: "3", AskID: "b", CreatedAt: start.Add(2 * time.Hour)}
+	for _, call := range []models.LLMCall{second, other, first} {
+		require.NoError(t, store.SaveLLMCall(ctx, call))
+	}
+
+	calls, err := store.GetLLMCalls(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []models.LLMCall{first, second}, calls)
+
+	// The stored messages are not shared with callers
+	calls[0].Messages[0].Content = "changed"
+	calls, err = store.GetLLMCalls(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "q", calls[0].Messages[0].Content)
+
+	deleted, err := store.DeleteLLMCallsBefore(ctx, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	calls, err = store.GetLLMCalls(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []models.LLMCall{second}, calls)
+}
+
+func TestMemoryDatasetStorage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	created := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sales := models.Dataset{
+		Name:      "sales",
+		FileName:  "sales.csv",
+		Format:    models.CSV,
+		Rows:      2,
+		Columns:   []models.DatasetColumn{{Name: "region", Type: "VARCHAR"}},
+		CreatedAt: created,
+		ExpiresAt: created.Add(24 * time.Hour),
+	}
+	budget := models.Dataset{Name: "budget", FileName: "budget.xlsx", Format: models.XLSX, Sheet: "Sheet1"}
+	require.NoError(t, store.SaveDataset(ctx, sales))
+	require.NoError(t, store.SaveDataset(ctx, budget))
+	assert.Equal(t, storage.ErrConfigExists, store.SaveDataset(ctx, sales))
+
+	loaded, err := store.LoadDataset(ctx, "sales")
+	require.NoError(t, err)
+	assert.Equal(t, sales, *loaded)
+
+	// The stored columns are not shared with callers
+	loaded.Columns[0].Type = "changed"
+	datasets, err := store.GetDatasets(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.Equal(t, "budget", datasets[0].Name)
+	assert.Equal(t, "VARCHAR", datasets[1].Columns[0].Type)
+
+	require.NoError(t, store.DeleteDataset(ctx, "sales"))
+	_, err = store.LoadDataset(ctx, "sales")
+	assert.Equal(t, storage.ErrConfigNotFound, err)
+	assert.Equal(t, storage.ErrConfigNotFound, store.DeleteDataset(ctx, "sales"))
+}
+
 func TestAssistantStorage(t *testing.T) {
 	ctx := context.Background()
 	store := NewMemoryStorage()
@@ -115,4 +239,25 @@ func TestAssistantStorage(t *testing.T) {
 		assert.Contains(t, uuids, testResponse.UUID)
 		assert.Contains(t, uuids, secondResponse.UUID)
 	})
+
+	// Test getting the asks of a thread
+	t.Run("get thread responses", func(t *testing.T) {
+		for _, response := range []models.AssistantResponse{
+			{UUID: "thread-ask-2", Question: "Now by month", ThreadID: "thread-1", Sequence: 2},
+			{UUID: "thread-ask-1", Question: "Total revenue", ThreadID: "thread-1", Sequence: 1},
+			{UUID: "other-thread-ask", Question: "Top customers", ThreadID: "thread-2", Sequence: 1},
+		} {
+			require.NoError(t, store.SaveAssistantResponse(ctx, response))
+		}
+
+		thread, err := store.GetThreadResponses(ctx, "thread-1")
+		require.NoError(t, err)
+		require.Len(t, thread, 2)
+		assert.Equal(t, "thread-ask-1", thread[0].UUID)
+		assert.Equal(t, "thread-ask-2", thread[1].UUID)
+
+		thread, err = store.GetThreadResponses(ctx, "unknown-thread")
+		require.NoError(t, err)
+		assert.Empty(t, thread)
+	})
 }
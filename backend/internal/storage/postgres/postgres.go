@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -83,6 +84,100 @@ func createTable(db *sql.DB) error {
         `,
 		`
         CREATE INDEX IF NOT EXISTS idx_assistant_responses_created_at ON assistant_responses(created_at);
+        `,
+		`
+        ALTER TABLE assistant_responses
+            ADD COLUMN IF NOT EXISTS thread_id VARCHAR(255),
+            ADD COLUMN IF NOT EXISTS sequence INTEGER NOT NULL DEFAULT 0,
+            ADD COLUMN IF NOT EXISTS sql_query TEXT NOT NULL DEFAULT '',
+            ADD COLUMN IF NOT EXISTS result_summary TEXT NOT NULL DEFAULT '';
+        `,
+		`
+        CREATE INDEX IF NOT EXISTS idx_assistant_responses_thread ON assistant_responses(thread_id, sequence);
+        `,
+		`
+        CREATE TABLE IF NOT EXISTS llm_chains (
+            name VARCHAR(255) PRIMARY KEY,
+            entries JSONB NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        `,
+		`
+        ALTER TABLE assistant_responses
+            ADD COLUMN IF NOT EXISTS answered_by JSONB;
+        `,
+		`
+        CREATE TABLE IF NOT EXISTS orchestration_profiles (
+            name VARCHAR(255) PRIMARY KEY,
+            steps JSONB NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        `,
+		`
+        CREATE TABLE IF NOT EXISTS llm_usage (
+            id BIGSERIAL PRIMARY KEY,
+            ask_id VARCHAR(255) NOT NULL,
+            provider VARCHAR(50) NOT NULL,
+            config VARCHAR(255) NOT NULL,
+            model VARCHAR(255) NOT NULL,
+            db_config VARCHAR(255) NOT NULL DEFAULT '',
+            step VARCHAR(50) NOT NULL DEFAULT '',
+            prompt_tokens INTEGER NOT NULL,
+            completion_tokens INTEGER NOT NULL,
+            total_tokens INTEGER NOT NULL,
+            cost DOUBLE PRECISION NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );
+        `,
+		`
+        CREATE INDEX IF NOT EXISTS idx_llm_usage_config ON llm_usage(provider, config, created_at);
+        `,
+		`
+        CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage(created_at);
+        `,
+		`
+        ALTER TABLE assistant_responses
+            ADD COLUMN IF NOT EXISTS usage JSONB;
+        `,
+		`
+        CREATE TABLE IF NOT EXISTS llm_calls (
+            id VARCHAR(255) PRIMARY KEY,
+            ask_id VARCHAR(255) NOT NULL,
+            step VARCHAR(50) NOT NULL DEFAULT '',
+            provider VARCHAR(50) NOT NULL,
+            config VARCHAR(255) NOT NULL,
+            model VARCHAR(255) NOT NULL,
+            messages JSONB NOT NULL,
+            completion TEXT NOT NULL DEFAULT '',
+            tool_calls JSONB,
+            prompt_tokens INTEGER NOT NULL DEFAULT 0,
+            completion_tokens INTEGER NOT NULL DEFAULT 0,
+            total_tokens INTEGER NOT NULL DEFAULT 0,
+            latency_ms BIGINT NOT NULL,
+            error TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );
+        `,
+		`
+        CREATE INDEX IF NOT EXISTS idx_llm_calls_ask ON llm_calls(ask_id, created_at);
+        `,
+		`
+        CREATE INDEX IF NOT EXISTS idx_llm_calls_created_at ON llm_calls(created_at);
+        `,
+		`
+        CREATE TABLE IF NOT EXISTS datasets (
+            name VARCHAR(255) PRIMARY KEY,
+            file_name VARCHAR(255) NOT NULL,
+            format VARCHAR(10) NOT NULL,
+            sheet VARCHAR(255) NOT NULL DEFAULT '',
+            size BIGINT NOT NULL,
+            row_count BIGINT NOT NULL,
+            columns JSONB NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );
         `,
 		`
         CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -300,6 +395,35 @@ func (p *PostgresStorage) GetLLMConfigs(ctx context.Context, provider string) ([
 					"organization": opts.Organization,
 					"max_tokens":   opts.MaxTokens,
 				}
+			case "openai_compatible":
+				var opts models.OpenAICompatibleOptions
+				if err := json.Unmarshal(optionsJSON, &opts); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal OpenAI-compatible options: %w", err)
+				}
+				optionsMap = map[string]interface{}{
+					"base_url":          opts.BaseURL,
+					"headers":           opts.Headers,
+					"api_version":       opts.APIVersion,
+					"max_tokens":        opts.MaxTokens,
+					"structured_output": opts.StructuredOutput,
+				}
+			case "http":
+				var opts models.HTTPOptions
+				if err := json.Unmarshal(optionsJSON, &opts); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal HTTP options: %w", err)
+				}
+				optionsMap = map[string]interface{}{
+					"url":                    opts.URL,
+					"method":                 opts.Method,
+					"headers":                opts.Headers,
+					"request_template":       opts.RequestTemplate,
+					"content_path":           opts.ContentPath,
+					"prompt_tokens_path":     opts.PromptTokensPath,
+					"completion_tokens_path": opts.CompletionTokensPath,
+					"total_tokens_path":      opts.TotalTokensPath,
+					"error_path":             opts.ErrorPath,
+					"max_tokens":             opts.MaxTokens,
+				}
 			case "anthropic":
 				var opts models.AnthropicOptions
 				if err := json.Unmarshal(optionsJSON, &opts); err != nil {
@@ -316,7 +440,6 @@ func (p *PostgresStorage) GetLLMConfigs(ctx context.Context, provider string) ([
 					return nil, fmt.Errorf("failed to unmarshal Gemini options: %w", err)
 				}
 				optionsMap = map[string]interface{}{
-					"location":          opts.Location,
 					"temperature":       opts.Temperature,
 					"max_output_tokens": opts.MaxOutputTokens,
 				}
@@ -328,6 +451,7 @@ func (p *PostgresStorage) GetLLMConfigs(ctx context.Context, provider string) ([
 				optionsMap = map[string]interface{}{
 					"region":         opts.Region,
 					"model_provider": opts.ModelProvider,
+					"endpoint":       opts.Endpoint,
 				}
 			default:
 				return nil, fmt.Errorf("unsupported provider: %s", provider)
@@ -382,6 +506,36 @@ func (p *PostgresStorage) LoadLLMConfig(ctx context.Context, provider, configNam
 			return nil, fmt.Errorf("failed to unmarshal OpenAI config: %w", err)
 		}
 		return &config, nil // Return pointer to config
+	case "openai_compatible":
+		var config models.LLMConfig
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal OpenAI-compatible config: %w", err)
+		}
+		return &config, nil
+	case "http":
+		var config models.LLMConfig
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal HTTP config: %w", err)
+		}
+		return &config, nil
+	case "anthropic":
+		var config models.LLMConfig
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Anthropic config: %w", err)
+		}
+		return &config, nil
+	case "gemini":
+		var config models.LLMConfig
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Gemini config: %w", err)
+		}
+		return &config, nil
+	case "bedrock":
+		var config models.LLMConfig
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Bedrock config: %w", err)
+		}
+		return &config, nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
@@ -411,24 +565,605 @@ func (p *PostgresStorage) DeleteLLMConfig(ctx context.Context, provider, configN
 	return nil
 }
 
+// SaveLLMChain stores a new fallback chain
+func (p *PostgresStorage) SaveLLMChain(ctx context.Context, chain models.LLMChain) error {
+	entriesJSON, err := json.Marshal(chain.Entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chain entries: %w", err)
+	}
+
+	query := `
+        INSERT INTO llm_chains (name, entries)
+        VALUES ($1, $2)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING name
+    `
+
+	var returnedName string
+	err = p.db.QueryRowContext(ctx, query, chain.Name, entriesJSON).Scan(&returnedName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrConfigExists
+		}
+		return fmt.Errorf("failed to save chain: %w", err)
+	}
+
+	return nil
+}
+
+// GetLLMChains retrieves all fallback chains ordered by name
+func (p *PostgresStorage) GetLLMChains(ctx context.Context) ([]models.LLMChain, error) {
+	query := `
+        SELECT name, entries
+        FROM llm_chains
+        ORDER BY name
+    `
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chains: %w", err)
+	}
+	defer rows.Close()
+
+	var chains []models.LLMChain
+	for rows.Next() {
+		chain, err := scanLLMChain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chain: %w", err)
+		}
+		chains = append(chains, *chain)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chains: %w", err)
+	}
+
+	return chains, nil
+}
+
+// LoadLLMChain retrieves a specific fallback chain by name
+func (p *PostgresStorage) LoadLLMChain(ctx context.Context, name string) (*models.LLMChain, error) {
+	query := `
+        SELECT name, entries
+        FROM llm_chains
+        WHERE name = $1
+    `
+
+	chain, err := scanLLMChain(p.db.QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrConfigNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chain: %w", err)
+	}
+
+	return chain, nil
+}
+
+// DeleteLLMChain deletes a specific fallback chain
+func (p *PostgresStorage) DeleteLLMChain(ctx context.Context, name string) error {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM llm_chains WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete chain: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrConfigNotFound
+	}
+
+	return nil
+}
+
+func scanLLMChain(row rowScanner) (*models.LLMChain, error) {
+	var chain models.LLMChain
+	var entriesJSON []byte
+
+	if err := row.Scan(&chain.Name, &entriesJSON); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(entriesJSON, &chain.Entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain entries: %w", err)
+	}
+
+	return &chain, nil
+}
+
+// SaveOrchestrationProfile stores a new orchestration profile
+func (p *PostgresStorage) SaveOrchestrationProfile(ctx context.Context, profile models.OrchestrationProfile) error {
+	stepsJSON, err := json.Marshal(profile.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile steps: %w", err)
+	}
+
+	query := `
+        INSERT INTO orchestration_profiles (name, steps)
+        VALUES ($1, $2)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING name
+    `
+
+	var returnedName string
+	err = p.db.QueryRowContext(ctx, query, profile.Name, stepsJSON).Scan(&returnedName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrConfigExists
+		}
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrchestrationProfiles retrieves all orchestration profiles ordered by name
+func (p *PostgresStorage) GetOrchestrationProfiles(ctx context.Context) ([]models.OrchestrationProfile, error) {
+	query := `
+        SELECT name, steps
+        FROM orchestration_profiles
+        ORDER BY name
+    `
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []models.OrchestrationProfile
+	for rows.Next() {
+		profile, err := scanOrchestrationProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+		profiles = append(profiles, *profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// LoadOrchestrationProfile retrieves a specific orchestration profile by name
+func (p *PostgresStorage) LoadOrchestrationProfile(ctx context.Context, name string) (*models.OrchestrationProfile, error) {
+	query := `
+        SELECT name, steps
+        FROM orchestration_profiles
+        WHERE name = $1
+    `
+
+	profile, err := scanOrchestrationProfile(p.db.QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrConfigNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// DeleteOrchestrationProfile deletes a specific orchestration profile
+func (p *PostgresStorage) DeleteOrchestrationProfile(ctx context.Context, name string) error {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM orchestration_profiles WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrConfigNotFound
+	}
+
+	return nil
+}
+
+func scanOrchestrationProfile(row rowScanner) (*models.OrchestrationProfile, error) {
+	var profile models.OrchestrationProfile
+	var stepsJSON []byte
+
+	if err := row.Scan(&profile.Name, &stepsJSON); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(stepsJSON, &profile.Steps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile steps: %w", err)
+	}
+
+	return &profile, nil
+}
+
+// SaveLLMUsage records the usage of one LLM call
+func (p *PostgresStorage) SaveLLMUsage(ctx context.Context, usage models.LLMUsage) error {
+	query := `
+        INSERT INTO llm_usage (ask_id, provider, config, model, db_config, step,
+            prompt_tokens, completion_tokens, total_tokens, cost, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `
+
+	_, err := p.db.ExecContext(ctx, query,
+		usage.AskID,
+		usage.Provider,
+		usage.Config,
+		usage.Model,
+		usage.DBConfig,
+		usage.Step,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.TotalTokens,
+		usage.Cost,
+		usage.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save LLM usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetLLMUsage retrieves the usage records selected by filter ordered by time
+func (p *PostgresStorage) GetLLMUsage(ctx context.Context, filter models.UsageFilter) ([]models.LLMUsage, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Provider != "" {
+		where("provider = $%d", filter.Provider)
+	}
+	if filter.Config != "" {
+		where("config = $%d", filter.Config)
+	}
+	if filter.DBConfig != "" {
+		where("db_config = $%d", filter.DBConfig)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	query := `
+        SELECT ask_id, provider, config, model, db_config, step,
+            prompt_tokens, completion_tokens, total_tokens, cost, created_at
+        FROM llm_usage
+    `
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at ASC, id ASC"
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	var records []models.LLMUsage
+	for rows.Next() {
+		var usage models.LLMUsage
+		if err := rows.Scan(
+			&usage.AskID,
+			&usage.Provider,
+			&usage.Config,
+			&usage.Model,
+			&usage.DBConfig,
+			&usage.Step,
+			&usage.PromptTokens,
+			&usage.CompletionTokens,
+			&usage.TotalTokens,
+			&usage.Cost,
+			&usage.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		records = append(records, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating LLM usage: %w", err)
+	}
+
+	return records, nil
+}
+
+// SaveLLMCall stores the audit record of one LLM call
+func (p *PostgresStorage) SaveLLMCall(ctx context.Context, call models.LLMCall) error {
+	messagesJSON, err := json.Marshal(call.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal LLM call messages: %w", err)
+	}
+
+	var toolCallsJSON []byte
+	if len(call.ToolCalls) > 0 {
+		toolCallsJSON, err = json.Marshal(call.ToolCalls)
+		if err != nil {
+			return fmt.Errorf("failed to marshal LLM call tool calls: %w", err)
+		}
+	}
+
+	query := `
+        INSERT INTO llm_calls (id, ask_id, step, provider, config, model, messages, completion, tool_calls,
+            prompt_tokens, completion_tokens, total_tokens, latency_ms, error, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+    `
+
+	_, err = p.db.ExecContext(ctx, query,
+		call.ID,
+		call.AskID,
+		call.Step,
+		call.Provider,
+		call.Config,
+		call.Model,
+		messagesJSON,
+		call.Completion,
+		toolCallsJSON,
+		call.PromptTokens,
+		call.CompletionTokens,
+		call.TotalTokens,
+		call.LatencyMS,
+		call.Error,
+		call.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save LLM call: %w", err)
+	}
+
+	return nil
+}
+
+// GetLLMCalls retrieves the LLM calls of an ask ordered by time
+func (p *PostgresStorage) GetLLMCalls(ctx context.Context, askID string) ([]models.LLMCall, error) {
+	query := `
+        SELECT id, ask_id, step, provider, config, model, messages, completion, tool_calls,
+            prompt_tokens, completion_tokens, total_tokens, latency_ms, error, created_at
+        FROM llm_calls
+        WHERE ask_id = $1
+        ORDER BY created_at ASC
+    `
+
+	rows, err := p.db.QueryContext(ctx, query, askID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []models.LLMCall
+	for rows.Next() {
+		var call models.LLMCall
+		var messagesJSON, toolCallsJSON []byte
+		if err := rows.Scan(
+			&call.ID,
+			&call.AskID,
+			&call.Step,
+			&call.Provider,
+			&call.Config,
+			&call.Model,
+			&messagesJSON,
+			&call.Completion,
+			&toolCallsJSON,
+			&call.PromptTokens,
+			&call.CompletionTokens,
+			&call.TotalTokens,
+			&call.LatencyMS,
+			&call.Error,
+			&call.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM call: %w", err)
+		}
+
+		if err := json.Unmarshal(messagesJSON, &call.Messages); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal LLM call messages: %w", err)
+		}
+		if toolCallsJSON != nil {
+			if err := json.Unmarshal(toolCallsJSON, &call.ToolCalls); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal LLM call tool calls: %w", err)
+			}
+		}
+		calls = append(calls, call)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating LLM calls: %w", err)
+	}
+
+	return calls, nil
+}
+
+// DeleteLLMCallsBefore deletes the LLM calls made before a time
+func (p *PostgresStorage) DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM llm_calls WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete LLM calls: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// SaveDataset stores the metadata of a new uploaded dataset
+func (p *PostgresStorage) SaveDataset(ctx context.Context, dataset models.Dataset) error {
+	columnsJSON, err := json.Marshal(dataset.Columns)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dataset columns: %w", err)
+	}
+
+	query := `
+        INSERT INTO datasets (name, file_name, format, sheet, size, row_count, columns, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING name
+    `
+
+	var returnedName string
+	err = p.db.QueryRowContext(ctx, query,
+		dataset.Name,
+		dataset.FileName,
+		dataset.Format,
+		dataset.Sheet,
+		dataset.Size,
+		dataset.Rows,
+		columnsJSON,
+		dataset.CreatedAt,
+		dataset.ExpiresAt,
+	).Scan(&returnedName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ErrConfigExists
+		}
+		return fmt.Errorf("failed to save dataset: %w", err)
+	}
+
+	return nil
+}
+
+// GetDatasets retrieves all datasets ordered by name
+func (p *PostgresStorage) GetDatasets(ctx context.Context) ([]models.Dataset, error) {
+	query := `
+        SELECT name, file_name, format, sheet, size, row_count, columns, created_at, expires_at
+        FROM datasets
+        ORDER BY name
+    `
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query datasets: %w", err)
+	}
+	defer rows.Close()
+
+	var datasets []models.Dataset
+	for rows.Next() {
+		dataset, err := scanDataset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dataset: %w", err)
+		}
+		datasets = append(datasets, *dataset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating datasets: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// LoadDataset retrieves a specific dataset by name
+func (p *PostgresStorage) LoadDataset(ctx context.Context, name string) (*models.Dataset, error) {
+	query := `
+        SELECT name, file_name, format, sheet, size, row_count, columns, created_at, expires_at
+        FROM datasets
+        WHERE name = $1
+    `
+
+	dataset, err := scanDataset(p.db.QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrConfigNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dataset: %w", err)
+	}
+
+	return dataset, nil
+}
+
+// DeleteDataset deletes the metadata of a specific dataset
+func (p *PostgresStorage) DeleteDataset(ctx context.Context, name string) error {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM datasets WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete dataset: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrConfigNotFound
+	}
+
+	return nil
+}
+
+func scanDataset(row rowScanner) (*models.Dataset, error) {
+	var dataset models.Dataset
+	var columnsJSON []byte
+
+	if err := row.Scan(
+		&dataset.Name,
+		&dataset.FileName,
+		&dataset.Format,
+		&dataset.Sheet,
+		&dataset.Size,
+		&dataset.Rows,
+		&columnsJSON,
+		&dataset.CreatedAt,
+		&dataset.ExpiresAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(columnsJSON, &dataset.Columns); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dataset columns: %w", err)
+	}
+
+	return &dataset, nil
+}
+
 func (p *PostgresStorage) SaveAssistantResponse(ctx context.Context, response models.AssistantResponse) error {
 	responseJSON, err := json.Marshal(response.Response)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
+	// A NULL answered_by means no LLM has answered yet
+	var answeredByJSON []byte
+	if response.AnsweredBy != nil {
+		answeredByJSON, err = json.Marshal(response.AnsweredBy)
+		if err != nil {
+			return fmt.Errorf("failed to marshal answered by: %w", err)
+		}
+	}
+
+	var usageJSON []byte
+	if response.Usage != nil {
+		usageJSON, err = json.Marshal(response.Usage)
+		if err != nil {
+			return fmt.Errorf("failed to marshal usage: %w", err)
+		}
+	}
+
 	// Add query timeout if context doesn't have one
 	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	query := `
-        INSERT INTO assistant_responses (uuid, question, success, status, response)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO assistant_responses (uuid, question, success, status, response, thread_id, sequence, sql_query, result_summary, answered_by, usage)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         ON CONFLICT (uuid) DO UPDATE SET
             question = EXCLUDED.question,
             success = EXCLUDED.success,
             status = EXCLUDED.status,
             response = EXCLUDED.response,
+            thread_id = EXCLUDED.thread_id,
+            sequence = EXCLUDED.sequence,
+            sql_query = EXCLUDED.sql_query,
+            result_summary = EXCLUDED.result_summary,
+            answered_by = EXCLUDED.answered_by,
+            usage = EXCLUDED.usage,
             updated_at = CURRENT_TIMESTAMP
     `
 
@@ -438,6 +1173,12 @@ func (p *PostgresStorage) SaveAssistantResponse(ctx context.Context, response mo
 		response.Success,
 		response.Status,
 		responseJSON,
+		sql.NullString{String: response.ThreadID, Valid: response.ThreadID != ""},
+		response.Sequence,
+		response.SQLQuery,
+		response.ResultSummary,
+		answeredByJSON,
+		usageJSON,
 	)
 
 	if err != nil {
@@ -456,27 +1197,100 @@ func (p *PostgresStorage) SaveAssistantResponse(ctx context.Context, response mo
 // LoadAssistantResponse retrieves a specific assistant response by UUID
 func (p *PostgresStorage) LoadAssistantResponse(ctx context.Context, uuid string) (*models.AssistantResponse, error) {
 	query := `
-        SELECT uuid, question, success, status, response
+        SELECT ` + assistantResponseColumns + `
         FROM assistant_responses
         WHERE uuid = $1
     `
 
+	response, err := scanAssistantResponse(p.db.QueryRowContext(ctx, query, uuid))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrResponseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assistant response: %w", err)
+	}
+
+	return response, nil
+}
+
+// GetAssistantHistories retrieves all assistant responses ordered by creation time
+func (p *PostgresStorage) GetAssistantHistories(ctx context.Context) ([]models.AssistantResponse, error) {
+	query := `
+        SELECT ` + assistantResponseColumns + `
+        FROM assistant_responses
+        ORDER BY created_at DESC
+    `
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assistant histories: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssistantResponses(rows)
+}
+
+// GetThreadResponses retrieves the assistant responses of a thread ordered by sequence
+func (p *PostgresStorage) GetThreadResponses(ctx context.Context, threadID string) ([]models.AssistantResponse, error) {
+	query := `
+        SELECT ` + assistantResponseColumns + `
+        FROM assistant_responses
+        WHERE thread_id = $1
+        ORDER BY sequence ASC
+    `
+
+	rows, err := p.db.QueryContext(ctx, query, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread responses: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssistantResponses(rows)
+}
+
+// assistantResponseColumns are the columns read by scanAssistantResponse
+const assistantResponseColumns = "uuid, question, success, status, response, thread_id, sequence, sql_query, result_summary, answered_by, usage"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAssistantResponse(row rowScanner) (*models.AssistantResponse, error) {
 	var response models.AssistantResponse
 	var responseJSON []byte
+	var threadID sql.NullString
+	var answeredByJSON []byte
+	var usageJSON []byte
 
-	err := p.db.QueryRowContext(ctx, query, uuid).Scan(
+	err := row.Scan(
 		&response.UUID,
 		&response.Question,
 		&response.Success,
 		&response.Status,
 		&responseJSON,
+		&threadID,
+		&response.Sequence,
+		&response.SQLQuery,
+		&response.ResultSummary,
+		&answeredByJSON,
+		&usageJSON,
 	)
+	if err != nil {
+		return nil, err
+	}
+	response.ThreadID = threadID.String
 
-	if err == sql.ErrNoRows {
-		return nil, storage.ErrResponseNotFound
+	if answeredByJSON != nil {
+		if err := json.Unmarshal(answeredByJSON, &response.AnsweredBy); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal answered by: %w", err)
+		}
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query assistant response: %w", err)
+
+	if usageJSON != nil {
+		if err := json.Unmarshal(usageJSON, &response.Usage); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal usage: %w", err)
+		}
 	}
 
 	// Unmarshal the response array
@@ -489,51 +1303,21 @@ func (p *PostgresStorage) LoadAssistantResponse(ctx context.Context, uuid string
 	return &response, nil
 }
 
-// GetAssistantHistories retrieves all assistant responses ordered by creation time
-func (p *PostgresStorage) GetAssistantHistories(ctx context.Context) ([]models.AssistantResponse, error) {
-	query := `
-        SELECT uuid, question, success, status, response
-        FROM assistant_responses
-        ORDER BY created_at DESC
-    `
-
-	rows, err := p.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query assistant histories: %w", err)
-	}
-	defer rows.Close()
-
-	var histories []models.AssistantResponse
+func scanAssistantResponses(rows *sql.Rows) ([]models.AssistantResponse, error) {
+	var responses []models.AssistantResponse
 	for rows.Next() {
-		var response models.AssistantResponse
-		var responseJSON []byte
-
-		err := rows.Scan(
-			&response.UUID,
-			&response.Question,
-			&response.Success,
-			&response.Status,
-			&responseJSON,
-		)
+		response, err := scanAssistantResponse(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan assistant response: %w", err)
 		}
-
-		// Unmarshal the response array
-		var updates []models.Update
-		if err := json.Unmarshal(responseJSON, &updates); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response updates: %w", err)
-		}
-		response.Response = updates
-
-		histories = append(histories, response)
+		responses = append(responses, *response)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating assistant responses: %w", err)
 	}
 
-	return histories, nil
+	return responses, nil
 }
 
 func (p *PostgresStorage) Close() error {
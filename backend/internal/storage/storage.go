@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/shahariaazam/smart-insights/internal/api/models"
 )
@@ -27,9 +28,42 @@ type Storage interface {
 	LoadLLMConfig(ctx context.Context, provider, configName string) (interface{}, error)
 	DeleteLLMConfig(ctx context.Context, provider string, configName string) error
 
+	// LLM fallback chains share ErrConfigExists and ErrConfigNotFound with configs
+	SaveLLMChain(ctx context.Context, chain models.LLMChain) error
+	GetLLMChains(ctx context.Context) ([]models.LLMChain, error)
+	LoadLLMChain(ctx context.Context, name string) (*models.LLMChain, error)
+	DeleteLLMChain(ctx context.Context, name string) error
+
+	// Orchestration profiles share ErrConfigExists and ErrConfigNotFound with configs
+	SaveOrchestrationProfile(ctx context.Context, profile models.OrchestrationProfile) error
+	GetOrchestrationProfiles(ctx context.Context) ([]models.OrchestrationProfile, error)
+	LoadOrchestrationProfile(ctx context.Context, name string) (*models.OrchestrationProfile, error)
+	DeleteOrchestrationProfile(ctx context.Context, name string) error
+
+	// SaveLLMUsage records the usage of one LLM call; GetLLMUsage returns the
+	// records selected by filter, oldest first
+	SaveLLMUsage(ctx context.Context, usage models.LLMUsage) error
+	GetLLMUsage(ctx context.Context, filter models.UsageFilter) ([]models.LLMUsage, error)
+
+	// SaveLLMCall stores the audit record of one LLM call; GetLLMCalls returns the
+	// calls of an ask, oldest first. DeleteLLMCallsBefore removes the calls made
+	// before a time and returns how many were removed.
+	SaveLLMCall(ctx context.Context, call models.LLMCall) error
+	GetLLMCalls(ctx context.Context, askID string) ([]models.LLMCall, error)
+	DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// Uploaded datasets share ErrConfigExists and ErrConfigNotFound with configs;
+	// GetDatasets returns them ordered by name
+	SaveDataset(ctx context.Context, dataset models.Dataset) error
+	GetDatasets(ctx context.Context) ([]models.Dataset, error)
+	LoadDataset(ctx context.Context, name string) (*models.Dataset, error)
+	DeleteDataset(ctx context.Context, name string) error
+
 	SaveAssistantResponse(ctx context.Context, response models.AssistantResponse) error
 	LoadAssistantResponse(ctx context.Context, uuid string) (*models.AssistantResponse, error)
 	GetAssistantHistories(ctx context.Context) ([]models.AssistantResponse, error)
+	// GetThreadResponses returns the asks of a conversation thread ordered by sequence
+	GetThreadResponses(ctx context.Context, threadID string) ([]models.AssistantResponse, error)
 
 	Close() error
 }
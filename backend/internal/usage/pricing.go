@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"strings"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+)
+
+// DefaultPrices are list prices in USD per million tokens, keyed by model ID
+// prefix. Dated or versioned IDs such as gpt-4o-2024-08-06 or Bedrock's
+// anthropic.claude-3-5-sonnet-20240620-v1:0 match their base model.
+var DefaultPrices = map[string]models.LLMPricing{
+	// OpenAI
+	"gpt-4o":       {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4o-mini":  {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4.1":      {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"gpt-4.1-mini": {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1-nano": {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gpt-4-turbo":  {InputPerMillion: 10.00, OutputPerMillion: 30.00},
+	"o3-mini":      {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+
+	// Anthropic
+	"claude-opus-4":     {InputPerMillion: 15.00, OutputPerMillion: 75.00},
+	"claude-sonnet-4":   {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-7-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-5-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4.00},
+	"claude-3-haiku":    {InputPerMillion: 0.25, OutputPerMillion: 1.25},
+
+	// Gemini
+	"gemini-2.5-pro":   {InputPerMillion: 1.25, OutputPerMillion: 10.00},
+	"gemini-2.5-flash": {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	"gemini-2.0-flash": {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-1.5-pro":   {InputPerMillion: 1.25, OutputPerMillion: 5.00},
+	"gemini-1.5-flash": {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+}
+
+// Price returns the price of model for an LLM config: the config's own pricing,
+// or else the default price of the longest matching model prefix. ok is false
+// when the model has no known price.
+func Price(llmConfig *models.LLMConfig, model string) (price models.LLMPricing, ok bool) {
+	if llmConfig != nil && llmConfig.Pricing != nil {
+		return *llmConfig.Pricing, true
+	}
+
+	// Bedrock IDs carry a region and vendor prefix, e.g. us.anthropic.claude-...
+	candidates := []string{model}
+	if i := strings.Index(model, ".claude-"); i >= 0 {
+		candidates = append(candidates, model[i+1:])
+	}
+
+	matched := ""
+	for _, candidate := range candidates {
+		for prefix, p := range DefaultPrices {
+			if strings.HasPrefix(candidate, prefix) && len(prefix) > len(matched) {
+				matched, price = prefix, p
+			}
+		}
+	}
+	return price, matched != ""
+}
+
+// Cost returns the cost in USD of a call that used the given tokens
+func Cost(price models.LLMPricing, promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*price.InputPerMillion + float64(completionTokens)*price.OutputPerMillion) / 1e6
+}
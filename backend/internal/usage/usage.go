@@ -0,0 +1,114 @@
+// Package usage prices LLM calls and adds up their usage per ask, per LLM config
+// and per day, enforcing the monthly budgets of LLM configs.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage"
+)
+
+// Report adds up usage records in total, per LLM config and per UTC day. Configs
+// are sorted by provider and name, days in chronological order.
+func Report(records []models.LLMUsage) models.UsageReport {
+	report := models.UsageReport{
+		ByConfig: []models.ConfigUsage{},
+		ByDay:    []models.DailyUsage{},
+	}
+
+	configs := make(map[string]*models.ConfigUsage)
+	days := make(map[string]*models.DailyUsage)
+	for _, record := range records {
+		report.Total.Add(record)
+
+		key := string(record.Provider) + "/" + record.Config
+		config, ok := configs[key]
+		if !ok {
+			config = &models.ConfigUsage{Provider: record.Provider, Config: record.Config}
+			configs[key] = config
+		}
+		config.Add(record)
+
+		date := record.CreatedAt.UTC().Format(time.DateOnly)
+		day, ok := days[date]
+		if !ok {
+			day = &models.DailyUsage{Date: date}
+			days[date] = day
+		}
+		day.Add(record)
+	}
+
+	for _, config := range configs {
+		report.ByConfig = append(report.ByConfig, *config)
+	}
+	sort.Slice(report.ByConfig, func(i, j int) bool {
+		a, b := report.ByConfig[i], report.ByConfig[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Config < b.Config
+	})
+
+	for _, day := range days {
+		report.ByDay = append(report.ByDay, *day)
+	}
+	sort.Slice(report.ByDay, func(i, j int) bool {
+		return report.ByDay[i].Date < report.ByDay[j].Date
+	})
+
+	return report
+}
+
+// BudgetExceededError refuses an ask whose LLM config has spent its monthly budget
+type BudgetExceededError struct {
+	Provider models.LLMType
+	Config   string
+	Budget   float64
+	Spent    float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("LLM configuration '%s' of provider '%s' has spent $%.2f of its $%.2f monthly budget",
+		e.Config, e.Provider, e.Spent, e.Budget)
+}
+
+// MonthStart returns the start of the calendar month (UTC) of t
+func MonthStart(t time.Time) time.Time {
+	year, month, _ := t.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// CheckBudget returns a *BudgetExceededError when the cost of llmConfig in the
+// month of now has reached its monthly budget. Configs without a budget pass.
+func CheckBudget(ctx context.Context, store storage.Storage, llmConfig *models.LLMConfig, now time.Time) error {
+	if llmConfig.MonthlyBudget <= 0 {
+		return nil
+	}
+
+	records, err := store.GetLLMUsage(ctx, models.UsageFilter{
+		Provider: llmConfig.Type,
+		Config:   llmConfig.Name,
+		From:     MonthStart(now),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load usage of LLM configuration '%s': %w", llmConfig.Name, err)
+	}
+
+	var spent models.UsageSummary
+	for _, record := range records {
+		spent.Add(record)
+	}
+	if spent.Cost >= llmConfig.MonthlyBudget {
+		return &BudgetExceededError{
+			Provider: llmConfig.Type,
+			Config:   llmConfig.Name,
+			Budget:   llmConfig.MonthlyBudget,
+			Spent:    spent.Cost,
+		}
+	}
+	return nil
+}
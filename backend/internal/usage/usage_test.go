@@ -0,0 +1,119 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shahariaazam/smart-insights/internal/api/models"
+	"github.com/shahariaazam/smart-insights/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		llmConfig *models.LLMConfig
+		model     string
+		want      models.LLMPricing
+		wantOK    bool
+	}{
+		{
+			name:   "exact model",
+			model:  "gpt-4o",
+			want:   DefaultPrices["gpt-4o"],
+			wantOK: true,
+		},
+		{
+			name:   "longest prefix wins",
+			model:  "gpt-4o-mini-2024-07-18",
+			want:   DefaultPrices["gpt-4o-mini"],
+			wantOK: true,
+		},
+		{
+			name:   "bedrock model ID",
+			model:  "us.anthropic.claude-3-5-sonnet-20240620-v1:0",
+			want:   DefaultPrices["claude-3-5-sonnet"],
+			wantOK: true,
+		},
+		{
+			name:      "config pricing overrides the table",
+			llmConfig: &models.LLMConfig{Pricing: &models.LLMPricing{InputPerMillion: 1, OutputPerMillion: 2}},
+			model:     "gpt-4o",
+			want:      models.LLMPricing{InputPerMillion: 1, OutputPerMillion: 2},
+			wantOK:    true,
+		},
+		{
+			name:  "unknown model",
+			model: "llama3:70b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := Price(tt.llmConfig, tt.model)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, price)
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	price := models.LLMPricing{InputPerMillion: 2.5, OutputPerMillion: 10}
+	assert.InDelta(t, 0.0035, Cost(price, 1000, 100), 1e-12)
+}
+
+func TestReport(t *testing.T) {
+	day1 := time.Date(2026, 10, 1, 23, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 10, 2, 1, 0, 0, 0, time.UTC)
+	records := []models.LLMUsage{
+		{Provider: models.OpenAI, Config: "gpt-4o", PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.5, CreatedAt: day1},
+		{Provider: models.Anthropic, Config: "claude", PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220, Cost: 1, CreatedAt: day2},
+		{Provider: models.OpenAI, Config: "gpt-4o", PromptTokens: 300, CompletionTokens: 30, TotalTokens: 330, Cost: 1.5, CreatedAt: day2},
+	}
+
+	report := Report(records)
+	assert.Equal(t, models.UsageSummary{Calls: 3, PromptTokens: 600, CompletionTokens: 60, TotalTokens: 660, Cost: 3}, report.Total)
+	assert.Equal(t, []models.ConfigUsage{
+		{Provider: models.Anthropic, Config: "claude", UsageSummary: models.UsageSummary{Calls: 1, PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220, Cost: 1}},
+		{Provider: models.OpenAI, Config: "gpt-4o", UsageSummary: models.UsageSummary{Calls: 2, PromptTokens: 400, CompletionTokens: 40, TotalTokens: 440, Cost: 2}},
+	}, report.ByConfig)
+	assert.Equal(t, []models.DailyUsage{
+		{Date: "2026-10-01", UsageSummary: models.UsageSummary{Calls: 1, PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.5}},
+		{Date: "2026-10-02", UsageSummary: models.UsageSummary{Calls: 2, PromptTokens: 500, CompletionTokens: 50, TotalTokens: 550, Cost: 2.5}},
+	}, report.ByDay)
+
+	empty := Report(nil)
+	assert.NotNil(t, empty.ByConfig)
+	assert.NotNil(t, empty.ByDay)
+}
+
+func TestCheckBudget(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	llmConfig := &models.LLMConfig{Name: "gpt-4o", Type: models.OpenAI, MonthlyBudget: 10}
+
+	save := func(cost float64, at time.Time) {
+		require.NoError(t, store.SaveLLMUsage(ctx, models.LLMUsage{
+			Provider: models.OpenAI, Config: "gpt-4o", Cost: cost, CreatedAt: at,
+		}))
+	}
+
+	// Last month's spend does not count
+	save(100, time.Date(2026, 9, 30, 23, 59, 0, 0, time.UTC))
+	save(6, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, CheckBudget(ctx, store, llmConfig, now))
+
+	save(4, now.Add(-time.Hour))
+	err := CheckBudget(ctx, store, llmConfig, now)
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, 10.0, budgetErr.Spent)
+	assert.Contains(t, err.Error(), "has spent $10.00 of its $10.00 monthly budget")
+
+	// Configs without a budget are never refused
+	require.NoError(t, CheckBudget(ctx, store, &models.LLMConfig{Name: "gpt-4o", Type: models.OpenAI}, now))
+}